@@ -2,9 +2,9 @@ package afc
 
 import (
 	"encoding/binary"
-	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 )
 
 const (
@@ -102,65 +102,96 @@ const (
 	Afc_Err_DirNotEmpty            = 33
 )
 
+//StatusError is the error code of an afc status response. Use errors.Is with the Err values below to check for a specific status.
+//ErrObjectNotFound, ErrObjectExists and ErrPermDenied also match fs.ErrNotExist, fs.ErrExist and fs.ErrPermission.
+type StatusError uint64
+
+//errors for all afc status codes
+var (
+	ErrUnknownError           error = StatusError(Afc_Err_UnknownError)
+	ErrOperationHeaderInvalid error = StatusError(Afc_Err_OperationHeaderInvalid)
+	ErrNoResources            error = StatusError(Afc_Err_NoResources)
+	ErrReadError              error = StatusError(Afc_Err_ReadError)
+	ErrWriteError             error = StatusError(Afc_Err_WriteError)
+	ErrUnknownPacketType      error = StatusError(Afc_Err_UnknownPacketType)
+	ErrInvalidArgument        error = StatusError(Afc_Err_InvalidArgument)
+	ErrObjectNotFound         error = StatusError(Afc_Err_ObjectNotFound)
+	ErrObjectIsDir            error = StatusError(Afc_Err_ObjectIsDir)
+	ErrPermDenied             error = StatusError(Afc_Err_PermDenied)
+	ErrServiceNotConnected    error = StatusError(Afc_Err_ServiceNotConnected)
+	ErrOperationTimeout       error = StatusError(Afc_Err_OperationTimeout)
+	ErrTooMuchData            error = StatusError(Afc_Err_TooMuchData)
+	ErrEndOfData              error = StatusError(Afc_Err_EndOfData)
+	ErrOperationNotSupported  error = StatusError(Afc_Err_OperationNotSupported)
+	ErrObjectExists           error = StatusError(Afc_Err_ObjectExists)
+	ErrObjectBusy             error = StatusError(Afc_Err_ObjectBusy)
+	ErrNoSpaceLeft            error = StatusError(Afc_Err_NoSpaceLeft)
+	ErrOperationWouldBlock    error = StatusError(Afc_Err_OperationWouldBlock)
+	ErrIoError                error = StatusError(Afc_Err_IoError)
+	ErrOperationInterrupted   error = StatusError(Afc_Err_OperationInterrupted)
+	ErrOperationInProgress    error = StatusError(Afc_Err_OperationInProgress)
+	ErrInternalError          error = StatusError(Afc_Err_InternalError)
+	ErrMuxError               error = StatusError(Afc_Err_MuxError)
+	ErrNoMemory               error = StatusError(Afc_Err_NoMemory)
+	ErrNotEnoughData          error = StatusError(Afc_Err_NotEnoughData)
+	ErrDirNotEmpty            error = StatusError(Afc_Err_DirNotEmpty)
+)
+
+var statusNames = map[StatusError]string{
+	Afc_Err_UnknownError:           "UnknownError",
+	Afc_Err_OperationHeaderInvalid: "OperationHeaderInvalid",
+	Afc_Err_NoResources:            "NoResources",
+	Afc_Err_ReadError:              "ReadError",
+	Afc_Err_WriteError:             "WriteError",
+	Afc_Err_UnknownPacketType:      "UnknownPacketType",
+	Afc_Err_InvalidArgument:        "InvalidArgument",
+	Afc_Err_ObjectNotFound:         "ObjectNotFound",
+	Afc_Err_ObjectIsDir:            "ObjectIsDir",
+	Afc_Err_PermDenied:             "PermDenied",
+	Afc_Err_ServiceNotConnected:    "ServiceNotConnected",
+	Afc_Err_OperationTimeout:       "OperationTimeout",
+	Afc_Err_TooMuchData:            "TooMuchData",
+	Afc_Err_EndOfData:              "EndOfData",
+	Afc_Err_OperationNotSupported:  "OperationNotSupported",
+	Afc_Err_ObjectExists:           "ObjectExists",
+	Afc_Err_ObjectBusy:             "ObjectBusy",
+	Afc_Err_NoSpaceLeft:            "NoSpaceLeft",
+	Afc_Err_OperationWouldBlock:    "OperationWouldBlock",
+	Afc_Err_IoError:                "IoError",
+	Afc_Err_OperationInterrupted:   "OperationInterrupted",
+	Afc_Err_OperationInProgress:    "OperationInProgress",
+	Afc_Err_InternalError:          "InternalError",
+	Afc_Err_MuxError:               "MuxError",
+	Afc_Err_NoMemory:               "NoMemory",
+	Afc_Err_NotEnoughData:          "NotEnoughData",
+	Afc_Err_DirNotEmpty:            "DirNotEmpty",
+}
+
+func (e StatusError) Error() string {
+	if name, ok := statusNames[e]; ok {
+		return name
+	}
+	return fmt.Sprintf("UnknownStatus(%d)", uint64(e))
+}
+
+//Is makes StatusErrors compatible with the io/fs errors
+func (e StatusError) Is(target error) bool {
+	switch target {
+	case fs.ErrNotExist:
+		return e == Afc_Err_ObjectNotFound
+	case fs.ErrExist:
+		return e == Afc_Err_ObjectExists
+	case fs.ErrPermission:
+		return e == Afc_Err_PermDenied
+	}
+	return false
+}
+
 func getError(errorCode uint64) error {
-	switch errorCode {
-	case Afc_Err_UnknownError:
-		return errors.New("UnknownError")
-	case Afc_Err_OperationHeaderInvalid:
-		return errors.New("OperationHeaderInvalid")
-	case Afc_Err_NoResources:
-		return errors.New("NoResources")
-	case Afc_Err_ReadError:
-		return errors.New("ReadError")
-	case Afc_Err_WriteError:
-		return errors.New("WriteError")
-	case Afc_Err_UnknownPacketType:
-		return errors.New("UnknownPacketType")
-	case Afc_Err_InvalidArgument:
-		return errors.New("InvalidArgument")
-	case Afc_Err_ObjectNotFound:
-		return errors.New("ObjectNotFound")
-	case Afc_Err_ObjectIsDir:
-		return errors.New("ObjectIsDir")
-	case Afc_Err_PermDenied:
-		return errors.New("PermDenied")
-	case Afc_Err_ServiceNotConnected:
-		return errors.New("ServiceNotConnected")
-	case Afc_Err_OperationTimeout:
-		return errors.New("OperationTimeout")
-	case Afc_Err_TooMuchData:
-		return errors.New("TooMuchData")
-	case Afc_Err_EndOfData:
-		return errors.New("EndOfData")
-	case Afc_Err_OperationNotSupported:
-		return errors.New("OperationNotSupported")
-	case Afc_Err_ObjectExists:
-		return errors.New("ObjectExists")
-	case Afc_Err_ObjectBusy:
-		return errors.New("ObjectBusy")
-	case Afc_Err_NoSpaceLeft:
-		return errors.New("NoSpaceLeft")
-	case Afc_Err_OperationWouldBlock:
-		return errors.New("OperationWouldBlock")
-	case Afc_Err_IoError:
-		return errors.New("IoError")
-	case Afc_Err_OperationInterrupted:
-		return errors.New("OperationInterrupted")
-	case Afc_Err_OperationInProgress:
-		return errors.New("OperationInProgress")
-	case Afc_Err_InternalError:
-		return errors.New("InternalError")
-	case Afc_Err_MuxError:
-		return errors.New("MuxError")
-	case Afc_Err_NoMemory:
-		return errors.New("NoMemory")
-	case Afc_Err_NotEnoughData:
-		return errors.New("NotEnoughData")
-	case Afc_Err_DirNotEmpty:
-		return errors.New("DirNotEmpty")
-	default:
+	if errorCode == Afc_Err_Success {
 		return nil
 	}
+	return StatusError(errorCode)
 }
 
 type AfcPacketHeader struct {
@@ -0,0 +1,60 @@
+package afc_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"testing"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/afc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusError(t *testing.T) {
+	err := fmt.Errorf("unexpected afc status: %w", afc.StatusError(afc.Afc_Err_ObjectNotFound))
+	assert.ErrorIs(t, err, afc.ErrObjectNotFound)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.False(t, errors.Is(err, fs.ErrExist))
+	assert.Equal(t, "unexpected afc status: ObjectNotFound", err.Error())
+
+	assert.ErrorIs(t, afc.ErrPermDenied, fs.ErrPermission)
+	assert.ErrorIs(t, afc.ErrObjectExists, fs.ErrExist)
+	assert.Equal(t, "UnknownStatus(99)", afc.StatusError(99).Error())
+}
+
+func TestUnknownStatusIsAnError(t *testing.T) {
+	//net.Pipe does not work here, afc.Encode writes empty payloads which block until the other side reads
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		device, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer device.Close()
+		request, err := afc.Decode(device)
+		if err != nil {
+			return
+		}
+		status := make([]byte, 8)
+		binary.LittleEndian.PutUint64(status, 99)
+		header := afc.AfcPacketHeader{Magic: afc.Afc_magic, Entire_length: afc.Afc_header_size + 8, This_length: afc.Afc_header_size + 8,
+			Packet_num: request.Header.Packet_num, Operation: afc.Afc_operation_status}
+		afc.Encode(afc.AfcPacket{Header: header, HeaderPayload: status}, device)
+	}()
+	client, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	conn := afc.NewFromConn(ios.NewDeviceConnectionWithConn(client))
+
+	//unknown status codes used to be treated like Afc_Err_Success
+	err = conn.RemovePath("/file")
+	var statusErr afc.StatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, afc.StatusError(99), statusErr)
+}
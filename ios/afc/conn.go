@@ -2,6 +2,7 @@ package afc
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -54,6 +55,11 @@ func (conn *Connection) checkOperationStatus(packet AfcPacket) error {
 }
 
 func (conn *Connection) request(ops uint64, data, payload []byte) (*AfcPacket, error) {
+	return conn.requestContext(context.Background(), ops, data, payload)
+}
+
+//newPacket creates the packet for a request and assigns the next packet number to it
+func (conn *Connection) newPacket(ops uint64, data, payload []byte) AfcPacket {
 	header := AfcPacketHeader{
 		Magic:         Afc_magic,
 		Packet_num:    conn.packageNumber,
@@ -61,28 +67,41 @@ func (conn *Connection) request(ops uint64, data, payload []byte) (*AfcPacket, e
 		This_length:   Afc_header_size + uint64(len(data)),
 		Entire_length: Afc_header_size + uint64(len(data)+len(payload)),
 	}
-
-	packet := AfcPacket{
+	conn.packageNumber++
+	return AfcPacket{
 		Header:        header,
 		HeaderPayload: data,
 		Payload:       payload,
 	}
+}
 
-	conn.packageNumber++
-	response, err := conn.sendAfcPacketAndAwaitResponse(packet)
+//requestContext sends a single afc request and waits for the response. It is bounded by ctx, see ios.RunWithContext.
+func (conn *Connection) requestContext(ctx context.Context, ops uint64, data, payload []byte) (*AfcPacket, error) {
+	packet := conn.newPacket(ops, data, payload)
+	var response AfcPacket
+	err := ios.RunWithContext(ctx, conn.deviceConn, func() error {
+		var err error
+		response, err = conn.sendAfcPacketAndAwaitResponse(packet)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 	if err = conn.checkOperationStatus(response); err != nil {
-		return nil, fmt.Errorf("unexpected afc status: %v", err)
+		return nil, fmt.Errorf("unexpected afc status: %w", err)
 	}
 	return &response, nil
 }
 
 func (conn *Connection) RemovePath(path string) error {
+	return conn.RemovePathContext(context.Background(), path)
+}
+
+//RemovePathContext removes a file or an empty directory, bounded by ctx.
+func (conn *Connection) RemovePathContext(ctx context.Context, path string) error {
 	conn.mutex.Lock()
 	defer conn.mutex.Unlock()
-	_, err := conn.request(Afc_operation_remove_path, []byte(path), nil)
+	_, err := conn.requestContext(ctx, Afc_operation_remove_path, []byte(path), nil)
 	return err
 }
 
@@ -97,18 +116,28 @@ func (conn *Connection) RenamePath(from, to string) error {
 }
 
 func (conn *Connection) MakeDir(path string) error {
+	return conn.MakeDirContext(context.Background(), path)
+}
+
+//MakeDirContext creates a directory, bounded by ctx.
+func (conn *Connection) MakeDirContext(ctx context.Context, path string) error {
 	conn.mutex.Lock()
 	defer conn.mutex.Unlock()
-	_, err := conn.request(Afc_operation_make_dir, []byte(path), nil)
+	_, err := conn.requestContext(ctx, Afc_operation_make_dir, []byte(path), nil)
 	return err
 }
 
 func (conn *Connection) Stat(path string) (*StatInfo, error) {
+	return conn.StatContext(context.Background(), path)
+}
+
+//StatContext returns the StatInfo of path, bounded by ctx.
+func (conn *Connection) StatContext(ctx context.Context, path string) (*StatInfo, error) {
 	conn.mutex.Lock()
-	response, err := conn.request(Afc_operation_file_info, []byte(path), nil)
+	response, err := conn.requestContext(ctx, Afc_operation_file_info, []byte(path), nil)
 	if err != nil {
 		conn.mutex.Unlock()
-		return nil, fmt.Errorf("cannot stat '%v': %v", path, err)
+		return nil, fmt.Errorf("cannot stat '%v': %w", path, err)
 	}
 	conn.mutex.Unlock()
 
@@ -137,9 +166,14 @@ func (conn *Connection) Stat(path string) (*StatInfo, error) {
 }
 
 func (conn *Connection) ReadDir(path string) ([]string, error) {
+	return conn.ReadDirContext(context.Background(), path)
+}
+
+//ReadDirContext lists the entries of the directory at path without "." and "..", bounded by ctx.
+func (conn *Connection) ReadDirContext(ctx context.Context, path string) ([]string, error) {
 	// log.Infof("ReadDir path:%v", path)
 	conn.mutex.Lock()
-	response, err := conn.request(Afc_operation_read_dir, []byte(path), nil)
+	response, err := conn.requestContext(ctx, Afc_operation_read_dir, []byte(path), nil)
 	if err != nil {
 		conn.mutex.Unlock()
 		log.Infof("ReadDir error:%v", err)
@@ -160,12 +194,17 @@ func (conn *Connection) ReadDir(path string) ([]string, error) {
 }
 
 func (conn *Connection) OpenFile(path string, mode uint64) (uint64, error) {
+	return conn.OpenFileContext(context.Background(), path, mode)
+}
+
+//OpenFileContext opens the file at path with mode and returns its file descriptor, bounded by ctx.
+func (conn *Connection) OpenFileContext(ctx context.Context, path string, mode uint64) (uint64, error) {
 	// log.Infof("OpenFile path:%v", path)
 	data := make([]byte, 8+len(path)+1)
 	binary.LittleEndian.PutUint64(data, mode)
 	copy(data[8:], path)
 	conn.mutex.Lock()
-	response, err := conn.request(Afc_operation_file_open, data, make([]byte, 0))
+	response, err := conn.requestContext(ctx, Afc_operation_file_open, data, make([]byte, 0))
 	if err != nil {
 		conn.mutex.Unlock()
 		log.Errorf("OpenFile path:%v err:%v", path, err)
@@ -182,6 +221,11 @@ func (conn *Connection) OpenFile(path string, mode uint64) (uint64, error) {
 }
 
 func (conn *Connection) ReadFile(fd uint64, p []byte) (n int, err error) {
+	return conn.ReadFileContext(context.Background(), fd, p)
+}
+
+//ReadFileContext reads up to len(p) bytes from the file fd into p, bounded by ctx.
+func (conn *Connection) ReadFileContext(ctx context.Context, fd uint64, p []byte) (n int, err error) {
 	// log.Infof("ReadFile inbuf pd:%v, read len:%v", fd, len(p))
 	// defer log.Info("ReadFile end")
 	data := make([]byte, 16)
@@ -189,7 +233,7 @@ func (conn *Connection) ReadFile(fd uint64, p []byte) (n int, err error) {
 	binary.LittleEndian.PutUint64(data[8:], uint64(len(p)))
 
 	conn.mutex.Lock()
-	response, err := conn.request(Afc_operation_file_read, data, nil)
+	response, err := conn.requestContext(ctx, Afc_operation_file_read, data, nil)
 	if err != nil {
 		conn.mutex.Unlock()
 		return 0, err
@@ -213,22 +257,32 @@ func (conn *Connection) ReadFile(fd uint64, p []byte) (n int, err error) {
 }
 
 func (conn *Connection) WriteFile(fd uint64, p []byte) (n int, err error) {
+	return conn.WriteFileContext(context.Background(), fd, p)
+}
+
+//WriteFileContext writes p to the file fd, bounded by ctx.
+func (conn *Connection) WriteFileContext(ctx context.Context, fd uint64, p []byte) (n int, err error) {
 	data := make([]byte, 8)
 	binary.LittleEndian.PutUint64(data, fd)
 
 	conn.mutex.Lock()
 	defer conn.mutex.Unlock()
-	_, err = conn.request(Afc_operation_file_write, data, p)
+	_, err = conn.requestContext(ctx, Afc_operation_file_write, data, p)
 	return len(p), err
 }
 
 func (conn *Connection) CloseFile(fd uint64) error {
+	return conn.CloseFileContext(context.Background(), fd)
+}
+
+//CloseFileContext closes the file fd, bounded by ctx.
+func (conn *Connection) CloseFileContext(ctx context.Context, fd uint64) error {
 	data := make([]byte, 8)
 	binary.LittleEndian.PutUint64(data, fd)
 
 	conn.mutex.Lock()
 	defer conn.mutex.Unlock()
-	_, err := conn.request(Afc_operation_file_close, data, nil)
+	_, err := conn.requestContext(ctx, Afc_operation_file_close, data, nil)
 	return err
 }
 
@@ -285,7 +339,7 @@ func (conn *Connection) TellFile(fd uint64) (uint64, error) {
 func (conn *Connection) TruncateFile(fd uint64, size int64) error {
 	data := make([]byte, 16)
 	binary.LittleEndian.PutUint64(data, fd)
-	binary.LittleEndian.PutUint64(data, uint64(size))
+	binary.LittleEndian.PutUint64(data[8:], uint64(size))
 
 	conn.mutex.Lock()
 	defer conn.mutex.Unlock()
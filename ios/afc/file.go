@@ -1,10 +1,10 @@
 package afc
 
 import (
+	"errors"
 	log "github.com/sirupsen/logrus"
 	"os"
 	"path"
-	"strings"
 	"syscall"
 	"time"
 )
@@ -65,7 +65,7 @@ func (f *File) Readdir(count int) (fi []os.FileInfo, err error) {
 	for _, entry := range files {
 		fileInfo, err := f.conn.Stat(path.Join(f.absPath, entry))
 		if err != nil {
-			if strings.Contains(err.Error(), getError(Afc_Err_PermDenied).Error()) {
+			if errors.Is(err, ErrPermDenied) {
 				log.Errorf("Readdir: %v", err)
 				fileInfo = &StatInfo{
 					name:         entry,
@@ -77,6 +77,10 @@ func (f *File) Readdir(count int) (fi []os.FileInfo, err error) {
 					stIfmt:       "",
 					stLinktarget: "",
 				}
+			} else {
+				// f.ex. the entry was removed in the meantime
+				log.Warnf("Readdir: %v", err)
+				continue
 			}
 		}
 		fi = append(fi, fileInfo)
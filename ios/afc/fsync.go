@@ -342,7 +342,7 @@ func (fs *Fsync) Chown(name string, uid, gid int) error {
 }
 
 func (fs *Fsync) Chtimes(name string, atime time.Time, mtime time.Time) error {
-	return nil
+	return fs.SetFileTime(name, mtime)
 }
 
 func (fs *Fsync) RmTree(path string) error {
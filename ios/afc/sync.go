@@ -0,0 +1,372 @@
+package afc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// SyncDirection is the direction of Sync
+type SyncDirection string
+
+const (
+	// SyncPush copies from the host to the device
+	SyncPush SyncDirection = "push"
+	// SyncPull copies from the device to the host
+	SyncPull SyncDirection = "pull"
+)
+
+// SyncActionType is what Sync does with a path
+type SyncActionType string
+
+const (
+	SyncCopy   SyncActionType = "copy"
+	SyncMkdir  SyncActionType = "mkdir"
+	SyncDelete SyncActionType = "delete"
+)
+
+// SyncAction is a change Sync makes at the destination. Path is relative to the destination directory.
+type SyncAction struct {
+	Type SyncActionType `json:"type"`
+	Path string         `json:"path"`
+	Size int64          `json:"size,omitempty"`
+}
+
+// SyncResult counts the changes of Sync. With SyncOptions.DryRun, they are the changes that would have been made.
+type SyncResult struct {
+	Copied      int   `json:"copied"`
+	Created     int   `json:"created"`
+	Deleted     int   `json:"deleted"`
+	Unchanged   int   `json:"unchanged"`
+	Transferred int64 `json:"transferred"`
+}
+
+// SyncOptions configures Sync
+type SyncOptions struct {
+	Direction SyncDirection
+	// Delete removes files and directories from the destination that do not exist at the source
+	Delete bool
+	// DryRun only reports the actions without changing the destination
+	DryRun bool
+	// Include and Exclude are path.Match patterns that are matched against the path relative to the source
+	// directory and against the file name. Excluded files and directories are skipped, if Include is set only
+	// files matching one of its patterns are synced. Both apply to Delete too.
+	Include []string
+	Exclude []string
+	// Action is called before every change that is made
+	Action func(SyncAction)
+	// Transfer configures copying the files
+	Transfer TransferOptions
+}
+
+// Sync makes the directory dst a copy of the directory src. Files are copied only if their size or modification
+// time differ, afterwards their modification time is set to the one of the source. In the SyncPush direction src
+// is a host and dst a device path, in the SyncPull direction it is the other way around.
+func (fs *Fsync) Sync(src, dst string, options SyncOptions) (SyncResult, error) {
+	options.Transfer = options.Transfer.withDefaults()
+	s := &syncer{fs: fs, options: options}
+	switch options.Direction {
+	case SyncPush:
+		s.src, s.dst = hostTree{root: src}, deviceTree{fs: fs, root: dst}
+	case SyncPull:
+		s.src, s.dst = deviceTree{fs: fs, root: src}, hostTree{root: dst}
+	default:
+		return SyncResult{}, fmt.Errorf("invalid sync direction '%s', use %s or %s", options.Direction, SyncPush, SyncPull)
+	}
+	for _, patterns := range [][]string{options.Include, options.Exclude} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return SyncResult{}, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+			}
+		}
+	}
+	info, err := s.src.stat("")
+	if err != nil {
+		return SyncResult{}, err
+	}
+	if !info.IsDir() {
+		return SyncResult{}, fmt.Errorf("sync source %s is not a directory", src)
+	}
+	info, err = s.dst.stat("")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return SyncResult{}, err
+	}
+	if err != nil {
+		err = s.mkdir("")
+	} else if !info.IsDir() {
+		err = fmt.Errorf("sync destination %s is not a directory", dst)
+	}
+	if err != nil {
+		return SyncResult{}, err
+	}
+	err = s.syncDir("")
+	return s.result, err
+}
+
+type syncer struct {
+	fs       *Fsync
+	options  SyncOptions
+	src, dst syncTree
+	result   SyncResult
+}
+
+func (s *syncer) syncDir(dir string) error {
+	srcEntries, err := s.src.list(dir)
+	if err != nil {
+		return err
+	}
+	dstEntries, err := s.dst.list(dir)
+	if err != nil {
+		return err
+	}
+	for _, name := range sortedNames(srcEntries) {
+		p := path.Join(dir, name)
+		srcInfo := srcEntries[name]
+		if !s.selected(p, srcInfo.IsDir()) {
+			continue
+		}
+		dstInfo, exists := dstEntries[name]
+		if exists && dstInfo.IsDir() != srcInfo.IsDir() {
+			// a file replaces a directory or the other way around
+			if err := s.remove(p); err != nil {
+				return err
+			}
+			exists = false
+		}
+		if srcInfo.IsDir() {
+			if !exists {
+				if err := s.mkdir(p); err != nil {
+					return err
+				}
+			}
+			if err := s.syncDir(p); err != nil {
+				return err
+			}
+			continue
+		}
+		if exists && unchanged(srcInfo, dstInfo) {
+			s.result.Unchanged++
+			continue
+		}
+		if err := s.copy(p, srcInfo); err != nil {
+			return err
+		}
+	}
+	if !s.options.Delete {
+		return nil
+	}
+	for _, name := range sortedNames(dstEntries) {
+		if _, ok := srcEntries[name]; !ok {
+			if err := s.deleteExtraneous(path.Join(dir, name), dstEntries[name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deleteExtraneous removes p from the destination because it does not exist at the source.
+// Directories are removed as a whole unless Include patterns are set, only matching files are removed then.
+func (s *syncer) deleteExtraneous(p string, info os.FileInfo) error {
+	if !s.selected(p, info.IsDir()) {
+		return nil
+	}
+	if !info.IsDir() || len(s.options.Include) == 0 {
+		return s.remove(p)
+	}
+	entries, err := s.dst.list(p)
+	if err != nil {
+		return err
+	}
+	for _, name := range sortedNames(entries) {
+		if err := s.deleteExtraneous(path.Join(p, name), entries[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selected checks the Include and Exclude patterns for p, directories are only checked against Exclude
+func (s *syncer) selected(p string, isDir bool) bool {
+	if matchAny(s.options.Exclude, p) {
+		return false
+	}
+	return isDir || len(s.options.Include) == 0 || matchAny(s.options.Include, p)
+}
+
+func (s *syncer) copy(p string, info os.FileInfo) error {
+	s.action(SyncAction{Type: SyncCopy, Path: p, Size: info.Size()})
+	s.result.Copied++
+	s.result.Transferred += info.Size()
+	if s.options.DryRun {
+		return nil
+	}
+	srcPath, dstPath := s.src.path(p), s.dst.path(p)
+	var err error
+	if s.options.Direction == SyncPush {
+		err = s.fs.pushFile(srcPath, dstPath, info.Size(), s.options.Transfer)
+	} else {
+		err = s.fs.pullFile(srcPath, dstPath, info.(*StatInfo), s.options.Transfer)
+	}
+	if err != nil {
+		return fmt.Errorf("failed copying %s to %s: %w", srcPath, dstPath, err)
+	}
+	return s.dst.setModTime(p, info.ModTime())
+}
+
+func (s *syncer) mkdir(p string) error {
+	s.action(SyncAction{Type: SyncMkdir, Path: p})
+	s.result.Created++
+	if s.options.DryRun {
+		return nil
+	}
+	return s.dst.mkdir(p)
+}
+
+func (s *syncer) remove(p string) error {
+	s.action(SyncAction{Type: SyncDelete, Path: p})
+	s.result.Deleted++
+	if s.options.DryRun {
+		return nil
+	}
+	return s.dst.remove(p)
+}
+
+func (s *syncer) action(action SyncAction) {
+	if action.Path == "" {
+		action.Path = "."
+	}
+	if s.options.Action != nil {
+		s.options.Action(action)
+	}
+}
+
+// unchanged compares size and modification time, with a precision of one second because not all filesystems
+// store more
+func unchanged(src, dst os.FileInfo) bool {
+	return src.Size() == dst.Size() && src.ModTime().Truncate(time.Second).Equal(dst.ModTime().Truncate(time.Second))
+}
+
+func matchAny(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(p)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedNames(entries map[string]os.FileInfo) []string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// syncTree is the source or destination of Sync, paths are relative to its root and use slashes
+type syncTree interface {
+	path(p string) string
+	stat(p string) (os.FileInfo, error)
+	// list returns the entries of the directory p, no entries if it does not exist
+	list(p string) (map[string]os.FileInfo, error)
+	mkdir(p string) error
+	remove(p string) error
+	setModTime(p string, t time.Time) error
+}
+
+type hostTree struct {
+	root string
+}
+
+func (h hostTree) path(p string) string {
+	return filepath.Join(h.root, filepath.FromSlash(p))
+}
+
+func (h hostTree) stat(p string) (os.FileInfo, error) {
+	return os.Stat(h.path(p))
+}
+
+func (h hostTree) list(p string) (map[string]os.FileInfo, error) {
+	entries, err := os.ReadDir(h.path(p))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	infos := make(map[string]os.FileInfo, len(entries))
+	for _, entry := range entries {
+		// follow symlinks
+		info, err := os.Stat(filepath.Join(h.path(p), entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		infos[entry.Name()] = info
+	}
+	return infos, nil
+}
+
+func (h hostTree) mkdir(p string) error {
+	return os.MkdirAll(h.path(p), 0755)
+}
+
+func (h hostTree) remove(p string) error {
+	return os.RemoveAll(h.path(p))
+}
+
+func (h hostTree) setModTime(p string, t time.Time) error {
+	return os.Chtimes(h.path(p), t, t)
+}
+
+type deviceTree struct {
+	fs   *Fsync
+	root string
+}
+
+func (d deviceTree) path(p string) string {
+	return path.Join(d.root, p)
+}
+
+func (d deviceTree) stat(p string) (os.FileInfo, error) {
+	return d.fs.Connection.Stat(d.path(p))
+}
+
+func (d deviceTree) list(p string) (map[string]os.FileInfo, error) {
+	names, err := d.fs.ReadDir(d.path(p))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	infos := make(map[string]os.FileInfo, len(names))
+	for _, name := range names {
+		info, err := d.fs.Connection.Stat(path.Join(d.path(p), name))
+		if err != nil {
+			return nil, err
+		}
+		infos[name] = info
+	}
+	return infos, nil
+}
+
+func (d deviceTree) mkdir(p string) error {
+	return d.fs.MakeDir(d.path(p))
+}
+
+func (d deviceTree) remove(p string) error {
+	return d.fs.RemovePathAndContents(d.path(p))
+}
+
+func (d deviceTree) setModTime(p string, t time.Time) error {
+	return d.fs.SetFileTime(d.path(p), t)
+}
@@ -0,0 +1,107 @@
+package afc_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios/afc"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	}
+}
+
+func TestSyncPush(t *testing.T) {
+	fsync, memFs := startAfc(t)
+	src := t.TempDir()
+	writeFiles(t, src, map[string]string{
+		"a.txt":          "a",
+		"nested/b.txt":   "b",
+		"nested/c.log":   "c",
+		"ignored/d.txt":  "d",
+		"nested/e.cache": "e",
+	})
+	options := afc.SyncOptions{Direction: afc.SyncPush, Exclude: []string{"ignored", "*.cache"}}
+
+	result, err := fsync.Sync(src, "/Documents", options)
+	require.NoError(t, err)
+	assert.Equal(t, afc.SyncResult{Copied: 3, Created: 2, Transferred: 3}, result)
+	b, err := afero.ReadFile(memFs, "/Documents/nested/b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(b))
+	exists, _ := afero.Exists(memFs, "/Documents/ignored")
+	assert.False(t, exists)
+	exists, _ = afero.Exists(memFs, "/Documents/nested/e.cache")
+	assert.False(t, exists)
+	local, err := os.Stat(filepath.Join(src, "nested", "b.txt"))
+	require.NoError(t, err)
+	remote, err := memFs.Stat("/Documents/nested/b.txt")
+	require.NoError(t, err)
+	assert.True(t, local.ModTime().Equal(remote.ModTime()))
+
+	// only changed files are copied again
+	writeFiles(t, src, map[string]string{"a.txt": "changed"})
+	result, err = fsync.Sync(src, "/Documents", options)
+	require.NoError(t, err)
+	assert.Equal(t, afc.SyncResult{Copied: 1, Unchanged: 2, Transferred: 7}, result)
+
+	// extraneous files are deleted unless they are excluded, a dry run changes nothing
+	require.NoError(t, afero.WriteFile(memFs, "/Documents/extra/f.txt", []byte("f"), 0644))
+	require.NoError(t, afero.WriteFile(memFs, "/Documents/keep.cache", []byte("g"), 0644))
+	options.Delete = true
+	options.DryRun = true
+	var actions []afc.SyncAction
+	options.Action = func(action afc.SyncAction) { actions = append(actions, action) }
+	result, err = fsync.Sync(src, "/Documents", options)
+	require.NoError(t, err)
+	assert.Equal(t, []afc.SyncAction{{Type: afc.SyncDelete, Path: "extra"}}, actions)
+	exists, _ = afero.Exists(memFs, "/Documents/extra/f.txt")
+	assert.True(t, exists)
+
+	options.DryRun = false
+	_, err = fsync.Sync(src, "/Documents", options)
+	require.NoError(t, err)
+	exists, _ = afero.Exists(memFs, "/Documents/extra")
+	assert.False(t, exists)
+	exists, _ = afero.Exists(memFs, "/Documents/keep.cache")
+	assert.True(t, exists)
+}
+
+func TestSyncPull(t *testing.T) {
+	fsync, memFs := startAfc(t)
+	mtime := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, afero.WriteFile(memFs, "/Documents/a.txt", []byte("a"), 0644))
+	require.NoError(t, afero.WriteFile(memFs, "/Documents/nested/b.log", []byte("b"), 0644))
+	require.NoError(t, memFs.Chtimes("/Documents/a.txt", mtime, mtime))
+	dst := t.TempDir()
+	writeFiles(t, dst, map[string]string{"extra.txt": "x", "extra.log": "y"})
+
+	options := afc.SyncOptions{Direction: afc.SyncPull, Include: []string{"*.txt"}, Delete: true}
+	result, err := fsync.Sync("/Documents", dst, options)
+	require.NoError(t, err)
+	assert.Equal(t, afc.SyncResult{Copied: 1, Created: 1, Deleted: 1, Transferred: 1}, result)
+	b, err := os.ReadFile(filepath.Join(dst, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(b))
+	info, err := os.Stat(filepath.Join(dst, "a.txt"))
+	require.NoError(t, err)
+	assert.True(t, mtime.Equal(info.ModTime()))
+	_, err = os.Stat(filepath.Join(dst, "nested", "b.log"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dst, "extra.txt"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dst, "extra.log"))
+	assert.NoError(t, err)
+
+	_, err = fsync.Sync("/Documents", dst, afc.SyncOptions{Direction: "sideways"})
+	assert.Error(t, err)
+}
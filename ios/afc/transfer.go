@@ -0,0 +1,443 @@
+package afc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+const (
+	defaultTransferWindow    = 8
+	defaultTransferChunkSize = 256 * 1024
+	defaultProgressInterval  = 500 * time.Millisecond
+)
+
+// ErrChecksumMismatch is returned when the SHA-256 of a transferred file differs between source and destination
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// TransferProgress is the progress of the file that is currently transferred. Transferred includes the bytes
+// that were already at the destination when a transfer was resumed, BytesPerSecond only counts the new ones.
+type TransferProgress struct {
+	Path           string  `json:"path"`
+	Transferred    int64   `json:"transferred"`
+	Total          int64   `json:"total"`
+	BytesPerSecond float64 `json:"bytesPerSecond"`
+	Done           bool    `json:"done"`
+}
+
+// TransferOptions configures PullWithOptions and PushWithOptions
+type TransferOptions struct {
+	// Resume continues files at the size of the existing destination file instead of overwriting them.
+	// Files that are bigger at the destination than at the source are transferred again.
+	Resume bool
+	// Verify compares the SHA-256 of source and destination after every file and fails with ErrChecksumMismatch.
+	// Device files are read back for this.
+	Verify bool
+	// Progress is called at most every ProgressInterval (default 500ms) while a file is transferred and once it is done
+	Progress         func(TransferProgress)
+	ProgressInterval time.Duration
+	// Window is the number of read or write requests that are sent without waiting for their responses (default 8)
+	Window int
+	// ChunkSize is the number of bytes per read or write request (default 256KB)
+	ChunkSize int
+}
+
+func (options TransferOptions) withDefaults() TransferOptions {
+	if options.Window <= 0 {
+		options.Window = defaultTransferWindow
+	}
+	if options.ChunkSize <= 0 {
+		options.ChunkSize = defaultTransferChunkSize
+	}
+	if options.ProgressInterval <= 0 {
+		options.ProgressInterval = defaultProgressInterval
+	}
+	return options
+}
+
+// PullWithOptions copies the file or directory at srcPath on the device to dstPath on the host
+func (fs *Fsync) PullWithOptions(srcPath, dstPath string, options TransferOptions) error {
+	options = options.withDefaults()
+	info, err := fs.Connection.Stat(srcPath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fs.pullFile(srcPath, dstPath, info, options)
+	}
+	err = os.MkdirAll(dstPath, 0755)
+	if err != nil {
+		return err
+	}
+	entries, err := fs.ReadDir(srcPath)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		err = fs.PullWithOptions(path.Join(srcPath, entry), filepath.Join(dstPath, entry), options)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fs *Fsync) pullFile(srcPath, dstPath string, info *StatInfo, options TransferOptions) error {
+	if info.IsLink() {
+		var err error
+		srcPath = info.stLinktarget
+		info, err = fs.Connection.Stat(srcPath)
+		if err != nil {
+			return err
+		}
+	}
+	total := info.Size()
+	var offset int64
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if options.Resume {
+		if local, err := os.Stat(dstPath); err == nil && local.Size() <= total {
+			offset = local.Size()
+			flag = os.O_WRONLY | os.O_CREATE
+		}
+	}
+	f, err := os.OpenFile(dstPath, flag, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	fd, err := fs.Connection.OpenFile(srcPath, Afc_Mode_RDONLY)
+	if err != nil {
+		return err
+	}
+	defer fs.CloseFile(fd)
+	if offset > 0 {
+		_, err = fs.SeekFile(fd, offset, io.SeekStart)
+		if err != nil {
+			return err
+		}
+	}
+
+	progress := newProgressReporter(srcPath, offset, total, options)
+	err = fs.readPipelined(fd, total-offset, options, func(chunk []byte) error {
+		_, err := f.Write(chunk)
+		progress.add(len(chunk))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	progress.done()
+	if !options.Verify {
+		return nil
+	}
+	return fs.verify(srcPath, dstPath, options)
+}
+
+// PushWithOptions copies the file or directory at srcPath on the host to dstPath on the device.
+// If dstPath is an existing directory, srcPath is copied into it like Push does.
+func (fs *Fsync) PushWithOptions(srcPath, dstPath string, options TransferOptions) error {
+	options = options.withDefaults()
+	local, err := os.Stat(srcPath)
+	if err != nil {
+		return err
+	}
+	remote, err := fs.Connection.Stat(dstPath)
+	if err == nil && remote.IsDir() {
+		dstPath = path.Join(dstPath, filepath.Base(srcPath))
+	}
+	return fs.push(srcPath, dstPath, local, options)
+}
+
+func (fs *Fsync) push(srcPath, dstPath string, local os.FileInfo, options TransferOptions) error {
+	if !local.IsDir() {
+		return fs.pushFile(srcPath, dstPath, local.Size(), options)
+	}
+	err := fs.MkdirAll(dstPath, 0755)
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(srcPath)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		err = fs.push(filepath.Join(srcPath, entry.Name()), path.Join(dstPath, entry.Name()), info, options)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fs *Fsync) pushFile(srcPath, dstPath string, total int64, options TransferOptions) error {
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var offset int64
+	mode := Afc_Mode_WR
+	if options.Resume {
+		if remote, err := fs.Connection.Stat(dstPath); err == nil && !remote.IsDir() && remote.Size() <= total {
+			offset = remote.Size()
+			mode = Afc_Mode_RW
+		}
+	}
+	fd, err := fs.Connection.OpenFile(dstPath, mode)
+	if err != nil {
+		return err
+	}
+	defer fs.CloseFile(fd)
+	if offset > 0 {
+		_, err = fs.SeekFile(fd, offset, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		_, err = f.Seek(offset, io.SeekStart)
+		if err != nil {
+			return err
+		}
+	}
+
+	progress := newProgressReporter(dstPath, offset, total, options)
+	err = fs.writePipelined(fd, f, options, progress.add)
+	if err != nil {
+		return err
+	}
+	progress.done()
+	if !options.Verify {
+		return nil
+	}
+	return fs.verify(dstPath, srcPath, options)
+}
+
+// verify compares the SHA-256 of the device file at devicePath and the host file at hostPath
+func (fs *Fsync) verify(devicePath, hostPath string, options TransferOptions) error {
+	deviceHash, err := fs.fileHash(devicePath, options)
+	if err != nil {
+		return fmt.Errorf("failed hashing %s: %w", devicePath, err)
+	}
+	f, err := os.Open(hostPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hostHash := sha256.New()
+	_, err = io.Copy(hostHash, f)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(deviceHash, hostHash.Sum(nil)) {
+		return fmt.Errorf("%s and %s: %w", devicePath, hostPath, ErrChecksumMismatch)
+	}
+	return nil
+}
+
+// FileHash returns the SHA-256 of the file at path on the device. The file is read with pipelined requests.
+func (fs *Fsync) FileHash(path string) ([]byte, error) {
+	return fs.fileHash(path, TransferOptions{}.withDefaults())
+}
+
+func (fs *Fsync) fileHash(path string, options TransferOptions) ([]byte, error) {
+	info, err := fs.Connection.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := fs.Connection.OpenFile(path, Afc_Mode_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CloseFile(fd)
+	h := sha256.New()
+	err = fs.readPipelined(fd, info.Size(), options, func(chunk []byte) error {
+		_, err := h.Write(chunk)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// readPipelined reads length bytes from the current position of fd and passes them to handle in order.
+// Reads can return less than requested, so more reads are sent until length bytes were received. If a read
+// returns nothing before, the file got shorter and io.ErrUnexpectedEOF is returned.
+func (conn *Connection) readPipelined(fd uint64, length int64, options TransferOptions, handle func(chunk []byte) error) error {
+	var received int64
+	for received < length {
+		remaining := length - received
+		emptyRead := false
+		err := conn.pipeline(options.Window,
+			func() (AfcPacket, bool, error) {
+				if remaining <= 0 {
+					return AfcPacket{}, false, nil
+				}
+				size := int64(options.ChunkSize)
+				if remaining < size {
+					size = remaining
+				}
+				remaining -= size
+				data := make([]byte, 16)
+				binary.LittleEndian.PutUint64(data, fd)
+				binary.LittleEndian.PutUint64(data[8:], uint64(size))
+				return conn.newPacket(Afc_operation_file_read, data, nil), true, nil
+			},
+			func(response AfcPacket) error {
+				if len(response.Payload) == 0 {
+					emptyRead = true
+					return nil
+				}
+				received += int64(len(response.Payload))
+				return handle(response.Payload)
+			})
+		if err != nil {
+			return err
+		}
+		if emptyRead && received < length {
+			return fmt.Errorf("read %d of %d bytes: %w", received, length, io.ErrUnexpectedEOF)
+		}
+	}
+	return nil
+}
+
+// writePipelined writes everything from reader to the current position of fd and calls written for every chunk
+// the device confirmed
+func (conn *Connection) writePipelined(fd uint64, reader io.Reader, options TransferOptions, written func(n int)) error {
+	chunk := make([]byte, options.ChunkSize)
+	var sizes []int
+	return conn.pipeline(options.Window,
+		func() (AfcPacket, bool, error) {
+			n, err := io.ReadFull(reader, chunk)
+			if err == io.EOF {
+				return AfcPacket{}, false, nil
+			}
+			if err != nil && err != io.ErrUnexpectedEOF {
+				return AfcPacket{}, false, err
+			}
+			sizes = append(sizes, n)
+			data := make([]byte, 8)
+			binary.LittleEndian.PutUint64(data, fd)
+			return conn.newPacket(Afc_operation_file_write, data, chunk[:n]), true, nil
+		},
+		func(response AfcPacket) error {
+			written(sizes[0])
+			sizes = sizes[1:]
+			return nil
+		})
+}
+
+// pipeline sends the packets created by next while keeping up to window of them without a response and passes
+// the responses to handle in order. next returns false when there are no more packets. The packets are written
+// before next is called again, so next can reuse buffers.
+func (conn *Connection) pipeline(window int, next func() (AfcPacket, bool, error), handle func(AfcPacket) error) error {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	inFlight := 0
+	more := true
+	var err error
+	for more || inFlight > 0 {
+		for more && inFlight < window {
+			var packet AfcPacket
+			packet, more, err = next()
+			if err != nil {
+				return conn.drain(inFlight, err)
+			}
+			if !more {
+				break
+			}
+			err = Encode(packet, conn.deviceConn.Writer())
+			if err != nil {
+				return err
+			}
+			inFlight++
+		}
+		if inFlight == 0 {
+			break
+		}
+		response, err := Decode(conn.deviceConn.Reader())
+		if err != nil {
+			return err
+		}
+		inFlight--
+		err = conn.checkOperationStatus(response)
+		if err != nil {
+			return conn.drain(inFlight, fmt.Errorf("unexpected afc status: %w", err))
+		}
+		err = handle(response)
+		if err != nil {
+			return conn.drain(inFlight, err)
+		}
+	}
+	return nil
+}
+
+// drain reads the responses of requests that are still in flight after a pipeline failed with err,
+// so the connection can be used for further requests
+func (conn *Connection) drain(inFlight int, err error) error {
+	for ; inFlight > 0; inFlight-- {
+		_, readErr := Decode(conn.deviceConn.Reader())
+		if readErr != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// progressReporter calls TransferOptions.Progress for a file at most every ProgressInterval
+type progressReporter struct {
+	progress     TransferProgress
+	options      TransferOptions
+	start        time.Time
+	startOffset  int64
+	lastReported time.Time
+}
+
+func newProgressReporter(path string, offset int64, total int64, options TransferOptions) *progressReporter {
+	now := time.Now()
+	return &progressReporter{
+		progress:    TransferProgress{Path: path, Transferred: offset, Total: total},
+		options:     options,
+		start:       now,
+		startOffset: offset,
+	}
+}
+
+func (p *progressReporter) add(n int) {
+	p.progress.Transferred += int64(n)
+	if time.Since(p.lastReported) >= p.options.ProgressInterval {
+		p.report()
+	}
+}
+
+func (p *progressReporter) done() {
+	p.progress.Done = true
+	p.report()
+}
+
+func (p *progressReporter) report() {
+	if p.options.Progress == nil {
+		return
+	}
+	p.lastReported = time.Now()
+	if elapsed := p.lastReported.Sub(p.start).Seconds(); elapsed > 0 {
+		p.progress.BytesPerSecond = float64(p.progress.Transferred-p.startOffset) / elapsed
+	}
+	p.options.Progress(p.progress)
+}
@@ -0,0 +1,156 @@
+package afc_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/danielpaulus/go-ios/ios/afc"
+	"github.com/danielpaulus/go-ios/ios/usbmuxsim"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startAfc simulates a device that serves com.apple.afc from an in memory filesystem
+func startAfc(t *testing.T) (*afc.Fsync, afero.Fs) {
+	_, lockdownd, entry := usbmuxsim.StartPairedDevice(t, "udid0")
+	memFs := afero.NewMemMapFs()
+	lockdownd.HandleService("com.apple.afc", usbmuxsim.NewAfcService(memFs).Handle)
+	fsync, err := afc.New(entry)
+	require.NoError(t, err)
+	t.Cleanup(fsync.Close)
+	return fsync, memFs
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return b
+}
+
+func TestPushAndPullWithOptions(t *testing.T) {
+	fsync, memFs := startAfc(t)
+	content := randomBytes(t, 300*1024+17)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "file.bin")
+	require.NoError(t, os.WriteFile(src, content, 0644))
+	require.NoError(t, memFs.MkdirAll("/Documents", 0755))
+
+	var progress []afc.TransferProgress
+	options := afc.TransferOptions{
+		Verify:    true,
+		ChunkSize: 64 * 1024,
+		Window:    3,
+		Progress:  func(p afc.TransferProgress) { progress = append(progress, p) },
+	}
+	require.NoError(t, fsync.PushWithOptions(src, "/Documents", options))
+	pushed, err := afero.ReadFile(memFs, "/Documents/file.bin")
+	require.NoError(t, err)
+	assert.Equal(t, content, pushed)
+	require.NotEmpty(t, progress)
+	last := progress[len(progress)-1]
+	assert.True(t, last.Done)
+	assert.Equal(t, int64(len(content)), last.Transferred)
+	assert.Equal(t, int64(len(content)), last.Total)
+
+	dst := filepath.Join(dir, "pulled.bin")
+	require.NoError(t, fsync.PullWithOptions("/Documents/file.bin", dst, options))
+	pulled, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, content, pulled)
+
+	hash, err := fsync.FileHash("/Documents/file.bin")
+	require.NoError(t, err)
+	expected := sha256.Sum256(content)
+	assert.Equal(t, expected[:], hash)
+}
+
+func TestResume(t *testing.T) {
+	fsync, memFs := startAfc(t)
+	content := randomBytes(t, 200*1024)
+	dir := t.TempDir()
+
+	// the first half was pulled before
+	dst := filepath.Join(dir, "pulled.bin")
+	require.NoError(t, afero.WriteFile(memFs, "/file.bin", content, 0644))
+	require.NoError(t, os.WriteFile(dst, content[:100*1024], 0644))
+	var first *afc.TransferProgress
+	options := afc.TransferOptions{Resume: true, Verify: true, Progress: func(p afc.TransferProgress) {
+		if first == nil {
+			first = &p
+		}
+	}}
+	require.NoError(t, fsync.PullWithOptions("/file.bin", dst, options))
+	pulled, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, content, pulled)
+	require.NotNil(t, first)
+	assert.GreaterOrEqual(t, first.Transferred, int64(100*1024))
+
+	// the first part was pushed before
+	src := filepath.Join(dir, "push.bin")
+	require.NoError(t, os.WriteFile(src, content, 0644))
+	require.NoError(t, afero.WriteFile(memFs, "/push.bin", content[:1000], 0644))
+	require.NoError(t, fsync.PushWithOptions(src, "/push.bin", afc.TransferOptions{Resume: true, Verify: true}))
+	pushed, err := afero.ReadFile(memFs, "/push.bin")
+	require.NoError(t, err)
+	assert.Equal(t, content, pushed)
+
+	// a resumed file with different content is detected by the checksum
+	require.NoError(t, afero.WriteFile(memFs, "/push.bin", bytes.Repeat([]byte{1}, 1000), 0644))
+	err = fsync.PushWithOptions(src, "/push.bin", afc.TransferOptions{Resume: true, Verify: true})
+	assert.ErrorIs(t, err, afc.ErrChecksumMismatch)
+}
+
+func TestPullWithShortReads(t *testing.T) {
+	_, lockdownd, entry := usbmuxsim.StartPairedDevice(t, "udid0")
+	memFs := afero.NewMemMapFs()
+	service := usbmuxsim.NewAfcService(memFs)
+	service.SetMaxReadSize(64 * 1024)
+	lockdownd.HandleService("com.apple.afc", service.Handle)
+	fsync, err := afc.New(entry)
+	require.NoError(t, err)
+	defer fsync.Close()
+
+	// every 256KB read returns only 64KB, the rest has to be read again
+	content := randomBytes(t, 600*1024+17)
+	require.NoError(t, afero.WriteFile(memFs, "/file.bin", content, 0644))
+	dst := filepath.Join(t.TempDir(), "pulled.bin")
+	require.NoError(t, fsync.PullWithOptions("/file.bin", dst, afc.TransferOptions{Verify: true}))
+	pulled, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, content, pulled)
+
+	hash, err := fsync.FileHash("/file.bin")
+	require.NoError(t, err)
+	expected := sha256.Sum256(content)
+	assert.Equal(t, expected[:], hash)
+}
+
+func TestPushAndPullDirectory(t *testing.T) {
+	fsync, memFs := startAfc(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "fixtures")
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "nested"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "nested", "b.txt"), []byte("b"), 0644))
+
+	require.NoError(t, fsync.PushWithOptions(src, "/fixtures", afc.TransferOptions{}))
+	b, err := afero.ReadFile(memFs, "/fixtures/nested/b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(b))
+
+	dst := filepath.Join(dir, "pulled")
+	require.NoError(t, fsync.PullWithOptions("/fixtures", dst, afc.TransferOptions{}))
+	a, err := os.ReadFile(filepath.Join(dst, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(a))
+	b, err = os.ReadFile(filepath.Join(dst, "nested", "b.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(b))
+}
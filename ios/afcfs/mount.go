@@ -0,0 +1,66 @@
+package afcfs
+
+import (
+	"errors"
+	"time"
+)
+
+//ErrMountNotSupported is returned by Mount on platforms without FUSE support
+var ErrMountNotSupported = errors.New("mounting is only supported on linux")
+
+const (
+	defaultAttrTimeout = time.Second
+	defaultReadAhead   = 512 * 1024
+	defaultWriteBuffer = 256 * 1024
+)
+
+//MountOptions configures Mount, zero values are replaced by the defaults
+type MountOptions struct {
+	//AttrTimeout is how long the kernel caches attributes and directory entries, 1s by default
+	AttrTimeout time.Duration
+	//ReadAhead is the number of bytes that are read from the device at once, sequential reads are answered from
+	//this buffer. 512KB by default.
+	ReadAhead int
+	//WriteBuffer is the number of bytes of sequential writes that are collected before they are written to the
+	//device. Buffered data is written on flush, fsync and close of the file too. 256KB by default.
+	WriteBuffer int
+	//AllowOther allows other users to access the mounted filesystem
+	AllowOther bool
+	//Debug logs all FUSE requests
+	Debug bool
+}
+
+func (o MountOptions) withDefaults() MountOptions {
+	if o.AttrTimeout == 0 {
+		o.AttrTimeout = defaultAttrTimeout
+	}
+	if o.ReadAhead == 0 {
+		o.ReadAhead = defaultReadAhead
+	}
+	if o.WriteBuffer == 0 {
+		o.WriteBuffer = defaultWriteBuffer
+	}
+	return o
+}
+
+//MountedFs is a filesystem mounted with Mount
+type MountedFs struct {
+	mountPoint string
+	unmount    func() error
+	wait       func()
+}
+
+//MountPoint returns the directory the filesystem is mounted on
+func (m *MountedFs) MountPoint() string {
+	return m.mountPoint
+}
+
+//Unmount unmounts the filesystem, it fails if files are still in use
+func (m *MountedFs) Unmount() error {
+	return m.unmount()
+}
+
+//Wait blocks until the filesystem was unmounted, f.ex. with Unmount or fusermount -u
+func (m *MountedFs) Wait() {
+	m.wait()
+}
@@ -0,0 +1,415 @@
+//go:build linux
+// +build linux
+
+package afcfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+	"sync"
+	"syscall"
+
+	"github.com/danielpaulus/go-ios/ios/afc"
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+//Mount serves afs, f.ex. a VirtualRootFs or an afc.Fsync, with FUSE on mountPoint. The kernel caches attributes
+//and directory entries for options.AttrTimeout, reads are done in blocks of options.ReadAhead bytes and sequential
+//writes are collected in a buffer of options.WriteBuffer bytes. Because of that, write errors may be returned only
+//when the file is flushed or closed. Mount returns once the filesystem is mounted, use Unmount and Wait of the result
+//to stop serving it.
+func Mount(afs afero.Fs, mountPoint string, options MountOptions) (*MountedFs, error) {
+	options = options.withDefaults()
+	root := &node{afs: afs, options: options}
+	timeout := options.AttrTimeout
+	server, err := fs.Mount(mountPoint, root, &fs.Options{
+		MountOptions: fuse.MountOptions{
+			AllowOther:    options.AllowOther,
+			MaxReadAhead:  options.ReadAhead,
+			FsName:        afs.Name(),
+			Name:          "ios",
+			DisableXAttrs: true,
+			Debug:         options.Debug,
+			//use mount(2) when running as root, which works without fusermount, fusermount is used otherwise
+			DirectMount: true,
+		},
+		EntryTimeout: &timeout,
+		AttrTimeout:  &timeout,
+		UID:          uint32(os.Getuid()),
+		GID:          uint32(os.Getgid()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed mounting %s: %w", mountPoint, err)
+	}
+	return &MountedFs{mountPoint: mountPoint, unmount: server.Unmount, wait: server.Wait}, nil
+}
+
+//node is a file or directory of the mounted afero.Fs, its path is determined by its position in the tree of inodes
+type node struct {
+	fs.Inode
+	afs     afero.Fs
+	options MountOptions
+}
+
+var _ = (fs.NodeLookuper)((*node)(nil))
+var _ = (fs.NodeReaddirer)((*node)(nil))
+var _ = (fs.NodeGetattrer)((*node)(nil))
+var _ = (fs.NodeSetattrer)((*node)(nil))
+var _ = (fs.NodeOpener)((*node)(nil))
+var _ = (fs.NodeCreater)((*node)(nil))
+var _ = (fs.NodeMkdirer)((*node)(nil))
+var _ = (fs.NodeUnlinker)((*node)(nil))
+var _ = (fs.NodeRmdirer)((*node)(nil))
+var _ = (fs.NodeRenamer)((*node)(nil))
+
+func (n *node) path(name ...string) string {
+	return path.Join(append([]string{"/", n.Path(nil)}, name...)...)
+}
+
+func (n *node) newChild(ctx context.Context, info os.FileInfo, out *fuse.EntryOut) *fs.Inode {
+	fillAttr(info, &out.Attr)
+	child := &node{afs: n.afs, options: n.options}
+	return n.NewInode(ctx, child, fs.StableAttr{Mode: out.Attr.Mode & syscall.S_IFMT})
+}
+
+func (n *node) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	info, err := n.afs.Stat(n.path(name))
+	if err != nil {
+		return nil, errno(err)
+	}
+	return n.newChild(ctx, info, out), 0
+}
+
+func (n *node) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	dir, err := n.afs.Open(n.path())
+	if err != nil {
+		return nil, errno(err)
+	}
+	defer dir.Close()
+	//the names are enough, stating every entry of a directory on the device is slow and done by Lookup if needed
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return nil, errno(err)
+	}
+	entries := make([]fuse.DirEntry, len(names))
+	for i, name := range names {
+		entries[i] = fuse.DirEntry{Name: name}
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (n *node) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if h, ok := f.(*handle); ok {
+		//the size on the device is only correct once buffered writes were written
+		if status := h.Flush(ctx); status != 0 {
+			return status
+		}
+	}
+	info, err := n.afs.Stat(n.path())
+	if err != nil {
+		return errno(err)
+	}
+	fillAttr(info, &out.Attr)
+	return 0
+}
+
+//Setattr supports changing the size and the modification time, the device does not support permissions and owners
+func (n *node) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	if size, ok := in.GetSize(); ok {
+		if err := n.truncate(f, int64(size)); err != nil {
+			return errno(err)
+		}
+	}
+	if mtime, ok := in.GetMTime(); ok {
+		if err := n.afs.Chtimes(n.path(), mtime, mtime); err != nil {
+			return errno(err)
+		}
+	}
+	return n.Getattr(ctx, f, out)
+}
+
+func (n *node) truncate(f fs.FileHandle, size int64) error {
+	if h, ok := f.(*handle); ok {
+		return h.truncate(size)
+	}
+	file, err := n.afs.OpenFile(n.path(), os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	err = file.Truncate(size)
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+func (n *node) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	file, err := n.afs.OpenFile(n.path(), openFlags(flags), 0)
+	if err != nil {
+		return nil, 0, errno(err)
+	}
+	return newHandle(file, n.options), 0, 0
+}
+
+func (n *node) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*fs.Inode, fs.FileHandle, uint32, syscall.Errno) {
+	p := n.path(name)
+	file, err := n.afs.OpenFile(p, openFlags(flags)|os.O_CREATE, os.FileMode(mode).Perm())
+	if err != nil {
+		return nil, nil, 0, errno(err)
+	}
+	info, err := n.afs.Stat(p)
+	if err != nil {
+		file.Close()
+		return nil, nil, 0, errno(err)
+	}
+	return n.newChild(ctx, info, out), newHandle(file, n.options), 0, 0
+}
+
+func (n *node) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	p := n.path(name)
+	if err := n.afs.Mkdir(p, os.FileMode(mode).Perm()); err != nil {
+		return nil, errno(err)
+	}
+	info, err := n.afs.Stat(p)
+	if err != nil {
+		return nil, errno(err)
+	}
+	return n.newChild(ctx, info, out), 0
+}
+
+func (n *node) Unlink(ctx context.Context, name string) syscall.Errno {
+	return errno(n.afs.Remove(n.path(name)))
+}
+
+func (n *node) Rmdir(ctx context.Context, name string) syscall.Errno {
+	return errno(n.afs.Remove(n.path(name)))
+}
+
+func (n *node) Rename(ctx context.Context, name string, newParent fs.InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	//RENAME_NOREPLACE and RENAME_EXCHANGE can not be done atomically with afc
+	if flags != 0 {
+		return syscall.EINVAL
+	}
+	parent, ok := newParent.(*node)
+	if !ok {
+		return syscall.EXDEV
+	}
+	return errno(n.afs.Rename(n.path(name), parent.path(newName)))
+}
+
+//handle is an open file, it answers reads from a read ahead buffer and collects sequential writes in a write buffer
+type handle struct {
+	file    afero.File
+	options MountOptions
+	mux     sync.Mutex
+	//position is the offset of the file descriptor on the device, -1 if it is not known
+	position int64
+	//readBuffer contains the file content starting at readOffset, readEOF is set if it ends at the end of the file
+	readBuffer []byte
+	readOffset int64
+	readEOF    bool
+	//writeBuffer contains data that still has to be written at writeOffset
+	writeBuffer []byte
+	writeOffset int64
+}
+
+var _ = (fs.FileReader)((*handle)(nil))
+var _ = (fs.FileWriter)((*handle)(nil))
+var _ = (fs.FileFlusher)((*handle)(nil))
+var _ = (fs.FileFsyncer)((*handle)(nil))
+var _ = (fs.FileReleaser)((*handle)(nil))
+
+func newHandle(file afero.File, options MountOptions) *handle {
+	return &handle{file: file, options: options}
+}
+
+func (h *handle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if err := h.flush(); err != nil {
+		return nil, errno(err)
+	}
+	end := h.readOffset + int64(len(h.readBuffer))
+	buffered := off >= h.readOffset && off < end && (off+int64(len(dest)) <= end || h.readEOF)
+	if !buffered {
+		size := h.options.ReadAhead
+		if len(dest) > size {
+			size = len(dest)
+		}
+		if err := h.fill(off, size); err != nil {
+			return nil, errno(err)
+		}
+	}
+	start := off - h.readOffset
+	if start >= int64(len(h.readBuffer)) {
+		return fuse.ReadResultData(nil), 0
+	}
+	n := copy(dest, h.readBuffer[start:])
+	return fuse.ReadResultData(dest[:n]), 0
+}
+
+//fill reads up to size bytes starting at off into the read buffer
+func (h *handle) fill(off int64, size int) error {
+	h.readBuffer = h.readBuffer[:0]
+	if err := h.seek(off); err != nil {
+		return err
+	}
+	if cap(h.readBuffer) < size {
+		h.readBuffer = make([]byte, size)
+	}
+	buffer := h.readBuffer[:size]
+	h.readOffset = off
+	h.readEOF = false
+	n := 0
+	//afc returns io.EOF for every short read, only an empty read means that the end of the file was reached
+	for n < size {
+		read, err := h.file.Read(buffer[n:])
+		n += read
+		h.position += int64(read)
+		if err == io.EOF && read == 0 {
+			h.readEOF = true
+			break
+		}
+		if err != nil && err != io.EOF {
+			h.position = -1
+			return err
+		}
+	}
+	h.readBuffer = buffer[:n]
+	return nil
+}
+
+func (h *handle) Write(ctx context.Context, data []byte, off int64) (uint32, syscall.Errno) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.readBuffer = h.readBuffer[:0]
+	if len(h.writeBuffer) > 0 && off != h.writeOffset+int64(len(h.writeBuffer)) {
+		if err := h.flush(); err != nil {
+			return 0, errno(err)
+		}
+	}
+	if len(h.writeBuffer) == 0 {
+		h.writeOffset = off
+	}
+	h.writeBuffer = append(h.writeBuffer, data...)
+	if len(h.writeBuffer) >= h.options.WriteBuffer {
+		if err := h.flush(); err != nil {
+			return 0, errno(err)
+		}
+	}
+	return uint32(len(data)), 0
+}
+
+//flush writes the write buffer to the device
+func (h *handle) flush() error {
+	if len(h.writeBuffer) == 0 {
+		return nil
+	}
+	err := h.seek(h.writeOffset)
+	if err == nil {
+		var n int
+		n, err = h.file.Write(h.writeBuffer)
+		h.position += int64(n)
+	}
+	h.writeBuffer = h.writeBuffer[:0]
+	if err != nil {
+		h.position = -1
+	}
+	return err
+}
+
+func (h *handle) seek(off int64) error {
+	if h.position == off {
+		return nil
+	}
+	position, err := h.file.Seek(off, io.SeekStart)
+	if err != nil {
+		h.position = -1
+		return err
+	}
+	h.position = position
+	return nil
+}
+
+func (h *handle) truncate(size int64) error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if err := h.flush(); err != nil {
+		return err
+	}
+	h.readBuffer = h.readBuffer[:0]
+	return h.file.Truncate(size)
+}
+
+func (h *handle) Flush(ctx context.Context) syscall.Errno {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return errno(h.flush())
+}
+
+func (h *handle) Fsync(ctx context.Context, flags uint32) syscall.Errno {
+	return h.Flush(ctx)
+}
+
+func (h *handle) Release(ctx context.Context) syscall.Errno {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	err := h.flush()
+	closeErr := h.file.Close()
+	if err != nil {
+		return errno(err)
+	}
+	return errno(closeErr)
+}
+
+func fillAttr(info os.FileInfo, out *fuse.Attr) {
+	if info.IsDir() {
+		out.Mode = syscall.S_IFDIR | 0755
+	} else {
+		out.Mode = syscall.S_IFREG | 0644
+	}
+	out.Size = uint64(info.Size())
+	out.Blocks = (out.Size + 511) / 512
+	out.Nlink = 1
+	mtime := info.ModTime()
+	out.SetTimes(&mtime, &mtime, &mtime)
+}
+
+func openFlags(flags uint32) int {
+	return int(flags) & (syscall.O_ACCMODE | os.O_APPEND | os.O_TRUNC | os.O_CREATE)
+}
+
+//errno converts errors of afero.Fs and afc to the errno returned to the kernel
+func errno(err error) syscall.Errno {
+	if err == nil {
+		return 0
+	}
+	var e syscall.Errno
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, afc.ErrDirNotEmpty):
+		return syscall.ENOTEMPTY
+	case errors.Is(err, afc.ErrObjectIsDir):
+		return syscall.EISDIR
+	case errors.Is(err, afc.ErrNoSpaceLeft):
+		return syscall.ENOSPC
+	case errors.Is(err, iofs.ErrNotExist):
+		return syscall.ENOENT
+	case errors.Is(err, iofs.ErrExist):
+		return syscall.EEXIST
+	case errors.Is(err, iofs.ErrPermission):
+		return syscall.EACCES
+	}
+	log.Debugf("mount: %v", err)
+	return syscall.EIO
+}
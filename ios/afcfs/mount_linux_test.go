@@ -0,0 +1,84 @@
+//go:build linux
+// +build linux
+
+package afcfs_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/danielpaulus/go-ios/ios/afcfs"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//mount mounts an in memory filesystem served with afc, the test is skipped if FUSE is not available
+func mount(t *testing.T, options afcfs.MountOptions) (string, afero.Fs) {
+	fsync, memFs := startAfc(t)
+	mountPoint := t.TempDir()
+	mounted, err := afcfs.Mount(fsync, mountPoint, options)
+	if err != nil {
+		t.Skipf("FUSE is not available: %v", err)
+	}
+	t.Cleanup(func() {
+		assert.NoError(t, mounted.Unmount())
+		mounted.Wait()
+	})
+	return mountPoint, memFs
+}
+
+func TestMount(t *testing.T) {
+	mountPoint, memFs := mount(t, afcfs.MountOptions{ReadAhead: 4096, WriteBuffer: 4096})
+	require.NoError(t, afero.WriteFile(memFs, "/DCIM/photo.jpg", []byte("photo"), 0644))
+
+	entries, err := os.ReadDir(mountPoint)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "DCIM", entries[0].Name())
+	assert.True(t, entries[0].IsDir())
+	b, err := os.ReadFile(filepath.Join(mountPoint, "DCIM", "photo.jpg"))
+	require.NoError(t, err)
+	assert.Equal(t, "photo", string(b))
+
+	//larger than the buffers so reads and writes are split up
+	content := bytes.Repeat([]byte("0123456789"), 2000)
+	require.NoError(t, os.Mkdir(filepath.Join(mountPoint, "Downloads"), 0755))
+	file := filepath.Join(mountPoint, "Downloads", "file.txt")
+	require.NoError(t, os.WriteFile(file, content, 0644))
+	onDevice, err := afero.ReadFile(memFs, "/Downloads/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, content, onDevice)
+	info, err := os.Stat(file)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), info.Size())
+	b, err = os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, content, b)
+
+	require.NoError(t, os.Truncate(file, 10))
+	onDevice, err = afero.ReadFile(memFs, "/Downloads/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, content[:10], onDevice)
+
+	renamed := filepath.Join(mountPoint, "DCIM", "file.txt")
+	require.NoError(t, os.Rename(file, renamed))
+	names, err := afero.ReadDir(memFs, "/DCIM")
+	require.NoError(t, err)
+	var found []string
+	for _, n := range names {
+		found = append(found, n.Name())
+	}
+	sort.Strings(found)
+	assert.Equal(t, []string{"file.txt", "photo.jpg"}, found)
+
+	require.NoError(t, os.Remove(renamed))
+	assert.Error(t, os.Remove(filepath.Join(mountPoint, "DCIM")))
+	require.NoError(t, os.Remove(filepath.Join(mountPoint, "DCIM", "photo.jpg")))
+	require.NoError(t, os.Remove(filepath.Join(mountPoint, "DCIM")))
+	_, err = os.Stat(filepath.Join(mountPoint, "DCIM"))
+	assert.True(t, os.IsNotExist(err))
+}
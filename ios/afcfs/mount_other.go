@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package afcfs
+
+import "github.com/spf13/afero"
+
+//Mount is only supported on linux and returns ErrMountNotSupported
+func Mount(fs afero.Fs, mountPoint string, options MountOptions) (*MountedFs, error) {
+	return nil, ErrMountNotSupported
+}
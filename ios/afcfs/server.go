@@ -0,0 +1,203 @@
+package afcfs
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"html/template"
+	iofs "io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"golang.org/x/net/webdav"
+)
+
+//ServeOptions configures NewFileServer
+type ServeOptions struct {
+	//Username and Password enable HTTP basic authentication if Username is set
+	Username string
+	Password string
+}
+
+//NewFileServer serves afs, f.ex. a VirtualRootFs or an afc.Fsync, with WebDAV. Additionally GET requests for
+//directories return an HTML listing so the filesystem can be browsed with a browser. Files are served with
+//http.ServeContent, which supports range requests.
+func NewFileServer(afs afero.Fs, options ServeOptions) http.Handler {
+	return &fileServer{
+		afs:     afs,
+		options: options,
+		dav: &webdav.Handler{
+			FileSystem: webdavFs{afs},
+			LockSystem: webdav.NewMemLS(),
+			Logger: func(r *http.Request, err error) {
+				if err != nil {
+					log.Debugf("fsserve: %s %s failed: %v", r.Method, r.URL.Path, err)
+				}
+			},
+		},
+	}
+}
+
+type fileServer struct {
+	afs     afero.Fs
+	options ServeOptions
+	dav     *webdav.Handler
+}
+
+func (s *fileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="go-ios"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		name := path.Clean("/" + r.URL.Path)
+		info, err := s.afs.Stat(name)
+		if err == nil && info.IsDir() {
+			s.listDir(w, r, name)
+			return
+		}
+	}
+	s.dav.ServeHTTP(w, r)
+}
+
+func (s *fileServer) authorized(r *http.Request) bool {
+	if s.options.Username == "" {
+		return true
+	}
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	usernameOk := subtle.ConstantTimeCompare([]byte(username), []byte(s.options.Username)) == 1
+	passwordOk := subtle.ConstantTimeCompare([]byte(password), []byte(s.options.Password)) == 1
+	return usernameOk && passwordOk
+}
+
+type listEntry struct {
+	Name    string
+	Link    string
+	Size    int64
+	ModTime string
+	IsDir   bool
+}
+
+var listTemplate = template.Must(template.New("list").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Path}}</title></head>
+<body>
+<h1>{{.Path}}</h1>
+<table>
+<tr><th align="left">Name</th><th align="right">Size</th><th align="left">Modified</th></tr>
+{{if ne .Path "/"}}<tr><td><a href="../">../</a></td><td></td><td></td></tr>
+{{end}}{{range .Entries}}<tr><td><a href="{{.Link}}">{{.Name}}{{if .IsDir}}/{{end}}</a></td><td align="right">{{if not .IsDir}}{{.Size}}{{end}}</td><td>{{.ModTime}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+//listDir writes an HTML page with links to all entries of the directory name, directories are listed first
+func (s *fileServer) listDir(w http.ResponseWriter, r *http.Request, name string) {
+	//relative links only work if the URL of a directory ends with a slash
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		http.Redirect(w, r, path.Base(r.URL.Path)+"/", http.StatusMovedPermanently)
+		return
+	}
+	dir, err := s.afs.Open(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer dir.Close()
+	infos, err := dir.Readdir(-1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	entries := make([]listEntry, 0, len(infos))
+	for _, info := range infos {
+		link := url.PathEscape(info.Name())
+		if info.IsDir() {
+			link += "/"
+		}
+		entries = append(entries, listEntry{
+			Name:    info.Name(),
+			Link:    link,
+			Size:    info.Size(),
+			ModTime: info.ModTime().Format(time.RFC3339),
+			IsDir:   info.IsDir(),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Method == http.MethodHead {
+		return
+	}
+	err = listTemplate.Execute(w, struct {
+		Path    string
+		Entries []listEntry
+	}{name, entries})
+	if err != nil {
+		log.Debugf("fsserve: failed writing listing of %s: %v", name, err)
+	}
+}
+
+//webdavFs adapts an afero.Fs to webdav.FileSystem. webdav checks errors with os.IsNotExist and os.IsExist,
+//which do not know the afc status errors, so they are converted to os.PathErrors.
+type webdavFs struct {
+	afs afero.Fs
+}
+
+func (w webdavFs) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return pathError("mkdir", name, w.afs.Mkdir(name, perm))
+}
+
+func (w webdavFs) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	f, err := w.afs.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+	return f, nil
+}
+
+func (w webdavFs) RemoveAll(ctx context.Context, name string) error {
+	return pathError("remove", name, w.afs.RemoveAll(name))
+}
+
+func (w webdavFs) Rename(ctx context.Context, oldName, newName string) error {
+	return pathError("rename", oldName, w.afs.Rename(oldName, newName))
+}
+
+func (w webdavFs) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	info, err := w.afs.Stat(name)
+	if err != nil {
+		return nil, pathError("stat", name, err)
+	}
+	return info, nil
+}
+
+func pathError(op string, name string, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, iofs.ErrNotExist):
+		return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	case errors.Is(err, iofs.ErrExist):
+		return &os.PathError{Op: op, Path: name, Err: os.ErrExist}
+	case errors.Is(err, iofs.ErrPermission):
+		return &os.PathError{Op: op, Path: name, Err: os.ErrPermission}
+	}
+	return err
+}
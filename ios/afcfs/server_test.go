@@ -0,0 +1,87 @@
+package afcfs_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danielpaulus/go-ios/ios/afc"
+	"github.com/danielpaulus/go-ios/ios/afcfs"
+	"github.com/danielpaulus/go-ios/ios/usbmuxsim"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//startAfc simulates a device that serves com.apple.afc from an in memory filesystem
+func startAfc(t *testing.T) (*afc.Fsync, afero.Fs) {
+	_, lockdownd, entry := usbmuxsim.StartPairedDevice(t, "udid0")
+	memFs := afero.NewMemMapFs()
+	lockdownd.HandleService("com.apple.afc", usbmuxsim.NewAfcService(memFs).Handle)
+	fsync, err := afc.New(entry)
+	require.NoError(t, err)
+	t.Cleanup(fsync.Close)
+	return fsync, memFs
+}
+
+func request(t *testing.T, method string, url string, body io.Reader, headers map[string]string) (*http.Response, string) {
+	r, err := http.NewRequest(method, url, body)
+	require.NoError(t, err)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	r.SetBasicAuth("tester", "secret")
+	response, err := http.DefaultClient.Do(r)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	b, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	return response, string(b)
+}
+
+func TestFileServer(t *testing.T) {
+	fsync, memFs := startAfc(t)
+	server := httptest.NewServer(afcfs.NewFileServer(fsync, afcfs.ServeOptions{Username: "tester", Password: "secret"}))
+	defer server.Close()
+	require.NoError(t, afero.WriteFile(memFs, "/Documents/report.txt", []byte("0123456789"), 0644))
+	require.NoError(t, memFs.MkdirAll("/Documents/logs", 0755))
+
+	response, err := http.Get(server.URL + "/Documents/report.txt")
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+
+	response, body := request(t, http.MethodGet, server.URL+"/Documents/", nil, nil)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Contains(t, body, `<a href="logs/">logs/</a>`)
+	assert.Contains(t, body, `<a href="report.txt">report.txt</a>`)
+	assert.Less(t, strings.Index(body, "logs/"), strings.Index(body, "report.txt"))
+
+	response, body = request(t, http.MethodGet, server.URL+"/Documents/report.txt", nil, map[string]string{"Range": "bytes=2-5"})
+	assert.Equal(t, http.StatusPartialContent, response.StatusCode)
+	assert.Equal(t, "2345", body)
+
+	response, _ = request(t, http.MethodPut, server.URL+"/Documents/logs/new.txt", bytes.NewBufferString("uploaded"), nil)
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	b, err := afero.ReadFile(memFs, "/Documents/logs/new.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "uploaded", string(b))
+
+	response, body = request(t, "PROPFIND", server.URL+"/Documents/logs/", nil, map[string]string{"Depth": "1"})
+	assert.Equal(t, http.StatusMultiStatus, response.StatusCode)
+	assert.Contains(t, body, "/Documents/logs/new.txt")
+
+	response, _ = request(t, "MOVE", server.URL+"/Documents/logs/new.txt", nil, map[string]string{"Destination": server.URL + "/Documents/moved.txt"})
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	exists, err := afero.Exists(memFs, "/Documents/moved.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	response, _ = request(t, http.MethodDelete, server.URL+"/Documents/moved.txt", nil, nil)
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+	response, _ = request(t, http.MethodGet, server.URL+"/Documents/moved.txt", nil, nil)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
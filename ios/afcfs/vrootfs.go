@@ -11,6 +11,7 @@ import (
 	"path"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -23,10 +24,14 @@ const (
 	documentsDirName      = "Documents"
 )
 
+//VirtualRootFs is an afero.Fs that contains the media directory of the device in /afc, the crash reports
+//in /crashreports and the sandboxes of all apps with file sharing in /apps/<bundleID>.
+//It is safe for concurrent use.
 type VirtualRootFs struct {
 	afero.Fs
 	device      ios.DeviceEntry
 	mountPoints map[string]*afc.Fsync
+	mux         sync.RWMutex
 }
 
 func NewVfs(device ios.DeviceEntry) (*VirtualRootFs, error) {
@@ -50,6 +55,8 @@ func NewVfs(device ios.DeviceEntry) (*VirtualRootFs, error) {
 }
 
 func (fs *VirtualRootFs) umountAppsSandbox() {
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
 	for name, _ := range fs.mountPoints {
 		if strings.HasPrefix(name, sandboxMountPath) {
 			delete(fs.mountPoints, name)
@@ -127,6 +134,8 @@ func (fs *VirtualRootFs) findMountPoint(filepath string) (f afero.Fs, p string)
 }
 
 func (fs *VirtualRootFs) findMountPoint2(filepath string) (f afero.Fs, mountPoint, newPath string) {
+	fs.mux.RLock()
+	defer fs.mux.RUnlock()
 	for mp, f := range fs.mountPoints {
 		if strings.HasPrefix(filepath, mp) {
 			// When mount as VendDocuments, TrimPrefix trims away the the mount point "/app/<bundle_id>/Documents"
@@ -143,11 +152,15 @@ func (fs *VirtualRootFs) findMountPoint2(filepath string) (f afero.Fs, mountPoin
 }
 
 func (fs *VirtualRootFs) Mount(mountPath string, vfs *afc.Fsync) {
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
 	fs.mountPoints[mountPath] = vfs
 }
 
 func (fs *VirtualRootFs) Unmount(mountPath string) {
 	// TODO: need call fs.Unmount
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
 	delete(fs.mountPoints, mountPath)
 }
 
@@ -213,29 +226,42 @@ func (fs *VirtualRootFs) OpenFile(name string, flag int, perm os.FileMode) (afer
 	} else if name == sandboxMountPath || name == sandboxMountPath+"/" {
 		fs.umountAppsSandbox()
 		_ = fs.mountAppsSandbox()
-		var names []string
-		for _, s := range fs.mountPoints {
-			if s.FsType == afc.HouseArrestDocumentFs ||
-				s.FsType == afc.HouseArrestContainerFs {
-				// We extract the bundleIDs from the mounted paths to be
-				// the VFile -- the /apps dir -- child dir names.
-				names = append(names, s.BundleId)
-			}
+		return &VFile{absPath: name, names: fs.sandboxNames()}, nil
+	} else if fs.isDocumentsParent(name) {
+		// open the /app/<bundle_id>/
+		return &VFile{absPath: name, names: []string{documentsDirName}}, nil
+	}
+
+	return nil, syscall.EPERM
+}
+
+//sandboxNames returns the bundleIDs of the mounted app sandboxes, they are the child dir names of /apps
+func (fs *VirtualRootFs) sandboxNames() []string {
+	fs.mux.RLock()
+	defer fs.mux.RUnlock()
+	var names []string
+	for _, s := range fs.mountPoints {
+		if s.FsType == afc.HouseArrestDocumentFs ||
+			s.FsType == afc.HouseArrestContainerFs {
+			names = append(names, s.BundleId)
 		}
-		return &VFile{absPath: name, names: names}, nil
-	} else {
-		// check if open the /app/<bundle_id>/
-		for _, s := range fs.mountPoints {
-			if s.FsType == afc.HouseArrestDocumentFs {
-				parentDir := path.Join(sandboxMountPath, s.BundleId)
-				if name == parentDir+"/" || name == parentDir {
-					return &VFile{absPath: name, names: []string{documentsDirName}}, nil
-				}
+	}
+	return names
+}
+
+//isDocumentsParent checks if name is the /apps/<bundle_id> dir of an app whose Documents dir is mounted
+func (fs *VirtualRootFs) isDocumentsParent(name string) bool {
+	fs.mux.RLock()
+	defer fs.mux.RUnlock()
+	for _, s := range fs.mountPoints {
+		if s.FsType == afc.HouseArrestDocumentFs {
+			parentDir := path.Join(sandboxMountPath, s.BundleId)
+			if name == parentDir+"/" || name == parentDir {
+				return true
 			}
 		}
 	}
-
-	return nil, syscall.EPERM
+	return false
 }
 
 func (fs *VirtualRootFs) Remove(name string) error {
@@ -276,11 +302,14 @@ func (fs *VirtualRootFs) Stat(name string) (os.FileInfo, error) {
 	name = winPathToUnix(name)
 
 	mp, newPath := fs.findMountPoint(name)
-	if mp == nil {
+	if mp != nil {
+		return mp.Stat(newPath)
+	}
+	name = path.Clean("/" + name)
+	if name == "/" || name == sandboxMountPath || fs.isDocumentsParent(name) {
 		return afc.NewDirStatInfo(name), nil
 	}
-
-	return mp.Stat(newPath)
+	return nil, &os.PathError{Op: "stat", Path: name, Err: syscall.ENOENT}
 }
 
 func (fs *VirtualRootFs) Name() string { return "iOSVirtualRootFs" }
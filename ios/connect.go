@@ -41,7 +41,7 @@ func (muxConn *UsbMuxConnection) Connect(deviceID int, port uint16) error {
 	if response.IsSuccessFull() {
 		return nil
 	}
-	return fmt.Errorf("Failed connecting to service, error code:%d", response.Number)
+	return fmt.Errorf("Failed connecting to service: %w", MuxError{"Connect", response.Number})
 }
 
 //serviceConfigurations stores info about which DTX based services only execute a SSL Handshake
@@ -74,7 +74,7 @@ func (muxConn *UsbMuxConnection) ConnectLockdown(deviceID int) (*LockDownConnect
 		return &LockDownConnection{muxConn.deviceConn, "", NewPlistCodec()}, nil
 	}
 
-	return nil, fmt.Errorf("Failed connecting to Lockdown with error code:%d", response.Number)
+	return nil, fmt.Errorf("Failed connecting to Lockdown: %w", MuxError{"Connect", response.Number})
 }
 
 //ConnectToService connects to a service on the phone and returns the ready to use DeviceConnectionInterface
@@ -126,22 +126,22 @@ func (muxConn *UsbMuxConnection) connectWithStartServiceResponse(deviceID int, s
 func ConnectLockdownWithSession(device DeviceEntry) (*LockDownConnection, error) {
 	muxConnection, err := NewUsbMuxConnectionSimple()
 	if err != nil {
-		return nil, fmt.Errorf("USBMuxConnection failed with: %v", err)
+		return nil, fmt.Errorf("USBMuxConnection failed with: %w", err)
 	}
 	defer muxConnection.ReleaseDeviceConnection()
 
 	pairRecord, err := muxConnection.ReadPair(device.Properties.SerialNumber)
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve PairRecord with error: %v", err)
+		return nil, fmt.Errorf("could not retrieve PairRecord with error: %w", err)
 	}
 
 	lockdownConnection, err := muxConnection.ConnectLockdown(device.DeviceID)
 	if err != nil {
-		return nil, fmt.Errorf("Lockdown connection failed with: %v", err)
+		return nil, fmt.Errorf("Lockdown connection failed with: %w", err)
 	}
 	resp, err := lockdownConnection.StartSession(pairRecord)
 	if err != nil {
-		return nil, fmt.Errorf("StartSession failed: %+v error: %v", resp, err)
+		return nil, fmt.Errorf("StartSession failed: %+v error: %w", resp, err)
 	}
 	return lockdownConnection, nil
 }
@@ -0,0 +1,70 @@
+package ios
+
+import (
+	"context"
+	"time"
+)
+
+//RunWithContext runs a blocking operation on deviceConn and bounds it by ctx. The deadline of ctx is set on the
+//underlying net.Conn and cancelling ctx sets the deadline to now, which unblocks pending reads and writes.
+//The deadline is reset once the operation returns. If ctx ended before the operation completed, ctx.Err() is returned.
+//An interrupted operation can leave a partially read or written message on the connection, so callers should
+//usually close the connection afterwards.
+func RunWithContext(ctx context.Context, deviceConn DeviceConnectionInterface, operation func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ctx.Done() == nil || deviceConn == nil || deviceConn.Conn() == nil {
+		return operation()
+	}
+	conn := deviceConn.Conn()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	done := make(chan struct{})
+	interrupted := make(chan struct{})
+	go func() {
+		defer close(interrupted)
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+	err := operation()
+	close(done)
+	<-interrupted
+	conn.SetDeadline(time.Time{})
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+//ConnectToServiceContext is like ConnectToService but gives up once ctx is done. A connection that is established
+//after ctx ended is closed right away.
+func ConnectToServiceContext(ctx context.Context, device DeviceEntry, serviceName string) (DeviceConnectionInterface, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	type result struct {
+		conn DeviceConnectionInterface
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		conn, err := ConnectToService(device, serviceName)
+		results <- result{conn, err}
+	}()
+	select {
+	case r := <-results:
+		return r.conn, r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-results; r.err == nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
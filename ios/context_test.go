@@ -0,0 +1,66 @@
+package ios_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/usbmuxsim"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//connectToSilentService returns a connection to a simulated service that reads everything but never answers
+func connectToSilentService(t *testing.T) ios.DeviceConnectionInterface {
+	_, lockdownd, entry := usbmuxsim.StartPairedDevice(t, "udid0")
+	lockdownd.HandleService("com.apple.silent", func(conn net.Conn) {
+		io.Copy(io.Discard, conn)
+	})
+	conn, err := ios.ConnectToServiceContext(context.Background(), entry, "com.apple.silent")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestRunWithContextDeadline(t *testing.T) {
+	conn := connectToSilentService(t)
+	lockdown := ios.NewLockDownConnection(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := lockdown.ReadMessageContext(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	//the deadline is removed again after the operation
+	assert.NoError(t, lockdown.Send(map[string]interface{}{"Request": "QueryType"}))
+}
+
+func TestRunWithContextCancel(t *testing.T) {
+	conn := connectToSilentService(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	err := ios.RunWithContext(ctx, conn, func() error {
+		_, err := conn.Reader().Read(make([]byte, 1))
+		return err
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	err = ios.RunWithContext(ctx, conn, func() error {
+		t.Fatal("operation must not run with a cancelled context")
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestConnectToServiceContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ios.ConnectToServiceContext(ctx, ios.DeviceEntry{}, "com.apple.silent")
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -1,10 +1,12 @@
 package crashreport
 
 import (
+	"context"
 	"fmt"
 	"github.com/danielpaulus/go-ios/ios"
 	"github.com/danielpaulus/go-ios/ios/afc"
 	log "github.com/sirupsen/logrus"
+	"io"
 	"os"
 	"path"
 )
@@ -131,14 +133,24 @@ func ListReports(device ios.DeviceEntry, pattern string) ([]string, error) {
 }
 
 func moveReports(device ios.DeviceEntry) error {
+	return moveReportsContext(context.Background(), device)
+}
+
+//moveReportsContext triggers the crash report mover, which moves new reports into the directory shared with
+//crashreportcopymobile, and waits until it is done.
+func moveReportsContext(ctx context.Context, device ios.DeviceEntry) error {
 	log.Debug("moving crashreports")
-	conn, err := newMover(device)
+	conn, err := newMover(ctx, device)
 	if err != nil {
 		return err
 	}
+	defer conn.deviceConn.Close()
 	log.Debug("connected to mover, awaiting ping")
 	ping := make([]byte, 4)
-	_, err = conn.deviceConn.Reader().Read(ping)
+	err = ios.RunWithContext(ctx, conn.deviceConn, func() error {
+		_, err := io.ReadFull(conn.deviceConn.Reader(), ping)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -155,8 +167,8 @@ type moverConnection struct {
 }
 
 //NewWithHouseArrest returns a new ZipConduit Connection for the given DeviceID and Udid
-func newMover(device ios.DeviceEntry) (*moverConnection, error) {
-	deviceConn, err := ios.ConnectToService(device, crashReportMoverService)
+func newMover(ctx context.Context, device ios.DeviceEntry) (*moverConnection, error) {
+	deviceConn, err := ios.ConnectToServiceContext(ctx, device, crashReportMoverService)
 	if err != nil {
 		return &moverConnection{}, err
 	}
@@ -0,0 +1,548 @@
+package crashreport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	//FormatCrash is the text format of .crash reports and of .ips reports before iOS 15
+	FormatCrash = "crash"
+	//FormatIps is the JSON format of .ips reports since iOS 15
+	FormatIps = "ips"
+)
+
+//Report is a parsed crash report
+type Report struct {
+	Format                 string        `json:"format"`
+	Process                string        `json:"process"`
+	Pid                    int           `json:"pid"`
+	Path                   string        `json:"path,omitempty"`
+	BundleID               string        `json:"bundleId,omitempty"`
+	Version                string        `json:"version,omitempty"`
+	OSVersion              string        `json:"osVersion,omitempty"`
+	HardwareModel          string        `json:"hardwareModel,omitempty"`
+	Timestamp              string        `json:"timestamp,omitempty"`
+	IncidentID             string        `json:"incidentId,omitempty"`
+	ExceptionType          string        `json:"exceptionType"`
+	ExceptionCodes         string        `json:"exceptionCodes,omitempty"`
+	Signal                 string        `json:"signal,omitempty"`
+	TerminationReason      string        `json:"terminationReason,omitempty"`
+	CrashedThread          int           `json:"crashedThread"`
+	Threads                []Thread      `json:"threads"`
+	LastExceptionBacktrace []Frame       `json:"lastExceptionBacktrace,omitempty"`
+	BinaryImages           []BinaryImage `json:"binaryImages"`
+}
+
+//Thread is a thread of a crashed process with its stack, the first frame is the innermost
+type Thread struct {
+	Index   int     `json:"index"`
+	Name    string  `json:"name,omitempty"`
+	Queue   string  `json:"queue,omitempty"`
+	Crashed bool    `json:"crashed"`
+	Frames  []Frame `json:"frames"`
+}
+
+//Frame is a stack frame, Image is the name of the binary image that contains Address. Symbol, SymbolOffset,
+//File and Line are set if the report was symbolicated on the device or with a Symbolicator.
+type Frame struct {
+	Index        int    `json:"index"`
+	Image        string `json:"image"`
+	Address      uint64 `json:"address"`
+	ImageOffset  uint64 `json:"imageOffset"`
+	Symbol       string `json:"symbol,omitempty"`
+	SymbolOffset uint64 `json:"symbolOffset,omitempty"`
+	File         string `json:"file,omitempty"`
+	Line         int    `json:"line,omitempty"`
+}
+
+//BinaryImage is an executable or library that was loaded into the crashed process
+type BinaryImage struct {
+	Name        string `json:"name"`
+	UUID        string `json:"uuid"`
+	Arch        string `json:"arch,omitempty"`
+	LoadAddress uint64 `json:"loadAddress"`
+	Size        uint64 `json:"size,omitempty"`
+	Path        string `json:"path,omitempty"`
+}
+
+//CrashedFrames returns the frames of the crashed thread
+func (r *Report) CrashedFrames() []Frame {
+	for _, t := range r.Threads {
+		if t.Crashed {
+			return t.Frames
+		}
+	}
+	return nil
+}
+
+//image returns the binary image containing address
+func (r *Report) image(address uint64) (BinaryImage, bool) {
+	for _, image := range r.BinaryImages {
+		if address >= image.LoadAddress && address < image.LoadAddress+image.Size {
+			return image, true
+		}
+	}
+	return BinaryImage{}, false
+}
+
+//ParseReportFile parses the .crash or .ips report at path
+func ParseReportFile(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseReport(data)
+}
+
+//ParseReport parses crash reports in the JSON format of iOS 15 and later and in the older text format.
+//The text format is also used by .ips files of iOS 15 and later that contain no crash, f.ex. jetsam events,
+//after the JSON header line.
+func ParseReport(data []byte) (*Report, error) {
+	data = bytes.TrimLeft(data, "\ufeff \t\r\n")
+	if !bytes.HasPrefix(data, []byte("{")) {
+		return parseCrash(data)
+	}
+	header := data
+	body := []byte{}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		header, body = data[:i], bytes.TrimSpace(data[i+1:])
+	}
+	var ipsHeader ipsHeader
+	err := json.Unmarshal(header, &ipsHeader)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ips header: %w", err)
+	}
+	var report *Report
+	if bytes.HasPrefix(body, []byte("{")) {
+		report, err = parseIps(body)
+	} else {
+		report, err = parseCrash(body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ipsHeader.fill(report)
+	return report, nil
+}
+
+type ipsHeader struct {
+	AppName    string `json:"app_name"`
+	AppVersion string `json:"app_version"`
+	BundleID   string `json:"bundleID"`
+	OSVersion  string `json:"os_version"`
+	Timestamp  string `json:"timestamp"`
+	IncidentID string `json:"incident_id"`
+	Name       string `json:"name"`
+}
+
+//fill sets the fields the body did not contain
+func (h ipsHeader) fill(report *Report) {
+	setIfEmpty(&report.Process, h.AppName)
+	setIfEmpty(&report.Process, h.Name)
+	setIfEmpty(&report.Version, h.AppVersion)
+	setIfEmpty(&report.BundleID, h.BundleID)
+	setIfEmpty(&report.OSVersion, h.OSVersion)
+	setIfEmpty(&report.Timestamp, h.Timestamp)
+	setIfEmpty(&report.IncidentID, h.IncidentID)
+}
+
+func setIfEmpty(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+type ipsReport struct {
+	ProcName   string `json:"procName"`
+	Pid        int    `json:"pid"`
+	ProcPath   string `json:"procPath"`
+	BundleInfo struct {
+		ShortVersion string `json:"CFBundleShortVersionString"`
+		Version      string `json:"CFBundleVersion"`
+		Identifier   string `json:"CFBundleIdentifier"`
+	} `json:"bundleInfo"`
+	OSVersion struct {
+		Train string `json:"train"`
+		Build string `json:"build"`
+	} `json:"osVersion"`
+	ModelCode   string `json:"modelCode"`
+	CaptureTime string `json:"captureTime"`
+	IncidentID  string `json:"incident"`
+	Exception   struct {
+		Type    string `json:"type"`
+		Signal  string `json:"signal"`
+		Codes   string `json:"codes"`
+		Subtype string `json:"subtype"`
+	} `json:"exception"`
+	Termination struct {
+		Namespace string `json:"namespace"`
+		Indicator string `json:"indicator"`
+	} `json:"termination"`
+	FaultingThread int `json:"faultingThread"`
+	Threads        []struct {
+		Triggered bool       `json:"triggered"`
+		Name      string     `json:"name"`
+		Queue     string     `json:"queue"`
+		Frames    []ipsFrame `json:"frames"`
+	} `json:"threads"`
+	LastExceptionBacktrace []ipsFrame `json:"lastExceptionBacktrace"`
+	UsedImages             []struct {
+		Arch string `json:"arch"`
+		Base uint64 `json:"base"`
+		Size uint64 `json:"size"`
+		UUID string `json:"uuid"`
+		Path string `json:"path"`
+		Name string `json:"name"`
+	} `json:"usedImages"`
+}
+
+type ipsFrame struct {
+	ImageOffset    uint64 `json:"imageOffset"`
+	ImageIndex     int    `json:"imageIndex"`
+	Symbol         string `json:"symbol"`
+	SymbolLocation uint64 `json:"symbolLocation"`
+	SourceFile     string `json:"sourceFile"`
+	SourceLine     int    `json:"sourceLine"`
+}
+
+func parseIps(body []byte) (*Report, error) {
+	var ips ipsReport
+	err := json.Unmarshal(body, &ips)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ips report: %w", err)
+	}
+	report := &Report{
+		Format:         FormatIps,
+		Process:        ips.ProcName,
+		Pid:            ips.Pid,
+		Path:           ips.ProcPath,
+		BundleID:       ips.BundleInfo.Identifier,
+		HardwareModel:  ips.ModelCode,
+		Timestamp:      ips.CaptureTime,
+		IncidentID:     ips.IncidentID,
+		ExceptionType:  ips.Exception.Type,
+		ExceptionCodes: ips.Exception.Codes,
+		Signal:         ips.Exception.Signal,
+		CrashedThread:  ips.FaultingThread,
+	}
+	if ips.Exception.Subtype != "" {
+		report.ExceptionCodes = strings.TrimPrefix(ips.Exception.Subtype+", "+ips.Exception.Codes, ", ")
+	}
+	if ips.BundleInfo.ShortVersion != "" {
+		report.Version = fmt.Sprintf("%s (%s)", ips.BundleInfo.ShortVersion, ips.BundleInfo.Version)
+	}
+	if ips.OSVersion.Train != "" {
+		report.OSVersion = fmt.Sprintf("%s (%s)", ips.OSVersion.Train, ips.OSVersion.Build)
+	}
+	if ips.Termination.Indicator != "" {
+		report.TerminationReason = strings.TrimSpace(ips.Termination.Namespace + " " + ips.Termination.Indicator)
+	}
+	for _, image := range ips.UsedImages {
+		name := image.Name
+		if name == "" && image.Path != "" {
+			name = image.Path[strings.LastIndex(image.Path, "/")+1:]
+		}
+		report.BinaryImages = append(report.BinaryImages, BinaryImage{
+			Name:        name,
+			UUID:        normalizeUUID(image.UUID),
+			Arch:        image.Arch,
+			LoadAddress: image.Base,
+			Size:        image.Size,
+			Path:        image.Path,
+		})
+	}
+	for i, t := range ips.Threads {
+		report.Threads = append(report.Threads, Thread{
+			Index:   i,
+			Name:    t.Name,
+			Queue:   t.Queue,
+			Crashed: t.Triggered || i == ips.FaultingThread,
+			Frames:  report.ipsFrames(t.Frames),
+		})
+	}
+	report.LastExceptionBacktrace = report.ipsFrames(ips.LastExceptionBacktrace)
+	return report, nil
+}
+
+func (r *Report) ipsFrames(frames []ipsFrame) []Frame {
+	result := make([]Frame, len(frames))
+	for i, f := range frames {
+		frame := Frame{
+			Index:        i,
+			ImageOffset:  f.ImageOffset,
+			Symbol:       f.Symbol,
+			SymbolOffset: f.SymbolLocation,
+			File:         f.SourceFile,
+			Line:         f.SourceLine,
+		}
+		if f.ImageIndex >= 0 && f.ImageIndex < len(r.BinaryImages) {
+			image := r.BinaryImages[f.ImageIndex]
+			frame.Image = image.Name
+			frame.Address = image.LoadAddress + f.ImageOffset
+		}
+		result[i] = frame
+	}
+	return result
+}
+
+var (
+	threadHeaderRegex = regexp.MustCompile(`^Thread (\d+)(?: name:\s*(.*)| (Crashed))?:?\s*$`)
+	//0   libsystem_kernel.dylib        	0x00000001bde8e334 0x1bde66000 + 164660
+	//1   MyApp                         	0x0000000104a1c1f4 main + 120 (main.swift:5)
+	frameRegex = regexp.MustCompile(`^(\d+)\s+(.+?)\s+(0x[0-9a-fA-F]+)\s+(.+?)(?: \+ (\d+))?(?: \((.+):(\d+)\))?\s*$`)
+	//0x104a14000 - 0x104a1ffff MyApp arm64  <d2b4f5e8c3a13f2a9b1e2c3d4e5f6a7b> /private/var/containers/Bundle/Application/MyApp.app/MyApp
+	imageRegex = regexp.MustCompile(`^\s*(0x[0-9a-fA-F]+)\s*-\s*(0x[0-9a-fA-F]+)\s+\+?(.+?)\s+(\S+)\s+<([0-9a-fA-F-]+)>\s*(.*)$`)
+	//Process:             MyApp [1234]
+	processRegex = regexp.MustCompile(`^(.*?)\s*\[(\d+)\]$`)
+	//Exception Type:  EXC_CRASH (SIGABRT)
+	exceptionRegex = regexp.MustCompile(`^(\S+)\s*\((\S+)\)$`)
+)
+
+func parseCrash(data []byte) (*Report, error) {
+	report := &Report{Format: FormatCrash, CrashedThread: -1}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var thread *Thread
+	var frames *[]Frame
+	inImages := false
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			thread, frames = nil, nil
+			continue
+		}
+		if inImages {
+			if image, ok := parseImage(line); ok {
+				report.BinaryImages = append(report.BinaryImages, image)
+			}
+			continue
+		}
+		if frames != nil {
+			if frame, ok := parseFrame(line); ok {
+				*frames = append(*frames, frame)
+				continue
+			}
+		}
+		if m := threadHeaderRegex.FindStringSubmatch(line); m != nil {
+			index, _ := strconv.Atoi(m[1])
+			thread = report.thread(index)
+			if m[2] != "" {
+				thread.Name = m[2]
+				if queue := strings.TrimPrefix(m[2], "Dispatch queue: "); queue != m[2] {
+					thread.Name, thread.Queue = "", queue
+				}
+				continue
+			}
+			if m[3] != "" {
+				thread.Crashed = true
+				report.CrashedThread = index
+			}
+			frames = &thread.Frames
+			continue
+		}
+		if strings.HasPrefix(line, "Last Exception Backtrace:") {
+			frames = &report.LastExceptionBacktrace
+			continue
+		}
+		if strings.HasPrefix(line, "Binary Images:") {
+			inImages = true
+			continue
+		}
+		key, value, ok := splitField(line)
+		if ok {
+			report.setField(key, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(report.Threads) == 0 && report.ExceptionType == "" {
+		return nil, fmt.Errorf("not a crash report")
+	}
+	if report.CrashedThread >= 0 {
+		for i := range report.Threads {
+			report.Threads[i].Crashed = report.Threads[i].Index == report.CrashedThread
+		}
+	}
+	report.linkFrames()
+	return report, nil
+}
+
+//thread returns the thread with index and adds it if needed
+func (r *Report) thread(index int) *Thread {
+	for i := range r.Threads {
+		if r.Threads[i].Index == index {
+			return &r.Threads[i]
+		}
+	}
+	r.Threads = append(r.Threads, Thread{Index: index})
+	return &r.Threads[len(r.Threads)-1]
+}
+
+func splitField(line string) (string, string, bool) {
+	i := strings.Index(line, ":")
+	if i <= 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]), true
+}
+
+func (r *Report) setField(key string, value string) {
+	switch key {
+	case "Process":
+		r.Process = value
+		if m := processRegex.FindStringSubmatch(value); m != nil {
+			r.Process = m[1]
+			r.Pid, _ = strconv.Atoi(m[2])
+		}
+	case "Path":
+		r.Path = value
+	case "Identifier":
+		r.BundleID = value
+	case "Version":
+		r.Version = value
+	case "OS Version":
+		r.OSVersion = value
+	case "Hardware Model":
+		r.HardwareModel = value
+	case "Date/Time":
+		r.Timestamp = value
+	case "Incident Identifier":
+		r.IncidentID = value
+	case "Exception Type":
+		r.ExceptionType = value
+		if m := exceptionRegex.FindStringSubmatch(value); m != nil {
+			r.ExceptionType, r.Signal = m[1], m[2]
+		}
+	case "Exception Codes":
+		r.ExceptionCodes = value
+	case "Termination Reason":
+		r.TerminationReason = value
+	case "Triggered by Thread", "Crashed Thread":
+		if index, err := strconv.Atoi(strings.Fields(value + " x")[0]); err == nil {
+			r.CrashedThread = index
+		}
+	}
+}
+
+func parseFrame(line string) (Frame, bool) {
+	m := frameRegex.FindStringSubmatch(line)
+	if m == nil {
+		return Frame{}, false
+	}
+	frame := Frame{Image: m[2]}
+	frame.Index, _ = strconv.Atoi(m[1])
+	frame.Address, _ = strconv.ParseUint(m[3], 0, 64)
+	offset, _ := strconv.ParseUint(m[5], 10, 64)
+	//unsymbolicated frames contain the load address of the image instead of a symbol
+	if loadAddress, err := strconv.ParseUint(m[4], 0, 64); err == nil && strings.HasPrefix(m[4], "0x") {
+		frame.ImageOffset = frame.Address - loadAddress
+	} else {
+		frame.Symbol = m[4]
+		frame.SymbolOffset = offset
+	}
+	if m[6] != "" {
+		frame.File = m[6]
+		frame.Line, _ = strconv.Atoi(m[7])
+	}
+	return frame, true
+}
+
+func parseImage(line string) (BinaryImage, bool) {
+	m := imageRegex.FindStringSubmatch(line)
+	if m == nil {
+		return BinaryImage{}, false
+	}
+	start, _ := strconv.ParseUint(m[1], 0, 64)
+	end, _ := strconv.ParseUint(m[2], 0, 64)
+	return BinaryImage{
+		Name:        m[3],
+		Arch:        m[4],
+		UUID:        normalizeUUID(m[5]),
+		LoadAddress: start,
+		Size:        end - start + 1,
+		Path:        m[6],
+	}, true
+}
+
+//linkFrames computes the image offsets of symbolicated frames, which only contain the address
+func (r *Report) linkFrames() {
+	link := func(frames []Frame) {
+		for i := range frames {
+			if image, ok := r.image(frames[i].Address); ok {
+				frames[i].ImageOffset = frames[i].Address - image.LoadAddress
+			}
+		}
+	}
+	for i := range r.Threads {
+		link(r.Threads[i].Frames)
+	}
+	link(r.LastExceptionBacktrace)
+}
+
+//normalizeUUID converts UUIDs to lower case without dashes, the form used in the binary images of .crash reports
+func normalizeUUID(uuid string) string {
+	return strings.ToLower(strings.ReplaceAll(uuid, "-", ""))
+}
+
+//String formats the report like the text format, but only with the crashed thread
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Process:            %s [%d]\n", r.Process, r.Pid)
+	fmt.Fprintf(&b, "Identifier:         %s\n", r.BundleID)
+	fmt.Fprintf(&b, "Version:            %s\n", r.Version)
+	fmt.Fprintf(&b, "OS Version:         %s\n", r.OSVersion)
+	fmt.Fprintf(&b, "Hardware Model:     %s\n", r.HardwareModel)
+	fmt.Fprintf(&b, "Date/Time:          %s\n", r.Timestamp)
+	exception := r.ExceptionType
+	if r.Signal != "" {
+		exception += " (" + r.Signal + ")"
+	}
+	fmt.Fprintf(&b, "Exception Type:     %s\n", exception)
+	if r.ExceptionCodes != "" {
+		fmt.Fprintf(&b, "Exception Codes:    %s\n", r.ExceptionCodes)
+	}
+	if r.TerminationReason != "" {
+		fmt.Fprintf(&b, "Termination Reason: %s\n", r.TerminationReason)
+	}
+	if len(r.LastExceptionBacktrace) > 0 {
+		b.WriteString("\nLast Exception Backtrace:\n")
+		writeFrames(&b, r.LastExceptionBacktrace)
+	}
+	for _, t := range r.Threads {
+		if !t.Crashed {
+			continue
+		}
+		fmt.Fprintf(&b, "\nThread %d Crashed:", t.Index)
+		if t.Queue != "" {
+			fmt.Fprintf(&b, " Dispatch queue: %s", t.Queue)
+		} else if t.Name != "" {
+			fmt.Fprintf(&b, " %s", t.Name)
+		}
+		b.WriteString("\n")
+		writeFrames(&b, t.Frames)
+	}
+	return b.String()
+}
+
+func writeFrames(b *strings.Builder, frames []Frame) {
+	for _, f := range frames {
+		fmt.Fprintf(b, "%-3d %-30s 0x%016x ", f.Index, f.Image, f.Address)
+		if f.Symbol == "" {
+			fmt.Fprintf(b, "0x%x + %d", f.Address-f.ImageOffset, f.ImageOffset)
+		} else {
+			fmt.Fprintf(b, "%s + %d", f.Symbol, f.SymbolOffset)
+		}
+		if f.File != "" {
+			fmt.Fprintf(b, " (%s:%d)", f.File, f.Line)
+		}
+		b.WriteString("\n")
+	}
+}
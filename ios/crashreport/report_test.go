@@ -0,0 +1,97 @@
+package crashreport_test
+
+import (
+	"testing"
+
+	"github.com/danielpaulus/go-ios/ios/crashreport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCrash(t *testing.T) {
+	report, err := crashreport.ParseReportFile("fixtures/example.crash")
+	require.NoError(t, err)
+	assert.Equal(t, crashreport.FormatCrash, report.Format)
+	assert.Equal(t, "MyApp", report.Process)
+	assert.Equal(t, 1234, report.Pid)
+	assert.Equal(t, "com.example.MyApp", report.BundleID)
+	assert.Equal(t, "1.2.3 (45)", report.Version)
+	assert.Equal(t, "iPhone OS 14.6 (18F72)", report.OSVersion)
+	assert.Equal(t, "iPhone12,1", report.HardwareModel)
+	assert.Equal(t, "EXC_CRASH", report.ExceptionType)
+	assert.Equal(t, "SIGABRT", report.Signal)
+	assert.Equal(t, 0, report.CrashedThread)
+
+	require.Len(t, report.Threads, 2)
+	main := report.Threads[0]
+	assert.True(t, main.Crashed)
+	assert.Equal(t, "com.apple.main-thread", main.Queue)
+	assert.False(t, report.Threads[1].Crashed)
+	assert.Equal(t, main.Frames, report.CrashedFrames())
+	require.Len(t, main.Frames, 4)
+	assert.Equal(t, crashreport.Frame{Index: 0, Image: "libsystem_kernel.dylib", Address: 0x1bde8e334, ImageOffset: 0x28334, Symbol: "__pthread_kill", SymbolOffset: 8}, main.Frames[0])
+	assert.Equal(t, crashreport.Frame{Index: 2, Image: "MyApp", Address: 0x104a1c1f4, ImageOffset: 33268}, main.Frames[2])
+	assert.Equal(t, crashreport.Frame{Index: 3, Image: "MyApp", Address: 0x104a1c0a8, ImageOffset: 0x80a8, Symbol: "main", SymbolOffset: 120, File: "main.swift", Line: 5}, main.Frames[3])
+	require.Len(t, report.LastExceptionBacktrace, 3)
+	assert.Equal(t, "objc_exception_throw", report.LastExceptionBacktrace[1].Symbol)
+
+	require.Len(t, report.BinaryImages, 3)
+	assert.Equal(t, crashreport.BinaryImage{
+		Name:        "MyApp",
+		UUID:        "d2b4f5e8c3a13f2a9b1e2c3d4e5f6a7b",
+		Arch:        "arm64",
+		LoadAddress: 0x104a14000,
+		Size:        0xc000,
+		Path:        "/private/var/containers/Bundle/Application/0F1E2D3C-4B5A-6978-8796-A5B4C3D2E1F0/MyApp.app/MyApp",
+	}, report.BinaryImages[0])
+}
+
+func TestParseIps(t *testing.T) {
+	report, err := crashreport.ParseReportFile("fixtures/example.ips")
+	require.NoError(t, err)
+	assert.Equal(t, crashreport.FormatIps, report.Format)
+	assert.Equal(t, "MyApp", report.Process)
+	assert.Equal(t, 4321, report.Pid)
+	assert.Equal(t, "com.example.MyApp", report.BundleID)
+	assert.Equal(t, "2.0 (7)", report.Version)
+	assert.Equal(t, "iPhone OS 15.3.1 (19D52)", report.OSVersion)
+	assert.Equal(t, "iPhone13,2", report.HardwareModel)
+	assert.Equal(t, "EXC_BAD_ACCESS", report.ExceptionType)
+	assert.Equal(t, "SIGSEGV", report.Signal)
+	assert.Equal(t, "KERN_INVALID_ADDRESS at 0x0000000000000000, 0x0000000000000001, 0x0000000000000000", report.ExceptionCodes)
+	assert.Equal(t, "SIGNAL Segmentation fault: 11", report.TerminationReason)
+
+	require.Len(t, report.Threads, 2)
+	assert.True(t, report.Threads[0].Crashed)
+	assert.Equal(t, "com.apple.main-thread", report.Threads[0].Queue)
+	assert.Equal(t, "worker", report.Threads[1].Name)
+	assert.False(t, report.Threads[1].Crashed)
+	frames := report.CrashedFrames()
+	require.Len(t, frames, 3)
+	assert.Equal(t, crashreport.Frame{Index: 0, Image: "MyApp", Address: 0x104a1c1f4, ImageOffset: 33268}, frames[0])
+	assert.Equal(t, crashreport.Frame{Index: 1, Image: "MyApp", Address: 0x104a1c0a8, ImageOffset: 32936, Symbol: "main", SymbolOffset: 120, File: "main.swift", Line: 5}, frames[1])
+	assert.Equal(t, "dyld", frames[2].Image)
+
+	require.Len(t, report.BinaryImages, 3)
+	assert.Equal(t, "d2b4f5e8c3a13f2a9b1e2c3d4e5f6a7b", report.BinaryImages[0].UUID)
+	assert.Equal(t, uint64(0x104a14000), report.BinaryImages[0].LoadAddress)
+}
+
+func TestReportString(t *testing.T) {
+	report, err := crashreport.ParseReportFile("fixtures/example.crash")
+	require.NoError(t, err)
+	s := report.String()
+	assert.Contains(t, s, "Process:            MyApp [1234]")
+	assert.Contains(t, s, "Exception Type:     EXC_CRASH (SIGABRT)")
+	assert.Contains(t, s, "Thread 0 Crashed: Dispatch queue: com.apple.main-thread")
+	assert.Contains(t, s, "0x104a14000 + 33268")
+	assert.Contains(t, s, "main + 120 (main.swift:5)")
+	assert.NotContains(t, s, "start_wqthread")
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := crashreport.ParseReport([]byte("not a crash"))
+	assert.Error(t, err)
+	_, err = crashreport.ParseReport([]byte("{\"bug_type\":\"309\"}\n{broken"))
+	assert.Error(t, err)
+}
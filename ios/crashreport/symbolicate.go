@@ -0,0 +1,286 @@
+package crashreport
+
+import (
+	"debug/dwarf"
+	"debug/macho"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//lcUUID is the load command containing the UUID of a Mach-O binary
+const lcUUID = 0x1b
+
+//Symbolicator resolves stack frames of crash reports to function names, files and lines using the DWARF debug
+//information of dSYMs or unstripped binaries. Binaries are matched to the images of a report by their UUID.
+type Symbolicator struct {
+	binaries map[string]*binary
+}
+
+type binary struct {
+	textAddr  uint64
+	dwarf     *dwarf.Data
+	functions []function
+	symbols   []function
+}
+
+type function struct {
+	low, high uint64
+	name      string
+}
+
+//NewSymbolicator loads the Mach-O files at paths. Directories are searched recursively, which includes .dSYM
+//bundles, files in them that are not Mach-O are skipped.
+func NewSymbolicator(paths ...string) (*Symbolicator, error) {
+	s := &Symbolicator{binaries: map[string]*binary{}}
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			err := s.load(p)
+			if err != nil {
+				return nil, fmt.Errorf("failed loading %s: %w", p, err)
+			}
+			continue
+		}
+		err = filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil || !info.Mode().IsRegular() {
+				return err
+			}
+			err = s.load(path)
+			if err != nil {
+				log.Debugf("skipping %s: %v", path, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+//UUIDs returns the UUIDs of all loaded binaries
+func (s *Symbolicator) UUIDs() []string {
+	uuids := make([]string, 0, len(s.binaries))
+	for uuid := range s.binaries {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+	return uuids
+}
+
+//load adds all architectures of the Mach-O file at path
+func (s *Symbolicator) load(path string) error {
+	fat, err := macho.OpenFat(path)
+	if err == nil {
+		defer fat.Close()
+		for _, arch := range fat.Arches {
+			err := s.add(path, arch.File)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	f, err := macho.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.add(path, f)
+}
+
+func (s *Symbolicator) add(path string, f *macho.File) error {
+	uuid := machoUUID(f)
+	if uuid == "" {
+		return fmt.Errorf("no LC_UUID load command")
+	}
+	b := &binary{}
+	if text := f.Segment("__TEXT"); text != nil {
+		b.textAddr = text.Addr
+	}
+	if f.Symtab != nil {
+		b.symbols = symbols(f)
+	}
+	d, err := f.DWARF()
+	if err == nil {
+		b.dwarf = d
+		b.functions, err = functions(d)
+		if err != nil {
+			return err
+		}
+	} else if len(b.symbols) == 0 {
+		return fmt.Errorf("no debug information and symbols: %w", err)
+	}
+	log.Debugf("loaded %s with uuid %s", path, uuid)
+	s.binaries[uuid] = b
+	return nil
+}
+
+func machoUUID(f *macho.File) string {
+	for _, l := range f.Loads {
+		raw := l.Raw()
+		if len(raw) >= 24 && f.ByteOrder.Uint32(raw) == lcUUID {
+			return hex.EncodeToString(raw[8:24])
+		}
+	}
+	return ""
+}
+
+//symbols returns the defined symbols of the symbol table, which end where the next one starts
+func symbols(f *macho.File) []function {
+	var result []function
+	for _, sym := range f.Symtab.Syms {
+		//N_STAB debug entries and undefined symbols
+		if sym.Type&0xe0 != 0 || sym.Sect == 0 {
+			continue
+		}
+		result = append(result, function{low: sym.Value, name: strings.TrimPrefix(sym.Name, "_")})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].low < result[j].low })
+	for i := range result {
+		if i+1 < len(result) {
+			result[i].high = result[i+1].low
+		} else {
+			result[i].high = ^uint64(0)
+		}
+	}
+	return result
+}
+
+//functions returns the address ranges of all subprograms sorted by their start
+func functions(d *dwarf.Data) ([]function, error) {
+	var result []function
+	r := d.Reader()
+	for {
+		entry, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			break
+		}
+		if entry.Tag != dwarf.TagSubprogram {
+			continue
+		}
+		ranges, err := d.Ranges(entry)
+		if err != nil || len(ranges) == 0 {
+			continue
+		}
+		name := functionName(d, entry)
+		for _, rng := range ranges {
+			result = append(result, function{low: rng[0], high: rng[1], name: name})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].low < result[j].low })
+	return result, nil
+}
+
+//functionName returns the name of a subprogram, which is stored in its declaration for f.ex. C++ methods
+func functionName(d *dwarf.Data, entry *dwarf.Entry) string {
+	for i := 0; i < 8; i++ {
+		if name, ok := entry.Val(dwarf.AttrName).(string); ok {
+			return name
+		}
+		if name, ok := entry.Val(dwarf.AttrLinkageName).(string); ok {
+			return name
+		}
+		offset, ok := entry.Val(dwarf.AttrSpecification).(dwarf.Offset)
+		if !ok {
+			offset, ok = entry.Val(dwarf.AttrAbstractOrigin).(dwarf.Offset)
+		}
+		if !ok {
+			return ""
+		}
+		r := d.Reader()
+		r.Seek(offset)
+		next, err := r.Next()
+		if err != nil || next == nil {
+			return ""
+		}
+		entry = next
+	}
+	return ""
+}
+
+//lookup returns the function containing pc
+func lookup(functions []function, pc uint64) (function, bool) {
+	i := sort.Search(len(functions), func(i int) bool { return functions[i].low > pc }) - 1
+	if i < 0 || pc >= functions[i].high {
+		return function{}, false
+	}
+	return functions[i], true
+}
+
+//line returns the source file and line of pc
+func (b *binary) line(pc uint64) (string, int, bool) {
+	if b.dwarf == nil {
+		return "", 0, false
+	}
+	cu, err := b.dwarf.Reader().SeekPC(pc)
+	if err != nil {
+		return "", 0, false
+	}
+	lr, err := b.dwarf.LineReader(cu)
+	if err != nil || lr == nil {
+		return "", 0, false
+	}
+	var entry dwarf.LineEntry
+	if lr.SeekPC(pc, &entry) != nil || entry.File == nil {
+		return "", 0, false
+	}
+	return entry.File.Name, entry.Line, true
+}
+
+//Symbolicate adds function names, files and lines to the frames of report whose image was loaded and that have
+//no file yet. It returns the number of frames that were symbolicated.
+func (s *Symbolicator) Symbolicate(report *Report) int {
+	uuids := map[string]string{}
+	for _, image := range report.BinaryImages {
+		uuids[image.Name] = image.UUID
+	}
+	count := 0
+	symbolicate := func(frames []Frame) {
+		for i := range frames {
+			frame := &frames[i]
+			b, ok := s.binaries[uuids[frame.Image]]
+			if !ok || frame.File != "" {
+				continue
+			}
+			address := b.textAddr + frame.ImageOffset
+			//return addresses point after the call, the caller's line is the one of the instruction before
+			pc := address
+			if frame.Index > 0 && pc > 0 {
+				pc--
+			}
+			fn, found := lookup(b.functions, pc)
+			if !found {
+				fn, found = lookup(b.symbols, pc)
+			}
+			if found {
+				frame.Symbol = fn.name
+				frame.SymbolOffset = address - fn.low
+			}
+			if file, line, ok := b.line(pc); ok {
+				frame.File, frame.Line = file, line
+				found = true
+			}
+			if found {
+				count++
+			}
+		}
+	}
+	for i := range report.Threads {
+		symbolicate(report.Threads[i].Frames)
+	}
+	symbolicate(report.LastExceptionBacktrace)
+	return count
+}
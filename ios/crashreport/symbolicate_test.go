@@ -0,0 +1,82 @@
+package crashreport_test
+
+import (
+	"debug/macho"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/danielpaulus/go-ios/ios/crashreport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const crashingProgram = `package main
+
+//go:noinline
+func crashHere(values []int) int {
+	return values[10]
+}
+
+func main() {
+	println(crashHere(nil))
+}
+`
+
+//buildDarwinBinary cross compiles a program with DWARF for iOS devices, the test is skipped if that is not possible
+func buildDarwinBinary(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(crashingProgram), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/crash\n\ngo 1.17\n"), 0644))
+	binary := filepath.Join(dir, "crash")
+	cmd := exec.Command("go", "build", "-o", binary, ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOOS=darwin", "GOARCH=arm64", "CGO_ENABLED=0", "GOFLAGS=")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("cannot build darwin binary: %v %s", err, out)
+	}
+	return binary
+}
+
+func TestSymbolicate(t *testing.T) {
+	binary := buildDarwinBinary(t)
+	f, err := macho.Open(binary)
+	require.NoError(t, err)
+	var address uint64
+	for _, sym := range f.Symtab.Syms {
+		if sym.Name == "main.crashHere" {
+			address = sym.Value
+		}
+	}
+	textAddr := f.Segment("__TEXT").Addr
+	f.Close()
+	require.NotZero(t, address)
+
+	symbolicator, err := crashreport.NewSymbolicator(filepath.Dir(binary))
+	require.NoError(t, err)
+	uuids := symbolicator.UUIDs()
+	require.Len(t, uuids, 1)
+
+	loadAddress := uint64(0x104a14000)
+	offset := address - textAddr + 4
+	report := &crashreport.Report{
+		BinaryImages: []crashreport.BinaryImage{{Name: "crash", UUID: uuids[0], LoadAddress: loadAddress, Size: 0x1000000}},
+		Threads: []crashreport.Thread{{Crashed: true, Frames: []crashreport.Frame{
+			{Index: 0, Image: "crash", Address: loadAddress + offset, ImageOffset: offset},
+			{Index: 1, Image: "other", Address: 0x1000, ImageOffset: 0x1000},
+		}}},
+	}
+	assert.Equal(t, 1, symbolicator.Symbolicate(report))
+	frame := report.Threads[0].Frames[0]
+	assert.Equal(t, "main.crashHere", frame.Symbol)
+	assert.Equal(t, uint64(4), frame.SymbolOffset)
+	assert.True(t, strings.HasSuffix(frame.File, "main.go"), frame.File)
+	assert.GreaterOrEqual(t, frame.Line, 4)
+	assert.LessOrEqual(t, frame.Line, 6)
+	assert.Empty(t, report.Threads[0].Frames[1].Symbol)
+
+	_, err = crashreport.NewSymbolicator(filepath.Join(filepath.Dir(binary), "main.go"))
+	assert.Error(t, err)
+}
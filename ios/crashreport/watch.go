@@ -0,0 +1,184 @@
+package crashreport
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/afc"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+//DefaultWatchInterval is how often Watch moves and lists the crash reports if WatchOptions.Interval is not set
+const DefaultWatchInterval = 2 * time.Second
+
+//WatchOptions configures Watch
+type WatchOptions struct {
+	//Process only reports reports of this executable, which is the prefix of their file names. All reports if empty.
+	Process string
+	//Interval between two checks for new reports
+	Interval time.Duration
+	//Download is a directory new reports are copied to, they are not downloaded if it is empty
+	Download string
+	//Parse adds the parsed report to the events
+	Parse bool
+}
+
+//Event is a crash report that appeared on the device while Watch was running
+type Event struct {
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"modTime"`
+	LocalPath string    `json:"localPath,omitempty"`
+	Report    *Report   `json:"report,omitempty"`
+}
+
+//Watch calls notify for every new crash report until ctx is done. Reports that exist when Watch starts are not
+//reported. In every interval the crash report mover is triggered, which moves the reports the device wrote since
+//the last time into the directory shared with crashreportcopymobile, and that directory is listed again.
+//Watch returns nil if ctx is done and an error if the device cannot be reached anymore.
+func Watch(ctx context.Context, device ios.DeviceEntry, options WatchOptions, notify func(Event)) error {
+	if options.Interval <= 0 {
+		options.Interval = DefaultWatchInterval
+	}
+	err := moveReportsContext(ctx, device)
+	if err != nil {
+		return ignoreDone(ctx, err)
+	}
+	deviceConn, err := ios.ConnectToServiceContext(ctx, device, crashReportCopyMobileService)
+	if err != nil {
+		return ignoreDone(ctx, err)
+	}
+	fsync := afc.NewFsyncFromConn(deviceConn)
+	defer fsync.Close()
+	known, err := listReports(ctx, fsync.Connection, ".")
+	if err != nil {
+		return ignoreDone(ctx, err)
+	}
+	log.WithField("reports", len(known)).Debug("watching crashreports")
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(options.Interval):
+		}
+		err := moveReportsContext(ctx, device)
+		if err != nil {
+			return ignoreDone(ctx, err)
+		}
+		current, err := listReports(ctx, fsync.Connection, ".")
+		if err != nil {
+			return ignoreDone(ctx, err)
+		}
+		for _, p := range sortedPaths(current) {
+			info := current[p]
+			if _, ok := known[p]; ok {
+				continue
+			}
+			if !matchesProcess(p, options.Process) {
+				continue
+			}
+			event := Event{Path: p, Size: info.Size(), ModTime: info.ModTime()}
+			watchEvent(fsync, &event, options)
+			notify(event)
+		}
+		known = current
+	}
+}
+
+//watchEvent downloads and parses the report of event as configured, failures are only logged so the report is
+//still notified
+func watchEvent(fsync *afc.Fsync, event *Event, options WatchOptions) {
+	if options.Download != "" {
+		target := filepath.Join(options.Download, filepath.FromSlash(event.Path))
+		err := os.MkdirAll(filepath.Dir(target), 0755)
+		if err == nil {
+			err = fsync.PullFile(event.Path, target)
+		}
+		if err != nil {
+			log.WithFields(log.Fields{"path": event.Path, "err": err}).Warn("failed downloading crashreport")
+		} else {
+			event.LocalPath = target
+		}
+	}
+	if !options.Parse {
+		return
+	}
+	var data []byte
+	var err error
+	if event.LocalPath != "" {
+		data, err = os.ReadFile(event.LocalPath)
+	} else {
+		data, err = afero.ReadFile(fsync, event.Path)
+	}
+	if err == nil {
+		event.Report, err = ParseReport(data)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{"path": event.Path, "err": err}).Warn("failed parsing crashreport")
+	}
+}
+
+//listReports returns all files below dir by their path
+func listReports(ctx context.Context, conn *afc.Connection, dir string) (map[string]os.FileInfo, error) {
+	names, err := conn.ReadDirContext(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	reports := map[string]os.FileInfo{}
+	for _, name := range names {
+		p := path.Join(dir, name)
+		info, err := conn.StatContext(ctx, p)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			//the report was removed since listing the directory
+			continue
+		}
+		if !info.IsDir() {
+			reports[p] = info
+			continue
+		}
+		children, err := listReports(ctx, conn, p)
+		if err != nil {
+			return nil, err
+		}
+		for child, info := range children {
+			reports[child] = info
+		}
+	}
+	return reports, nil
+}
+
+//matchesProcess checks if the report file at p was written for process, report names start with the executable
+//name followed by a dash or a dot like MyApp-2022-03-04-101112.ips or MyApp.cpu_resource-2022-03-04-101112.ips
+func matchesProcess(p string, process string) bool {
+	if process == "" {
+		return true
+	}
+	name := path.Base(p)
+	return strings.HasPrefix(name, process+"-") || strings.HasPrefix(name, process+".")
+}
+
+func sortedPaths(reports map[string]os.FileInfo) []string {
+	paths := make([]string, 0, len(reports))
+	for p := range reports {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func ignoreDone(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,78 @@
+package crashreport_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/crashreport"
+	"github.com/danielpaulus/go-ios/ios/usbmuxsim"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//startDevice simulates a device with crashreportcopymobile serving an in memory filesystem and a crash report
+//mover that counts how often it was called
+func startDevice(t *testing.T) (ios.DeviceEntry, afero.Fs, *int32) {
+	_, lockdownd, entry := usbmuxsim.StartPairedDevice(t, "udid0")
+	reports := afero.NewMemMapFs()
+	var moves int32
+	lockdownd.HandleService("com.apple.crashreportmover", func(conn net.Conn) {
+		atomic.AddInt32(&moves, 1)
+		conn.Write([]byte("ping"))
+		io.Copy(io.Discard, conn)
+	})
+	lockdownd.HandleService("com.apple.crashreportcopymobile", usbmuxsim.NewAfcService(reports).Handle)
+	return entry, reports, &moves
+}
+
+func TestWatch(t *testing.T) {
+	device, reports, moves := startDevice(t)
+	ips, err := os.ReadFile("fixtures/example.ips")
+	require.NoError(t, err)
+	require.NoError(t, afero.WriteFile(reports, "/MyApp-2022-03-04-100000.ips", ips, 0644))
+
+	download := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan crashreport.Event, 10)
+	done := make(chan error, 1)
+	options := crashreport.WatchOptions{Process: "MyApp", Interval: 10 * time.Millisecond, Download: download, Parse: true}
+	go func() {
+		done <- crashreport.Watch(ctx, device, options, func(event crashreport.Event) { events <- event })
+	}()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(moves) >= 2 }, 5*time.Second, 5*time.Millisecond)
+
+	require.NoError(t, afero.WriteFile(reports, "/Other-2022-03-04-101112.ips", []byte("{}"), 0644))
+	require.NoError(t, afero.WriteFile(reports, "/Retired/MyApp-2022-03-04-101112.ips", ips, 0644))
+	var event crashreport.Event
+	select {
+	case event = <-events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event for the new report")
+	}
+	assert.Equal(t, "Retired/MyApp-2022-03-04-101112.ips", event.Path)
+	assert.Equal(t, int64(len(ips)), event.Size)
+	assert.Equal(t, filepath.Join(download, "Retired", "MyApp-2022-03-04-101112.ips"), event.LocalPath)
+	downloaded, err := os.ReadFile(event.LocalPath)
+	require.NoError(t, err)
+	assert.Equal(t, ips, downloaded)
+	require.NotNil(t, event.Report)
+	assert.Equal(t, "EXC_BAD_ACCESS", event.Report.ExceptionType)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch did not return after cancel")
+	}
+	assert.Empty(t, events)
+}
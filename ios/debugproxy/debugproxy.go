@@ -111,15 +111,21 @@ func (d *DebugProxy) Launch(device ios.DeviceEntry, binaryMode bool) error {
 		log.Error("Could not change permission on usbmuxd socket", err)
 		return err
 	}
+	return d.Serve(listener, originalSocket, pairRecord, binaryMode)
+}
 
+//Serve accepts connections on listener, forwards them to the usbmuxd on originalSocket and dumps all communication
+//into WorkingDir. It returns when the listener fails, f.ex. because it was closed.
+func (d *DebugProxy) Serve(listener net.Listener, originalSocket string, pairRecord ios.PairRecord, binaryMode bool) error {
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
 			log.Errorf("error with connection: %e", err)
+			return err
 		}
 		d.connectionCounter++
 		id := fmt.Sprintf("#%d", d.connectionCounter)
-		connectionPath := filepath.Join(".", d.WorkingDir, "connection-"+id+"-"+time.Now().UTC().Format("2006.01.02-15.04.05.000"))
+		connectionPath := filepath.Join(d.WorkingDir, "connection-"+id+"-"+time.Now().UTC().Format("2006.01.02-15.04.05.000"))
 
 		os.MkdirAll(connectionPath, os.ModePerm)
 
@@ -150,8 +156,8 @@ func startProxyConnection(conn net.Conn, originalSocket string, pairRecord ios.P
 	p := ProxyConnection{info.ID, pairRecord, debugProxy, info, logger, sync.Mutex{}, false}
 
 	if binaryMode {
-		binOnUnixSocket := BinaryForwardingProxy{ios.NewDeviceConnectionWithConn(conn), NewBinDumpOnly("does not matter", filepath.Join(info.ConnectionPath, "rawbindump-from-host-service.bin"), logger)}
-		binToDevice := BinaryForwardingProxy{devConn, NewBinDumpOnly("does not matter", filepath.Join(info.ConnectionPath, "rawbindump-from-device.bin"), logger)}
+		binOnUnixSocket := BinaryForwardingProxy{ios.NewDeviceConnectionWithConn(conn), NewBinDumpOnly(filepath.Join(info.ConnectionPath, "rawbindump-from-host-service.json"), filepath.Join(info.ConnectionPath, "rawbindump-from-host-service.bin"), logger)}
+		binToDevice := BinaryForwardingProxy{devConn, NewBinDumpOnly(filepath.Join(info.ConnectionPath, "rawbindump-from-device.json"), filepath.Join(info.ConnectionPath, "rawbindump-from-device.bin"), logger)}
 		go proxyBinDumpConnection(&p, binOnUnixSocket, binToDevice)
 		return
 	}
@@ -176,7 +182,7 @@ func (d *DebugProxy) setupDirectory() {
 	os.MkdirAll(newpath, os.ModePerm)
 }
 
-func (d DebugProxy) addConnectionInfoToJsonFile(connInfo ConnectionInfo) {
+func (d *DebugProxy) addConnectionInfoToJsonFile(connInfo ConnectionInfo) {
 	file, err := os.OpenFile(filepath.Join(d.WorkingDir, connectionJSONFileName),
 		os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
@@ -149,15 +149,25 @@ func logDtxMessageNice(log *log.Entry, msg dtx.Message) {
 }
 
 type binaryOnlyDumper struct {
-	path string
+	jsonFilePath string
+	path         string
 }
 
-//NewNoOpDecoder does nothing
+//NewBinDumpOnly creates a decoder that only dumps raw bytes to dumpFilePath. For each chunk of bytes, a MessageWithMetaInfo
+//is written to jsonFilePath, so the order of messages between host and device is known later.
 func NewBinDumpOnly(jsonFilePath string, dumpFilePath string, log *log.Entry) decoder {
-	return binaryOnlyDumper{dumpFilePath}
+	return binaryOnlyDumper{jsonFilePath, dumpFilePath}
 }
 func (n binaryOnlyDumper) decode(bytes []byte) {
+	if len(bytes) == 0 {
+		return
+	}
+	var offset int64
+	if s, err := os.Stat(n.path); err == nil {
+		offset = s.Size()
+	}
 	writeBytes(n.path, bytes)
+	writeJSON(n.jsonFilePath, MessageWithMetaInfo{nil, "bin", time.Now(), offset, len(bytes)})
 }
 
 func writeBytes(filePath string, data []byte) {
@@ -29,7 +29,7 @@ func proxyLockDownConnection(p *ProxyConnection, lockdownOnUnixSocket *ios.LockD
 		if err != nil {
 			p.log.Info("Failed decoding LockdownMessage", request, err)
 		}
-		p.logJSONMessageToDevice(map[string]interface{}{"payload": decodedRequest, "type": "LOCKDOWN"})
+		p.logJSONMessageToDevice(map[string]interface{}{"payload": decodedRequest, "rawPayload": request, "type": "LOCKDOWN"})
 		p.log.WithFields(log.Fields{"ID": p.id, "direction": "host2device"}).Info(decodedRequest)
 
 		err = lockdownToDevice.Send(decodedRequest)
@@ -50,7 +50,7 @@ func proxyLockDownConnection(p *ProxyConnection, lockdownOnUnixSocket *ios.LockD
 		if err != nil {
 			p.log.Info("Failed decoding LockdownMessage", decodedResponse, err)
 		}
-		p.logJSONMessageFromDevice(map[string]interface{}{"payload": decodedResponse, "type": "LOCKDOWN"})
+		p.logJSONMessageFromDevice(map[string]interface{}{"payload": decodedResponse, "rawPayload": response, "type": "LOCKDOWN"})
 		p.log.WithFields(log.Fields{"ID": p.id, "direction": "device2host"}).Info(decodedResponse)
 
 		err = lockdownOnUnixSocket.Send(decodedResponse)
@@ -30,7 +30,7 @@ func proxyUsbMuxConnection(p *ProxyConnection, muxOnUnixSocket *ios.UsbMuxConnec
 		if err != nil {
 			p.log.Info("Failed decoding MuxMessage", request, err)
 		}
-		p.logJSONMessageToDevice(map[string]interface{}{"header": request.Header, "payload": decodedRequest, "type": "USBMUX"})
+		p.logJSONMessageToDevice(map[string]interface{}{"header": request.Header, "payload": decodedRequest, "rawPayload": request.Payload, "type": "USBMUX"})
 
 		p.log.WithFields(log.Fields{"ID": p.id, "direction": "host->device"}).Trace(decodedRequest)
 		if decodedRequest["MessageType"] == "Connect" {
@@ -59,7 +59,7 @@ func proxyUsbMuxConnection(p *ProxyConnection, muxOnUnixSocket *ios.UsbMuxConnec
 		if err != nil {
 			p.log.Info("Failed decoding MuxMessage", decodedResponse, err)
 		}
-		p.logJSONMessageFromDevice(map[string]interface{}{"header": response.Header, "payload": decodedResponse, "type": "USBMUX"})
+		p.logJSONMessageFromDevice(map[string]interface{}{"header": response.Header, "payload": decodedResponse, "rawPayload": response.Payload, "type": "USBMUX"})
 		p.log.WithFields(log.Fields{"ID": p.id, "direction": "device->host"}).Trace(decodedResponse)
 		err = muxOnUnixSocket.SendMuxMessage(response)
 	}
@@ -79,7 +79,7 @@ func handleReadPairRecord(p *ProxyConnection, muxOnUnixSocket *ios.UsbMuxConnect
 	newPayload := []byte(ios.ToPlist(decodedResponse))
 	response.Payload = newPayload
 	response.Header.Length = uint32(len(newPayload) + 16)
-	p.logJSONMessageFromDevice(map[string]interface{}{"header": response.Header, "payload": decodedResponse, "type": "USBMUX"})
+	p.logJSONMessageFromDevice(map[string]interface{}{"header": response.Header, "payload": decodedResponse, "rawPayload": response.Payload, "type": "USBMUX"})
 	p.log.WithFields(log.Fields{"ID": p.id, "direction": "device->host"}).Trace(decodedResponse)
 	err = muxOnUnixSocket.SendMuxMessage(response)
 }
@@ -156,7 +156,7 @@ func handleListen(p *ProxyConnection, muxOnUnixSocket *ios.UsbMuxConnection, mux
 		if err != nil {
 			p.log.Info("Failed decoding MuxMessage", decodedResponse, err)
 		}
-		p.logJSONMessageFromDevice(map[string]interface{}{"header": response.Header, "payload": decodedResponse, "type": "USBMUX"})
+		p.logJSONMessageFromDevice(map[string]interface{}{"header": response.Header, "payload": decodedResponse, "rawPayload": response.Payload, "type": "USBMUX"})
 		p.log.WithFields(log.Fields{"ID": p.id, "direction": "device->host"}).Trace(decodedResponse)
 		err = muxOnUnixSocket.SendMuxMessage(response)
 	}
@@ -0,0 +1,547 @@
+package debugproxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	ios "github.com/danielpaulus/go-ios/ios"
+	dtx "github.com/danielpaulus/go-ios/ios/dtx_codec"
+	log "github.com/sirupsen/logrus"
+	"howett.net/plist"
+)
+
+const jsonDumpFileName = "jsondump.json"
+
+//Replay plays back the device side of a dump created by the DebugProxy. Serve it on a socket and point go-ios at it
+//using the USBMUXD_SOCKET_ADDRESS env variable to re-run a recorded session without a device.
+//Live connections are matched to recorded connections in the order they were recorded using their first usbmux request.
+//Replaying dumps created in binary mode is not supported.
+type Replay struct {
+	connections []*recordedConnection
+	services    map[uint16]PhoneServiceInformation
+	pairRecord  ios.PairRecord
+	mux         sync.Mutex
+}
+
+type recordedConnection struct {
+	path     string
+	messages []recordedMessage
+	used     bool
+}
+
+//recordedMessage is one line of a jsondump.json file
+type recordedMessage struct {
+	Direction  string                 `json:"direction"`
+	Type       string                 `json:"type"`
+	Header     ios.UsbMuxHeader       `json:"header"`
+	Payload    map[string]interface{} `json:"payload"`
+	RawPayload []byte                 `json:"rawPayload"`
+}
+
+//chunkMetaInfo is the part of MessageWithMetaInfo needed to restore the order of service messages
+type chunkMetaInfo struct {
+	MessageType  string
+	TimeReceived time.Time
+	OffsetInDump int64
+	Length       int
+}
+
+//replayChunk is data the device sent after the host sent hostUnits messages for DTX services or bytes for other services
+type replayChunk struct {
+	data      []byte
+	hostUnits int
+}
+
+//LoadReplay reads all connections of the dump in dumpDir, which is the directory DebugProxy.WorkingDir pointed to.
+func LoadReplay(dumpDir string) (*Replay, error) {
+	file, err := os.Open(filepath.Join(dumpDir, connectionJSONFileName))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := &Replay{services: map[uint16]PhoneServiceInformation{}}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var info ConnectionInfo
+		err := json.Unmarshal(scanner.Bytes(), &info)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing %s: %w", connectionJSONFileName, err)
+		}
+		connection, err := loadRecordedConnection(filepath.Join(dumpDir, filepath.Base(info.ConnectionPath)))
+		if err != nil {
+			return nil, err
+		}
+		r.connections = append(r.connections, connection)
+		r.extractSessionInfo(connection)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func loadRecordedConnection(path string) (*recordedConnection, error) {
+	connection := &recordedConnection{path: path}
+	file, err := os.Open(filepath.Join(path, jsonDumpFileName))
+	if os.IsNotExist(err) {
+		return connection, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var msg recordedMessage
+		err := json.Unmarshal(scanner.Bytes(), &msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing %s in %s: %w", jsonDumpFileName, path, err)
+		}
+		connection.messages = append(connection.messages, msg)
+	}
+	return connection, scanner.Err()
+}
+
+//extractSessionInfo stores the pairRecord and all started services, which are needed to replay SSL connections
+func (r *Replay) extractSessionInfo(connection *recordedConnection) {
+	for _, msg := range connection.messages {
+		if msg.Direction != "device->host" {
+			continue
+		}
+		payload, err := ios.ParsePlist(msg.payloadBytes())
+		if err != nil {
+			continue
+		}
+		if pairRecordData, ok := payload["PairRecordData"].([]byte); ok {
+			r.pairRecord = ios.PairRecordfromBytes(pairRecordData)
+		}
+		if payload["Request"] == "StartService" && payload["Error"] == nil {
+			useSSL, _ := payload["EnableServiceSSL"].(bool)
+			serviceName, _ := payload["Service"].(string)
+			port, _ := payload["Port"].(uint64)
+			r.services[uint16(port)] = PhoneServiceInformation{ServicePort: uint16(port), ServiceName: serviceName, UseSSL: useSSL}
+		}
+	}
+}
+
+//payloadBytes returns the original plist. For dumps that do not contain raw payloads yet, the JSON payload is
+//converted back to a plist which loses data types like []byte.
+func (m recordedMessage) payloadBytes() []byte {
+	if m.RawPayload != nil {
+		return m.RawPayload
+	}
+	return ios.ToPlistBytes(restorePlistTypes(m.Payload))
+}
+
+func (m recordedMessage) isRequest(messageType string) bool {
+	return m.Direction == "host->device" && m.Type == messageType
+}
+
+func restorePlistTypes(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) {
+			if v >= 0 {
+				return uint64(v)
+			}
+			return int64(v)
+		}
+		return v
+	case map[string]interface{}:
+		for key, inner := range v {
+			v[key] = restorePlistTypes(inner)
+		}
+		return v
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = restorePlistTypes(inner)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+//nextRequest returns the index of the next request of messageType starting at cursor or -1
+func (c *recordedConnection) nextRequest(cursor int, messageType string) int {
+	for i := cursor; i < len(c.messages); i++ {
+		if c.messages[i].isRequest(messageType) {
+			return i
+		}
+	}
+	return -1
+}
+
+//responses returns all device messages following the request at requestIndex and the index of the next host message
+func (c *recordedConnection) responses(requestIndex int, messageType string) ([]recordedMessage, int) {
+	var result []recordedMessage
+	i := requestIndex + 1
+	for ; i < len(c.messages); i++ {
+		msg := c.messages[i]
+		if msg.Direction == "host->device" {
+			break
+		}
+		if msg.Type == messageType {
+			result = append(result, msg)
+		}
+	}
+	return result, i
+}
+
+//findConnection returns the first unused recorded connection that starts with the same request.
+//If all of them have been used already, the last one is used again.
+func (r *Replay) findConnection(request map[string]interface{}) *recordedConnection {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	var lastUsed *recordedConnection
+	for _, connection := range r.connections {
+		index := connection.nextRequest(0, "USBMUX")
+		if index < 0 {
+			continue
+		}
+		first := connection.messages[index].Payload
+		if first["MessageType"] != request["MessageType"] {
+			continue
+		}
+		if request["MessageType"] == "Connect" && toUint64(first["PortNumber"]) != toUint64(request["PortNumber"]) {
+			continue
+		}
+		if connection.used {
+			lastUsed = connection
+			continue
+		}
+		connection.used = true
+		return connection
+	}
+	return lastUsed
+}
+
+func toUint64(value interface{}) uint64 {
+	switch v := value.(type) {
+	case float64:
+		return uint64(v)
+	case uint64:
+		return v
+	case int64:
+		return uint64(v)
+	}
+	return 0
+}
+
+//Serve accepts connections on listener and replays the device side on them. It returns when the listener fails,
+//f.ex. because it was closed.
+func (r *Replay) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go r.handleConnection(conn)
+	}
+}
+
+func (r *Replay) handleConnection(conn net.Conn) {
+	muxConn := ios.NewUsbMuxConnection(ios.NewDeviceConnectionWithConn(conn))
+	defer muxConn.Close()
+	var recorded *recordedConnection
+	cursor := 0
+	for {
+		request, err := muxConn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var decodedRequest map[string]interface{}
+		_, err = plist.Unmarshal(request.Payload, &decodedRequest)
+		if err != nil {
+			log.Warnf("replay: failed decoding usbmux request %x", request.Payload)
+			return
+		}
+		if recorded == nil {
+			recorded = r.findConnection(decodedRequest)
+			if recorded == nil {
+				log.Warnf("replay: no recorded connection found for %+v", decodedRequest)
+				return
+			}
+			log.Debugf("replay: replaying %s", recorded.path)
+		}
+		requestIndex := recorded.nextRequest(cursor, "USBMUX")
+		if requestIndex < 0 {
+			log.Warnf("replay: no more recorded usbmux messages in %s", recorded.path)
+			return
+		}
+
+		if decodedRequest["MessageType"] == "Connect" {
+			resp := ios.MuxResponse{MessageType: "Result", Number: 0}
+			payload := ios.ToPlistBytes(resp)
+			err := muxConn.SendMuxMessage(ios.UsbMuxMessage{
+				Header:  ios.UsbMuxHeader{Length: 16 + uint32(len(payload)), Version: 1, Request: 8, Tag: request.Header.Tag},
+				Payload: payload,
+			})
+			if err != nil {
+				return
+			}
+			port := toUint64(decodedRequest["PortNumber"])
+			deviceConn := muxConn.ReleaseDeviceConnection()
+			defer deviceConn.Close()
+			if uint16(port) == ios.Lockdownport {
+				r.replayLockdown(deviceConn, recorded, requestIndex+1)
+				return
+			}
+			r.replayService(deviceConn, recorded, ios.Ntohs(uint16(port)))
+			return
+		}
+
+		recordedTag := recorded.messages[requestIndex].Header.Tag
+		var responses []recordedMessage
+		responses, cursor = recorded.responses(requestIndex, "USBMUX")
+		for _, response := range responses {
+			payload := response.payloadBytes()
+			header := response.Header
+			header.Length = 16 + uint32(len(payload))
+			if header.Tag == recordedTag {
+				header.Tag = request.Header.Tag
+			}
+			err := muxConn.SendMuxMessage(ios.UsbMuxMessage{Header: header, Payload: payload})
+			if err != nil {
+				return
+			}
+		}
+		if decodedRequest["MessageType"] == "Listen" {
+			//block until the client closes the connection
+			muxConn.ReadMessage()
+			return
+		}
+	}
+}
+
+func (r *Replay) replayLockdown(deviceConn ios.DeviceConnectionInterface, recorded *recordedConnection, cursor int) {
+	codec := ios.NewPlistCodec()
+	for {
+		request, err := codec.Decode(deviceConn.Reader())
+		if err != nil {
+			return
+		}
+		requestIndex := recorded.nextRequest(cursor, "LOCKDOWN")
+		if requestIndex < 0 {
+			log.Warnf("replay: no more recorded lockdown messages in %s", recorded.path)
+			return
+		}
+		expected := recorded.messages[requestIndex].Payload["Request"]
+		if decoded, err := ios.ParsePlist(request); err == nil && decoded["Request"] != expected {
+			log.Warnf("replay: received lockdown request %v but recorded was %v", decoded["Request"], expected)
+		}
+		var responses []recordedMessage
+		responses, cursor = recorded.responses(requestIndex, "LOCKDOWN")
+		for _, response := range responses {
+			err := sendLockdownPayload(deviceConn, response.payloadBytes())
+			if err != nil {
+				return
+			}
+			if response.Payload["EnableSessionSSL"] == true {
+				err = deviceConn.EnableSessionSslServerMode(r.pairRecord)
+				if err != nil {
+					log.Warnf("replay: failed enabling ssl: %v", err)
+					return
+				}
+			}
+			//Apple tools disable SSL after StopSession and continue, go-ios just closes the connection
+			if response.Payload["Request"] == "StopSession" && recorded.nextRequest(cursor, "LOCKDOWN") >= 0 {
+				deviceConn.DisableSessionSSL()
+			}
+		}
+	}
+}
+
+func sendLockdownPayload(deviceConn ios.DeviceConnectionInterface, payload []byte) error {
+	buf := make([]byte, 4, 4+len(payload))
+	binary.BigEndian.PutUint32(buf, uint32(len(payload)))
+	return deviceConn.Send(append(buf, payload...))
+}
+
+func (r *Replay) replayService(deviceConn ios.DeviceConnectionInterface, recorded *recordedConnection, port uint16) {
+	r.mux.Lock()
+	info, ok := r.services[port]
+	r.mux.Unlock()
+	if !ok {
+		log.Warnf("replay: no service was started on port %d", port)
+		return
+	}
+	chunks, countDtxMessages, err := loadServiceChunks(recorded.path)
+	if err != nil {
+		log.Warnf("replay: failed loading service dump for %s: %v", info.ServiceName, err)
+		return
+	}
+	if info.UseSSL {
+		if getServiceConfigForName(info.ServiceName).handshakeOnlySSL {
+			err = deviceConn.EnableSessionSslServerModeHandshakeOnly(r.pairRecord)
+		} else {
+			err = deviceConn.EnableSessionSslServerMode(r.pairRecord)
+		}
+		if err != nil {
+			log.Warnf("replay: failed enabling ssl for %s: %v", info.ServiceName, err)
+			return
+		}
+	}
+
+	progress := newHostProgress()
+	go progress.track(deviceConn.Reader(), countDtxMessages)
+	for _, chunk := range chunks {
+		if !progress.waitFor(chunk.hostUnits) {
+			return
+		}
+		if deviceConn.Send(chunk.data) != nil {
+			return
+		}
+	}
+	progress.waitForClose()
+}
+
+//loadServiceChunks restores the order of device and host messages of a service connection.
+func loadServiceChunks(path string) ([]replayChunk, bool, error) {
+	fromDevice, err := os.ReadFile(filepath.Join(path, "from-device.bin"))
+	if err != nil {
+		return nil, false, err
+	}
+	deviceMeta, err := readChunkMetaInfo(filepath.Join(path, "from-device.json"))
+	if os.IsNotExist(err) {
+		//dumps of older versions do not contain meta info for binary services
+		return []replayChunk{{data: fromDevice}}, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	hostMeta, err := readChunkMetaInfo(filepath.Join(path, "to-device.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, false, err
+	}
+	countDtxMessages := len(deviceMeta) > 0 && deviceMeta[0].MessageType == "dtx"
+
+	chunks := make([]replayChunk, len(deviceMeta))
+	for i, meta := range deviceMeta {
+		if meta.OffsetInDump+int64(meta.Length) > int64(len(fromDevice)) {
+			return nil, false, fmt.Errorf("from-device.bin is shorter than expected")
+		}
+		hostUnits := 0
+		for _, host := range hostMeta {
+			if host.TimeReceived.After(meta.TimeReceived) {
+				continue
+			}
+			if countDtxMessages {
+				hostUnits++
+			} else {
+				hostUnits += host.Length
+			}
+		}
+		chunks[i] = replayChunk{data: fromDevice[meta.OffsetInDump : meta.OffsetInDump+int64(meta.Length)], hostUnits: hostUnits}
+	}
+	return chunks, countDtxMessages, nil
+}
+
+func readChunkMetaInfo(path string) ([]chunkMetaInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var result []chunkMetaInfo
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var meta chunkMetaInfo
+		err := json.Unmarshal(scanner.Bytes(), &meta)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing %s: %w", path, err)
+		}
+		result = append(result, meta)
+	}
+	return result, scanner.Err()
+}
+
+//hostProgress counts how many DTX messages or bytes a client sent, so recorded device messages can be sent in the right order
+type hostProgress struct {
+	cond   *sync.Cond
+	units  int
+	closed bool
+}
+
+func newHostProgress() *hostProgress {
+	return &hostProgress{cond: sync.NewCond(&sync.Mutex{})}
+}
+
+func (h *hostProgress) track(reader io.Reader, countDtxMessages bool) {
+	var buffer bytes.Buffer
+	data := make([]byte, 4096)
+	for {
+		n, err := reader.Read(data)
+		h.cond.L.Lock()
+		if n > 0 {
+			if countDtxMessages {
+				buffer.Write(data[:n])
+				h.units += countCompleteDtxMessages(&buffer)
+			} else {
+				h.units += n
+			}
+		}
+		if err != nil {
+			h.closed = true
+		}
+		h.cond.L.Unlock()
+		h.cond.Broadcast()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func countCompleteDtxMessages(buffer *bytes.Buffer) int {
+	count := 0
+	slice := buffer.Bytes()
+	for len(slice) > 0 {
+		_, remaining, err := dtx.DecodeNonBlocking(slice)
+		if dtx.IsIncomplete(err) {
+			break
+		}
+		if err != nil {
+			//count whatever we cannot decode as one message, so the replay does not get stuck
+			count++
+			slice = nil
+			break
+		}
+		count++
+		slice = remaining
+	}
+	remaining := append([]byte{}, slice...)
+	buffer.Reset()
+	buffer.Write(remaining)
+	return count
+}
+
+//waitFor blocks until the client sent units, it returns false if the client closed the connection before
+func (h *hostProgress) waitFor(units int) bool {
+	h.cond.L.Lock()
+	defer h.cond.L.Unlock()
+	for h.units < units && !h.closed {
+		h.cond.Wait()
+	}
+	return h.units >= units
+}
+
+func (h *hostProgress) waitForClose() {
+	h.cond.L.Lock()
+	defer h.cond.L.Unlock()
+	for !h.closed {
+		h.cond.Wait()
+	}
+}
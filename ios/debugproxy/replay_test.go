@@ -0,0 +1,91 @@
+package debugproxy_test
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/debugproxy"
+	"github.com/danielpaulus/go-ios/ios/usbmuxsim"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sessionResult struct {
+	devices string
+	values  ios.GetAllValuesResponse
+	echo    string
+}
+
+//runSession executes a couple of go-ios commands against whatever usbmuxd USBMUXD_SOCKET_ADDRESS points to
+func runSession(t *testing.T) sessionResult {
+	list, err := ios.ListDevices()
+	require.NoError(t, err)
+	device, err := ios.GetDevice("udid0")
+	require.NoError(t, err)
+	values, err := ios.GetValues(device)
+	require.NoError(t, err)
+
+	conn, err := ios.ConnectToService(device, "com.apple.echo")
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.Send([]byte("hello\n")))
+	line, err := bufio.NewReader(conn.Reader()).ReadString('\n')
+	require.NoError(t, err)
+	return sessionResult{list.String(), values, line}
+}
+
+//waitForDump polls until the proxy dumped content received from the device in any service connection. The proxy writes
+//everything to the dump before forwarding it, so all earlier messages of the session are in the dump then as well.
+func waitForDump(t *testing.T, dumpDir string, content string) {
+	require.Eventually(t, func() bool {
+		dumps, _ := filepath.Glob(filepath.Join(dumpDir, "connection-*", "from-device.bin"))
+		for _, dump := range dumps {
+			b, err := os.ReadFile(dump)
+			if err == nil && strings.Contains(string(b), content) {
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	simulator, lockdownd, _ := usbmuxsim.StartPairedDevice(t, "udid0")
+	pairRecord, _ := simulator.PairRecord("udid0")
+	lockdownd.HandleService("com.apple.echo", func(conn net.Conn) {
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte(line))
+	})
+
+	proxy := debugproxy.NewDebugProxy()
+	proxy.WorkingDir = filepath.Join(dir, "dump")
+	proxyListener, err := net.Listen("unix", filepath.Join(dir, "proxy"))
+	require.NoError(t, err)
+	go proxy.Serve(proxyListener, simulator.Socket(), pairRecord, false)
+
+	t.Setenv(ios.UsbmuxdSocketEnv, "unix:"+filepath.Join(dir, "proxy"))
+	recorded := runSession(t)
+	assert.Equal(t, "hello\n", recorded.echo)
+	waitForDump(t, proxy.WorkingDir, "hello\n")
+	proxyListener.Close()
+	simulator.Close()
+
+	replay, err := debugproxy.LoadReplay(proxy.WorkingDir)
+	require.NoError(t, err)
+	replayListener, err := net.Listen("unix", filepath.Join(dir, "replay"))
+	require.NoError(t, err)
+	defer replayListener.Close()
+	go replay.Serve(replayListener)
+
+	t.Setenv(ios.UsbmuxdSocketEnv, "unix:"+filepath.Join(dir, "replay"))
+	replayed := runSession(t)
+	assert.Equal(t, recorded, replayed)
+}
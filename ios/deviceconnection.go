@@ -6,6 +6,7 @@ import (
 	"io"
 	"net"
 	"runtime"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -43,11 +44,16 @@ func NewDeviceConnectionWithConn(conn net.Conn) *DeviceConnection {
 	return &DeviceConnection{c: conn}
 }
 
-//ConnectToSocketAddress connects to the USB multiplexer with a specified socket addres
+//ConnectToSocketAddress connects to the USB multiplexer with a specified socket addres.
+//Addresses prefixed with "unix:" or "tcp:" are dialed as they are, f.ex. "tcp:127.0.0.1:27015"
 func (conn *DeviceConnection) connectToSocketAddress(socketAddress string) error {
 	var network, address string
-	switch runtime.GOOS {
-	case "windows":
+	switch {
+	case strings.HasPrefix(socketAddress, "unix:"):
+		network, address = "unix", strings.TrimPrefix(socketAddress, "unix:")
+	case strings.HasPrefix(socketAddress, "tcp:"):
+		network, address = "tcp", strings.TrimPrefix(socketAddress, "tcp:")
+	case runtime.GOOS == "windows":
 		network, address = "tcp", "127.0.0.1:27015"
 	default:
 		network, address = "unix", socketAddress
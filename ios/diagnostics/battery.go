@@ -0,0 +1,78 @@
+package diagnostics
+
+import (
+	"time"
+)
+
+//BatteryInfo contains the values of the AppleSmartBattery IORegistry entry
+type BatteryInfo struct {
+	Timestamp time.Time `json:"timestamp"`
+	//CurrentCapacity is the charge in percent
+	CurrentCapacity uint64 `json:"currentCapacity"`
+	CycleCount      uint64 `json:"cycleCount"`
+	//Temperature in degrees celsius
+	Temperature float64 `json:"temperature"`
+	//Voltage in mV
+	Voltage int64 `json:"voltage"`
+	//InstantAmperage in mA, negative while discharging
+	InstantAmperage   int64 `json:"instantAmperage"`
+	IsCharging        bool  `json:"isCharging"`
+	ExternalConnected bool  `json:"externalConnected"`
+	FullyCharged      bool  `json:"fullyCharged"`
+	//DesignCapacity and NominalChargeCapacity in mAh
+	DesignCapacity        uint64 `json:"designCapacity"`
+	NominalChargeCapacity uint64 `json:"nominalChargeCapacity"`
+	//Health is the nominal charge capacity in percent of the design capacity
+	Health float64 `json:"health"`
+}
+
+//PowerDraw returns the power the device draws from the battery in watts, it is negative while charging
+func (b BatteryInfo) PowerDraw() float64 {
+	return -float64(b.Voltage) * float64(b.InstantAmperage) / 1e6
+}
+
+//BatteryInfo reads the AppleSmartBattery IORegistry entry
+func (diagnosticsConn *Connection) BatteryInfo() (BatteryInfo, error) {
+	values, err := diagnosticsConn.ioRegistryEntry("AppleSmartBattery")
+	if err != nil {
+		return BatteryInfo{}, err
+	}
+	return batteryInfoFromIORegistry(values), nil
+}
+
+func batteryInfoFromIORegistry(values map[string]interface{}) BatteryInfo {
+	battery := BatteryInfo{
+		Timestamp:             time.Now(),
+		CurrentCapacity:       uint64(toInt64(values["CurrentCapacity"])),
+		CycleCount:            uint64(toInt64(values["CycleCount"])),
+		Temperature:           float64(toInt64(values["Temperature"])) / 100,
+		Voltage:               toInt64(values["Voltage"]),
+		InstantAmperage:       toInt64(values["InstantAmperage"]),
+		DesignCapacity:        uint64(toInt64(values["DesignCapacity"])),
+		NominalChargeCapacity: uint64(toInt64(values["NominalChargeCapacity"])),
+	}
+	//older devices do not have NominalChargeCapacity
+	if battery.NominalChargeCapacity == 0 {
+		battery.NominalChargeCapacity = uint64(toInt64(values["AppleRawMaxCapacity"]))
+	}
+	if battery.DesignCapacity > 0 {
+		battery.Health = float64(battery.NominalChargeCapacity) * 100 / float64(battery.DesignCapacity)
+	}
+	battery.IsCharging, _ = values["IsCharging"].(bool)
+	battery.ExternalConnected, _ = values["ExternalConnected"].(bool)
+	battery.FullyCharged, _ = values["FullyCharged"].(bool)
+	return battery
+}
+
+//toInt64 converts plist integers, negative values are sometimes encoded as large unsigned integers
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case uint64:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package diagnostics_test
+
+import (
+	"bufio"
+	"net"
+	"testing"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/diagnostics"
+	"github.com/danielpaulus/go-ios/ios/usbmuxsim"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//startDiagnostics simulates a device with a diagnostics_relay that answers every request with response
+func startDiagnostics(t *testing.T, response map[string]interface{}) ios.DeviceEntry {
+	_, lockdownd, entry := usbmuxsim.StartPairedDevice(t, "udid0")
+	lockdownd.HandleService("com.apple.mobile.diagnostics_relay", func(conn net.Conn) {
+		codec := ios.NewPlistCodec()
+		reader := bufio.NewReader(conn)
+		for {
+			_, err := codec.Decode(reader)
+			if err != nil {
+				return
+			}
+			b, _ := codec.Encode(response)
+			conn.Write(b)
+		}
+	})
+	return entry
+}
+
+func TestBatteryInfo(t *testing.T) {
+	device := startDiagnostics(t, map[string]interface{}{
+		"Status": "Success",
+		"Diagnostics": map[string]interface{}{"IORegistry": map[string]interface{}{
+			"CurrentCapacity":       uint64(87),
+			"CycleCount":            uint64(312),
+			"Temperature":           uint64(3050),
+			"Voltage":               uint64(4100),
+			"InstantAmperage":       uint64(0xFFFFFFFFFFFFFE0C),
+			"IsCharging":            false,
+			"ExternalConnected":     false,
+			"DesignCapacity":        uint64(2000),
+			"NominalChargeCapacity": uint64(1800),
+		}},
+	})
+	conn, err := diagnostics.New(device)
+	require.NoError(t, err)
+	battery, err := conn.BatteryInfo()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(87), battery.CurrentCapacity)
+	assert.Equal(t, uint64(312), battery.CycleCount)
+	assert.Equal(t, 30.5, battery.Temperature)
+	assert.Equal(t, int64(4100), battery.Voltage)
+	assert.Equal(t, int64(-500), battery.InstantAmperage)
+	assert.Equal(t, 90.0, battery.Health)
+	assert.InDelta(t, 2.05, battery.PowerDraw(), 0.0001)
+	assert.NoError(t, conn.Close())
+}
@@ -1,9 +1,14 @@
 package diagnostics
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	ios "github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/notificationproxy"
+	log "github.com/sirupsen/logrus"
 )
 
 const serviceName = "com.apple.mobile.diagnostics_relay"
@@ -33,18 +38,83 @@ func Reboot(device ios.DeviceEntry) error {
 	return service.Close()
 }
 
+//RebootAndWait reboots the device and blocks until it is usable again: it was reattached to usbmuxd, lockdown accepts
+//sessions and springboard finished starting. It returns the DeviceEntry of the reattached device or an error
+//if that takes longer than timeout.
+func RebootAndWait(device ios.DeviceEntry, timeout time.Duration) (ios.DeviceEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	watcher, err := ios.WatchReconnect(device)
+	if err != nil {
+		return ios.DeviceEntry{}, err
+	}
+	defer watcher.Close()
+	err = Reboot(device)
+	if err != nil {
+		return ios.DeviceEntry{}, err
+	}
+	log.Debugf("waiting for %s to reconnect", device.Properties.SerialNumber)
+	device, err = watcher.WaitForAttach(ctx)
+	if err != nil {
+		return ios.DeviceEntry{}, fmt.Errorf("device did not reconnect: %w", err)
+	}
+	//springboard sends finishedstartup only once, so observe it as soon as lockdownd accepts connections
+	//instead of waiting for lockdown first
+	log.Debugf("%s reconnected, waiting for springboard", device.Properties.SerialNumber)
+	err = notificationproxy.WaitUntilSpringboardStartedContext(ctx, device)
+	if err != nil {
+		return ios.DeviceEntry{}, fmt.Errorf("springboard did not start: %w", err)
+	}
+	return device, nil
+}
+
 func (diagnosticsConn *Connection) Reboot() error {
-	req := rebootRequest{Request: "Restart", WaitForDisconnect: true, DisplayFail: true, DisplayPass: true}
-	reader := diagnosticsConn.deviceConn.Reader()
-	bytes, err := diagnosticsConn.plistCodec.Encode(req)
+	return diagnosticsConn.powerAction("Restart")
+}
+
+//Shutdown turns off the device
+func Shutdown(device ios.DeviceEntry) error {
+	service, err := New(device)
 	if err != nil {
 		return err
 	}
-	err = diagnosticsConn.deviceConn.Send(bytes)
+	err = service.Shutdown()
 	if err != nil {
 		return err
 	}
-	response, err := diagnosticsConn.plistCodec.Decode(reader)
+	return service.Close()
+}
+
+//Shutdown turns off the device, it disconnects once the request succeeded
+func (diagnosticsConn *Connection) Shutdown() error {
+	return diagnosticsConn.powerAction("Shutdown")
+}
+
+//Sleep puts the device to sleep, which locks it
+func Sleep(device ios.DeviceEntry) error {
+	service, err := New(device)
+	if err != nil {
+		return err
+	}
+	err = service.Sleep()
+	if err != nil {
+		return err
+	}
+	return service.Close()
+}
+
+//Sleep puts the device to sleep, which locks it
+func (diagnosticsConn *Connection) Sleep() error {
+	response, err := diagnosticsConn.request(diagnosticsRequest{"Sleep"})
+	if err != nil {
+		return err
+	}
+	return checkStatus("Sleep", response)
+}
+
+func (diagnosticsConn *Connection) powerAction(action string) error {
+	req := rebootRequest{Request: action, WaitForDisconnect: true, DisplayFail: true, DisplayPass: true}
+	response, err := diagnosticsConn.request(req)
 	if err != nil {
 		return err
 	}
@@ -61,7 +131,32 @@ func (diagnosticsConn *Connection) Reboot() error {
 		}
 
 	}
-	return fmt.Errorf("could not reboot, response: %+v", plist)
+	return fmt.Errorf("could not %s, response: %+v", strings.ToLower(action), plist)
+}
+
+//request sends req and returns the raw response
+func (diagnosticsConn *Connection) request(req interface{}) ([]byte, error) {
+	reader := diagnosticsConn.deviceConn.Reader()
+	bytes, err := diagnosticsConn.plistCodec.Encode(req)
+	if err != nil {
+		return nil, err
+	}
+	err = diagnosticsConn.deviceConn.Send(bytes)
+	if err != nil {
+		return nil, err
+	}
+	return diagnosticsConn.plistCodec.Decode(reader)
+}
+
+func checkStatus(request string, response []byte) error {
+	plist, err := ios.ParsePlist(response)
+	if err != nil {
+		return err
+	}
+	if status, _ := plist["Status"].(string); status != "Success" {
+		return fmt.Errorf("%s failed, response: %+v", request, plist)
+	}
+	return nil
 }
 
 func (diagnosticsConn *Connection) AllValues() (allDiagnosticsResponse, error) {
@@ -79,21 +174,66 @@ func (diagnosticsConn *Connection) AllValues() (allDiagnosticsResponse, error) {
 	return diagnosticsfromBytes(response), nil
 }
 
+//Goodbye ends the session with the diagnostics service without closing the connection
+func (diagnosticsConn *Connection) Goodbye() error {
+	_, err := diagnosticsConn.request(diagnosticsRequest{"Goodbye"})
+	return err
+}
+
 func (diagnosticsConn *Connection) Close() error {
-	reader := diagnosticsConn.deviceConn.Reader()
-	closeReq := diagnosticsRequest{"Goodbye"}
-	bytes, err := diagnosticsConn.plistCodec.Encode(closeReq)
+	err := diagnosticsConn.Goodbye()
 	if err != nil {
 		return err
 	}
-	err = diagnosticsConn.deviceConn.Send(bytes)
+	diagnosticsConn.deviceConn.Close()
+	return nil
+}
+
+//Domains of the diagnostics service, see Query
+const (
+	DomainAll      = "All"
+	DomainWiFi     = "WiFi"
+	DomainGasGauge = "GasGauge"
+	DomainNAND     = "NAND"
+	DomainHDMI     = "HDMI"
+)
+
+//Domains lists all domains that can be queried
+var Domains = []string{DomainAll, DomainWiFi, DomainGasGauge, DomainNAND, DomainHDMI}
+
+//Query returns the diagnostics of domain. Only the field of the domain is set unless domain is DomainAll.
+func (diagnosticsConn *Connection) Query(domain string) (Diagnostics, error) {
+	response, err := diagnosticsConn.request(diagnosticsRequest{domain})
 	if err != nil {
-		return err
+		return Diagnostics{}, err
 	}
-	_, err = diagnosticsConn.plistCodec.Decode(reader)
+	err = checkStatus(domain, response)
 	if err != nil {
-		return err
+		return Diagnostics{}, err
 	}
-	diagnosticsConn.deviceConn.Close()
-	return nil
+	return diagnosticsfromBytes(response).Diagnostics, nil
+}
+
+//WiFi returns the diagnostics of the WiFi domain
+func (diagnosticsConn *Connection) WiFi() (WiFi, error) {
+	diagnostics, err := diagnosticsConn.Query(DomainWiFi)
+	return diagnostics.WiFi, err
+}
+
+//GasGauge returns the diagnostics of the GasGauge domain, see BatteryInfo for live battery values
+func (diagnosticsConn *Connection) GasGauge() (GasGauge, error) {
+	diagnostics, err := diagnosticsConn.Query(DomainGasGauge)
+	return diagnostics.GasGauge, err
+}
+
+//NAND returns the diagnostics of the NAND domain
+func (diagnosticsConn *Connection) NAND() (NAND, error) {
+	diagnostics, err := diagnosticsConn.Query(DomainNAND)
+	return diagnostics.NAND, err
+}
+
+//HDMI returns the diagnostics of the HDMI domain
+func (diagnosticsConn *Connection) HDMI() (HDMI, error) {
+	diagnostics, err := diagnosticsConn.Query(DomainHDMI)
+	return diagnostics.HDMI, err
 }
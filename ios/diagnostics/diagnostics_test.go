@@ -0,0 +1,32 @@
+package diagnostics_test
+
+import (
+	"testing"
+
+	"github.com/danielpaulus/go-ios/ios/diagnostics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryDomain(t *testing.T) {
+	device := startDiagnostics(t, map[string]interface{}{
+		"Status":      "Success",
+		"Diagnostics": map[string]interface{}{"WiFi": map[string]interface{}{"Active": "true", "Status": "Success"}},
+	})
+	conn, err := diagnostics.New(device)
+	require.NoError(t, err)
+	wifi, err := conn.WiFi()
+	require.NoError(t, err)
+	assert.Equal(t, diagnostics.WiFi{Active: "true", Status: "Success"}, wifi)
+	assert.NoError(t, conn.Sleep())
+	assert.NoError(t, conn.Close())
+}
+
+func TestQueryDomainFailure(t *testing.T) {
+	device := startDiagnostics(t, map[string]interface{}{"Status": "UnknownRequest"})
+	conn, err := diagnostics.New(device)
+	require.NoError(t, err)
+	_, err = conn.Query("Unknown")
+	assert.Error(t, err)
+	assert.Error(t, conn.Shutdown())
+}
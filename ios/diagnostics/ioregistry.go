@@ -1,11 +1,34 @@
 package diagnostics
 
-import ios "github.com/danielpaulus/go-ios/ios"
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
 
-func ioregentryRequest(key string) []byte {
+	ios "github.com/danielpaulus/go-ios/ios"
+)
+
+//IORegistryQuery selects what IORegistry returns. With only a Plane, the whole tree of that plane is returned,
+//f.ex. "IODeviceTree" or "IOService". EntryName and EntryClass select a single entry like "AppleSmartBattery" or "IOPMPowerSource".
+type IORegistryQuery struct {
+	Plane      string
+	EntryName  string
+	EntryClass string
+}
+
+func ioregistryRequest(query IORegistryQuery) []byte {
 	requestMap := map[string]interface{}{
-		"Request":   "IORegistry",
-		"EntryName": key,
+		"Request": "IORegistry",
+	}
+	if query.Plane != "" {
+		requestMap["CurrentPlane"] = query.Plane
+	}
+	if query.EntryName != "" {
+		requestMap["EntryName"] = query.EntryName
+	}
+	if query.EntryClass != "" {
+		requestMap["EntryClass"] = query.EntryClass
 	}
 	bt, err := ios.PlistCodec{}.Encode(requestMap)
 	if err != nil {
@@ -14,6 +37,10 @@ func ioregentryRequest(key string) []byte {
 	return bt
 }
 
+func ioregentryRequest(key string) []byte {
+	return ioregistryRequest(IORegistryQuery{EntryName: key})
+}
+
 func (diagnosticsConn *Connection) IORegEntryQuery(key string) (interface{}, error) {
 	err := diagnosticsConn.deviceConn.Send(ioregentryRequest(key))
 	if err != nil {
@@ -26,3 +53,159 @@ func (diagnosticsConn *Connection) IORegEntryQuery(key string) (interface{}, err
 	plist, err := ios.ParsePlist(respBytes)
 	return plist, err
 }
+
+//IORegistry runs query and returns the properties of the selected entry, or the root of the tree for plane queries.
+//Use NewIORegistryEntry to walk the tree.
+func (diagnosticsConn *Connection) IORegistry(query IORegistryQuery) (map[string]interface{}, error) {
+	err := diagnosticsConn.deviceConn.Send(ioregistryRequest(query))
+	if err != nil {
+		return nil, err
+	}
+	respBytes, err := diagnosticsConn.plistCodec.Decode(diagnosticsConn.deviceConn.Reader())
+	if err != nil {
+		return nil, err
+	}
+	plist, err := ios.ParsePlist(respBytes)
+	if err != nil {
+		return nil, err
+	}
+	if status, _ := plist["Status"].(string); status != "Success" {
+		return nil, fmt.Errorf("IORegistry query %+v failed: %+v", query, plist)
+	}
+	diagnostics, _ := plist["Diagnostics"].(map[string]interface{})
+	values, ok := diagnostics["IORegistry"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("IORegistry query %+v returned no entry: %+v", query, plist)
+	}
+	return values, nil
+}
+
+//ioRegistryEntry queries an IORegistry entry by name and returns its properties
+func (diagnosticsConn *Connection) ioRegistryEntry(name string) (map[string]interface{}, error) {
+	return diagnosticsConn.IORegistry(IORegistryQuery{EntryName: name})
+}
+
+//keys IORegistry uses for the structure of the tree, the same as in "ioreg -a" on macOS
+const (
+	ioRegistryEntryName     = "IORegistryEntryName"
+	ioRegistryEntryID       = "IORegistryEntryID"
+	ioRegistryEntryChildren = "IORegistryEntryChildren"
+	ioObjectClass           = "IOObjectClass"
+)
+
+//IORegistryEntry is an entry of the IORegistry tree with its properties and children
+type IORegistryEntry struct {
+	Name       string                 `json:"name"`
+	Class      string                 `json:"class,omitempty"`
+	ID         uint64                 `json:"id,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	Children   []IORegistryEntry      `json:"children,omitempty"`
+}
+
+//NewIORegistryEntry converts the values returned by IORegistry to a tree of IORegistryEntry
+func NewIORegistryEntry(values map[string]interface{}) IORegistryEntry {
+	entry := IORegistryEntry{Properties: map[string]interface{}{}}
+	for k, v := range values {
+		switch k {
+		case ioRegistryEntryName:
+			entry.Name, _ = v.(string)
+		case ioObjectClass:
+			entry.Class, _ = v.(string)
+		case ioRegistryEntryID:
+			entry.ID = uint64(toInt64(v))
+		case ioRegistryEntryChildren:
+			children, _ := v.([]interface{})
+			for _, child := range children {
+				if childValues, ok := child.(map[string]interface{}); ok {
+					entry.Children = append(entry.Children, NewIORegistryEntry(childValues))
+				}
+			}
+		default:
+			entry.Properties[k] = v
+		}
+	}
+	return entry
+}
+
+//WriteTree writes the tree in the format of the macOS ioreg tool, including the properties if withProperties is true like "ioreg -l"
+func (e IORegistryEntry) WriteTree(w io.Writer, withProperties bool) error {
+	return e.writeTree(w, "", true, withProperties)
+}
+
+//writeTree writes the entry at indent. Lines below an entry that has more siblings are connected with "|".
+func (e IORegistryEntry) writeTree(w io.Writer, indent string, last bool, withProperties bool) error {
+	childIndent := indent + "| "
+	if last {
+		childIndent = indent + "  "
+	}
+	description := "class " + e.Class
+	if e.ID != 0 {
+		description += fmt.Sprintf(", id 0x%x", e.ID)
+	}
+	_, err := fmt.Fprintf(w, "%s+-o %s  <%s>\n", indent, e.Name, description)
+	if err != nil {
+		return err
+	}
+	if withProperties && len(e.Properties) > 0 {
+		propertyPrefix := childIndent + "  "
+		if len(e.Children) > 0 {
+			propertyPrefix = childIndent + "| "
+		}
+		lines := []string{"{"}
+		keys := make([]string, 0, len(e.Properties))
+		for k := range e.Properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			lines = append(lines, fmt.Sprintf("  %q = %s", k, formatIORegistryValue(e.Properties[k])))
+		}
+		lines = append(lines, "}", "")
+		for _, line := range lines {
+			_, err := fmt.Fprintf(w, "%s%s\n", propertyPrefix, line)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for i, child := range e.Children {
+		err := child.writeTree(w, childIndent, i == len(e.Children)-1, withProperties)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//formatIORegistryValue formats values like ioreg does, f.ex. Yes/No for booleans and <hex> for data
+func formatIORegistryValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case bool:
+		if v {
+			return "Yes"
+		}
+		return "No"
+	case []byte:
+		return fmt.Sprintf("<%x>", v)
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, element := range v {
+			values[i] = formatIORegistryValue(element)
+		}
+		return "(" + strings.Join(values, ",") + ")"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		values := make([]string, len(keys))
+		for i, k := range keys {
+			values[i] = fmt.Sprintf("%q=%s", k, formatIORegistryValue(v[k]))
+		}
+		return "{" + strings.Join(values, ",") + "}"
+	}
+	return fmt.Sprintf("%v", value)
+}
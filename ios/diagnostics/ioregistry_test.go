@@ -0,0 +1,82 @@
+package diagnostics_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/danielpaulus/go-ios/ios/diagnostics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIORegistryTree(t *testing.T) {
+	device := startDiagnostics(t, map[string]interface{}{
+		"Status": "Success",
+		"Diagnostics": map[string]interface{}{"IORegistry": map[string]interface{}{
+			"IORegistryEntryName": "Root",
+			"IOObjectClass":       "IORegistryEntry",
+			"IORegistryEntryChildren": []interface{}{
+				map[string]interface{}{
+					"IORegistryEntryName": "D63AP",
+					"IOObjectClass":       "IOPlatformExpertDevice",
+					"IORegistryEntryID":   uint64(0x100000110),
+					"model":               []byte("iPhone"),
+					"IORegistryEntryChildren": []interface{}{
+						map[string]interface{}{"IORegistryEntryName": "cpus", "IOObjectClass": "IOService", "count": uint64(6)},
+					},
+				},
+				map[string]interface{}{"IORegistryEntryName": "AppleSmartBattery", "IOObjectClass": "AppleSmartBattery", "IsCharging": true},
+			},
+		}},
+	})
+	conn, err := diagnostics.New(device)
+	require.NoError(t, err)
+	values, err := conn.IORegistry(diagnostics.IORegistryQuery{Plane: "IOService"})
+	require.NoError(t, err)
+	entry := diagnostics.NewIORegistryEntry(values)
+	assert.Equal(t, "Root", entry.Name)
+	require.Len(t, entry.Children, 2)
+	assert.Equal(t, uint64(0x100000110), entry.Children[0].ID)
+	assert.Equal(t, map[string]interface{}{"model": []byte("iPhone")}, entry.Children[0].Properties)
+
+	var out bytes.Buffer
+	require.NoError(t, entry.WriteTree(&out, false))
+	assert.Equal(t, `+-o Root  <class IORegistryEntry>
+  +-o D63AP  <class IOPlatformExpertDevice, id 0x100000110>
+  | +-o cpus  <class IOService>
+  +-o AppleSmartBattery  <class AppleSmartBattery>
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, entry.Children[0].WriteTree(&out, true))
+	assert.Equal(t, `+-o D63AP  <class IOPlatformExpertDevice, id 0x100000110>
+  | {
+  |   "model" = <6950686f6e65>
+  | }
+  | 
+  +-o cpus  <class IOService>
+      {
+        "count" = 6
+      }
+      
+`, out.String())
+}
+
+func TestGestaltInfo(t *testing.T) {
+	device := startDiagnostics(t, map[string]interface{}{
+		"Status": "Success",
+		"Diagnostics": map[string]interface{}{"MobileGestalt": map[string]interface{}{
+			"Status":            "Success",
+			"ProductType":       "iPhone14,2",
+			"ProductVersion":    "15.0",
+			"UniqueChipID":      uint64(1234),
+			"main-screen-scale": float64(3),
+			"main-screen-width": uint64(1170),
+		}},
+	})
+	conn, err := diagnostics.New(device)
+	require.NoError(t, err)
+	info, err := conn.GestaltInfo()
+	require.NoError(t, err)
+	assert.Equal(t, diagnostics.GestaltInfo{ProductType: "iPhone14,2", ProductVersion: "15.0", UniqueChipID: 1234, ScreenScale: 3, ScreenWidth: 1170}, info)
+}
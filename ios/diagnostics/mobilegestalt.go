@@ -1,6 +1,10 @@
 package diagnostics
 
-import ios "github.com/danielpaulus/go-ios/ios"
+import (
+	"fmt"
+
+	ios "github.com/danielpaulus/go-ios/ios"
+)
 
 func gestaltRequest(keys []string) []byte {
 	goodbyeMap := map[string]interface{}{
@@ -26,3 +30,91 @@ func (diagnosticsConn *Connection) MobileGestaltQuery(keys []string) (interface{
 	plist, err := ios.ParsePlist(respBytes)
 	return plist, err
 }
+
+//MobileGestalt queries all keys in one request and returns their values. Keys the device does not know are missing in the result.
+//Since iOS 17 MobileGestalt queries are deprecated and return an error.
+func (diagnosticsConn *Connection) MobileGestalt(keys ...string) (map[string]interface{}, error) {
+	response, err := diagnosticsConn.MobileGestaltQuery(keys)
+	if err != nil {
+		return nil, err
+	}
+	plist, _ := response.(map[string]interface{})
+	if status, _ := plist["Status"].(string); status != "Success" {
+		return nil, fmt.Errorf("MobileGestalt query failed: %+v", plist)
+	}
+	diagnostics, _ := plist["Diagnostics"].(map[string]interface{})
+	values, ok := diagnostics["MobileGestalt"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("MobileGestalt query returned no values: %+v", plist)
+	}
+	if status, _ := values["Status"].(string); status != "" && status != "Success" {
+		return nil, fmt.Errorf("MobileGestalt query failed with status %s", status)
+	}
+	delete(values, "Status")
+	return values, nil
+}
+
+//GestaltInfo contains commonly used MobileGestalt keys
+type GestaltInfo struct {
+	ProductType          string  `json:"productType"`
+	ProductVersion       string  `json:"productVersion"`
+	BuildVersion         string  `json:"buildVersion"`
+	SerialNumber         string  `json:"serialNumber"`
+	UniqueDeviceID       string  `json:"uniqueDeviceID"`
+	UniqueChipID         uint64  `json:"uniqueChipID"`
+	DeviceName           string  `json:"deviceName"`
+	ModelNumber          string  `json:"modelNumber"`
+	RegionInfo           string  `json:"regionInfo"`
+	HWModelStr           string  `json:"hwModel"`
+	CPUArchitecture      string  `json:"cpuArchitecture"`
+	DeviceColor          string  `json:"deviceColor"`
+	DeviceEnclosureColor string  `json:"deviceEnclosureColor"`
+	ScreenWidth          uint64  `json:"screenWidth"`
+	ScreenHeight         uint64  `json:"screenHeight"`
+	ScreenScale          float64 `json:"screenScale"`
+}
+
+//gestaltInfoKeys maps the MobileGestalt keys of GestaltInfo to setters for its fields
+var gestaltInfoKeys = map[string]func(info *GestaltInfo, value interface{}){
+	"ProductType":            func(info *GestaltInfo, v interface{}) { info.ProductType, _ = v.(string) },
+	"ProductVersion":         func(info *GestaltInfo, v interface{}) { info.ProductVersion, _ = v.(string) },
+	"BuildVersion":           func(info *GestaltInfo, v interface{}) { info.BuildVersion, _ = v.(string) },
+	"SerialNumber":           func(info *GestaltInfo, v interface{}) { info.SerialNumber, _ = v.(string) },
+	"UniqueDeviceID":         func(info *GestaltInfo, v interface{}) { info.UniqueDeviceID, _ = v.(string) },
+	"UniqueChipID":           func(info *GestaltInfo, v interface{}) { info.UniqueChipID = uint64(toInt64(v)) },
+	"UserAssignedDeviceName": func(info *GestaltInfo, v interface{}) { info.DeviceName, _ = v.(string) },
+	"ModelNumber":            func(info *GestaltInfo, v interface{}) { info.ModelNumber, _ = v.(string) },
+	"RegionInfo":             func(info *GestaltInfo, v interface{}) { info.RegionInfo, _ = v.(string) },
+	"HWModelStr":             func(info *GestaltInfo, v interface{}) { info.HWModelStr, _ = v.(string) },
+	"CPUArchitecture":        func(info *GestaltInfo, v interface{}) { info.CPUArchitecture, _ = v.(string) },
+	"DeviceColor":            func(info *GestaltInfo, v interface{}) { info.DeviceColor, _ = v.(string) },
+	"DeviceEnclosureColor":   func(info *GestaltInfo, v interface{}) { info.DeviceEnclosureColor, _ = v.(string) },
+	"main-screen-width":      func(info *GestaltInfo, v interface{}) { info.ScreenWidth = uint64(toInt64(v)) },
+	"main-screen-height":     func(info *GestaltInfo, v interface{}) { info.ScreenHeight = uint64(toInt64(v)) },
+	"main-screen-scale": func(info *GestaltInfo, v interface{}) {
+		if scale, ok := v.(float64); ok {
+			info.ScreenScale = scale
+			return
+		}
+		info.ScreenScale = float64(toInt64(v))
+	},
+}
+
+//GestaltInfo queries the keys of GestaltInfo in one request
+func (diagnosticsConn *Connection) GestaltInfo() (GestaltInfo, error) {
+	keys := make([]string, 0, len(gestaltInfoKeys))
+	for k := range gestaltInfoKeys {
+		keys = append(keys, k)
+	}
+	values, err := diagnosticsConn.MobileGestalt(keys...)
+	if err != nil {
+		return GestaltInfo{}, err
+	}
+	var info GestaltInfo
+	for k, v := range values {
+		if setter, ok := gestaltInfoKeys[k]; ok {
+			setter(&info, v)
+		}
+	}
+	return info, nil
+}
@@ -1,6 +1,7 @@
 package dtx
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -49,12 +50,17 @@ func (d *Channel) ReceiveMethodCall(selector string) Message {
 //MethodCall is the standard DTX style remote method invocation pattern. The ObjectiveC Selector goes as a NSKeyedArchiver.archived NSString into the
 //DTXMessage payload, and the arguments are separately NSKeyArchiver.archived and put into the Auxiliary DTXPrimitiveDictionary. It returns the response message and an error.
 func (d *Channel) MethodCall(selector string, args ...interface{}) (Message, error) {
+	return d.MethodCallContext(context.Background(), selector, args...)
+}
+
+//MethodCallContext is like MethodCall but stops waiting for the response once ctx is done. The channel timeout still applies.
+func (d *Channel) MethodCallContext(ctx context.Context, selector string, args ...interface{}) (Message, error) {
 	payload, _ := nskeyedarchiver.ArchiveBin(selector)
 	auxiliary := NewPrimitiveDictionary()
 	for _, arg := range args {
 		auxiliary.AddNsKeyedArchivedObject(arg)
 	}
-	msg, err := d.SendAndAwaitReply(true, Methodinvocation, payload, auxiliary)
+	msg, err := d.SendAndAwaitReplyContext(ctx, true, Methodinvocation, payload, auxiliary)
 	if err != nil {
 		log.WithFields(log.Fields{"channel_id": d.channelName, "error": err, "methodselector": selector}).Info("failed starting invoking method")
 		return msg, err
@@ -99,7 +105,22 @@ func (d *Channel) AddResponseWaiter(identifier int, channel chan Message) {
 	d.responseWaiters[identifier] = channel
 }
 
+func (d *Channel) removeResponseWaiter(identifier int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	delete(d.responseWaiters, identifier)
+}
+
 func (d *Channel) SendAndAwaitReply(expectsReply bool, messageType int, payloadBytes []byte, auxiliary PrimitiveDictionary) (Message, error) {
+	return d.SendAndAwaitReplyContext(context.Background(), expectsReply, messageType, payloadBytes, auxiliary)
+}
+
+//SendAndAwaitReplyContext sends a message and waits for the response until the channel timeout passed, ctx is done
+//or the connection was closed.
+func (d *Channel) SendAndAwaitReplyContext(ctx context.Context, expectsReply bool, messageType int, payloadBytes []byte, auxiliary PrimitiveDictionary) (Message, error) {
+	if err := ctx.Err(); err != nil {
+		return Message{}, err
+	}
 	d.mutex.Lock()
 	identifier := d.messageIdentifier
 	d.messageIdentifier++
@@ -108,18 +129,29 @@ func (d *Channel) SendAndAwaitReply(expectsReply bool, messageType int, payloadB
 	if err != nil {
 		return Message{}, err
 	}
-	responseChannel := make(chan Message)
+	//buffered so a late response does not block the reader after we stopped waiting
+	responseChannel := make(chan Message, 1)
 	d.AddResponseWaiter(identifier, responseChannel)
 
 	err = d.connection.Send(bytes)
 	if err != nil {
+		d.removeResponseWaiter(identifier)
 		return Message{}, err
 	}
+	timer := time.NewTimer(d.timeout)
+	defer timer.Stop()
 	select {
 	case response := <-responseChannel:
 		return response, nil
-	case <-time.After(d.timeout):
+	case <-timer.C:
+		d.removeResponseWaiter(identifier)
 		return Message{}, fmt.Errorf("Timed out waiting for response for message:%d channel:%d", identifier, d.channelCode)
+	case <-ctx.Done():
+		d.removeResponseWaiter(identifier)
+		return Message{}, ctx.Err()
+	case <-d.connection.Closed():
+		d.removeResponseWaiter(identifier)
+		return Message{}, fmt.Errorf("Connection closed while waiting for response for message:%d channel:%d", identifier, d.channelCode)
 	}
 
 }
@@ -158,8 +190,7 @@ func (d *Channel) Dispatch(msg Message) {
 					if err != nil {
 						log.Error("decoding framente")
 					}
-					d.responseWaiters[msg.Identifier] <- msg
-					delete(d.responseWaiters, msg.Identifier)
+					d.deliverResponse(msg)
 				}
 				return
 			}
@@ -168,9 +199,20 @@ func (d *Channel) Dispatch(msg Message) {
 			return
 		}
 
-		d.responseWaiters[msg.Identifier] <- msg
-		delete(d.responseWaiters, msg.Identifier)
+		d.deliverResponse(msg)
 		return
 	}
 	d.messageDispatcher.Dispatch(msg)
 }
+
+//deliverResponse hands msg to the waiting SendAndAwaitReply call, responses nobody waits for anymore are dropped.
+//It must be called with d.mutex held.
+func (d *Channel) deliverResponse(msg Message) {
+	waiter, ok := d.responseWaiters[msg.Identifier]
+	if !ok {
+		log.Debugf("dropping response for message:%d channel:%d, nobody is waiting for it", msg.Identifier, d.channelCode)
+		return
+	}
+	waiter <- msg
+	delete(d.responseWaiters, msg.Identifier)
+}
@@ -1,6 +1,7 @@
 package dtx
 
 import (
+	"context"
 	"io"
 	"strings"
 	"sync"
@@ -23,6 +24,7 @@ type Connection struct {
 	capabilities           map[string]interface{}
 	mutex                  sync.Mutex
 	requestChannelMessages chan Message
+	closed                 chan struct{}
 }
 
 //Dispatcher is a simple interface containing a Dispatch func to receive dtx.Messages
@@ -91,14 +93,20 @@ func notifyOfPublishedCapabilities(msg Message) {
 
 //NewConnection connects and starts reading from a Dtx based service on the device
 func NewConnection(device ios.DeviceEntry, serviceName string) (*Connection, error) {
-	conn, err := ios.ConnectToService(device, serviceName)
+	return NewConnectionContext(context.Background(), device, serviceName)
+}
+
+//NewConnectionContext is like NewConnection but the returned Connection is closed as soon as ctx is done.
+//This stops the reader and makes pending and future calls on all channels fail.
+func NewConnectionContext(ctx context.Context, device ios.DeviceEntry, serviceName string) (*Connection, error) {
+	conn, err := ios.ConnectToServiceContext(ctx, device, serviceName)
 	if err != nil {
 		return nil, err
 	}
 	requestChannelMessages := make(chan Message, 5)
 
 	//The global channel has channelCode 0, so we need to start with channelCodeCounter==1
-	dtxConnection := &Connection{deviceConnection: conn, channelCodeCounter: 1, requestChannelMessages: requestChannelMessages}
+	dtxConnection := &Connection{deviceConnection: conn, channelCodeCounter: 1, requestChannelMessages: requestChannelMessages, closed: make(chan struct{})}
 
 	//The global channel is automatically present and used for requesting other channels and some other methods like notifyPublishedCapabilities
 	globalChannel := Channel{channelCode: 0,
@@ -110,10 +118,24 @@ func NewConnection(device ios.DeviceEntry, serviceName string) (*Connection, err
 		timeout:           5 * time.Second}
 	dtxConnection.globalChannel = &globalChannel
 	go reader(dtxConnection)
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				dtxConnection.Close()
+			case <-dtxConnection.closed:
+			}
+		}()
+	}
 
 	return dtxConnection, nil
 }
 
+//Closed returns a channel that is closed once the reader stopped, for example because the Connection was closed.
+func (dtxConn *Connection) Closed() <-chan struct{} {
+	return dtxConn.closed
+}
+
 //Send sends the byte slice directly to the device using the underlying DeviceConnectionInterface
 func (dtxConn *Connection) Send(message []byte) error {
 	return dtxConn.deviceConnection.Send(message)
@@ -121,6 +143,7 @@ func (dtxConn *Connection) Send(message []byte) error {
 
 //reader reads messages from the byte stream and dispatches them to the right channel when they are decoded.
 func reader(dtxConn *Connection) {
+	defer close(dtxConn.closed)
 	for {
 		reader := dtxConn.deviceConnection.Reader()
 		msg, err := ReadMessage(reader)
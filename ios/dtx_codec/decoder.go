@@ -80,6 +80,11 @@ func ReadMessage(reader io.Reader) (Message, error) {
 			return Message{}, err
 		}
 
+		if result.PayloadHeader.MessageType == UnknownTypeOne {
+			//raw data messages, like the ones sent by tap channels, are not archived
+			result.Payload = []interface{}{payloadBytes}
+			return result, nil
+		}
 		payload, err := nskeyedarchiver.Unarchive(payloadBytes)
 		if err != nil {
 			return Message{}, err
@@ -0,0 +1,107 @@
+package ios
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//Sentinel errors for common device failures. Use errors.Is to check for them, the errors returned by
+//go-ios wrap them together with the original usbmuxd or lockdown error.
+var (
+	//ErrDeviceNotFound is returned when the requested device is not attached or usbmuxd does not know it
+	ErrDeviceNotFound = errors.New("device not found")
+	//ErrPairingRequired is returned when there is no valid pair record for the device, pair it again
+	ErrPairingRequired = errors.New("pairing required")
+	//ErrPasswordProtected is returned when the device needs to be unlocked before the request can be completed
+	ErrPasswordProtected = errors.New("device is password protected")
+	//ErrServiceUnavailable is returned when lockdown could not start a service
+	ErrServiceUnavailable = errors.New("service unavailable")
+	//ErrDeveloperImageMissing is returned when a developer service could not be started, usually
+	//because the developer disk image is not mounted
+	ErrDeveloperImageMissing = errors.New("developer image missing")
+	//ErrMuxConnectRefused is returned when usbmuxd could not connect to the port on the device
+	ErrMuxConnectRefused = errors.New("usbmuxd connection refused")
+)
+
+//usbmuxd result codes
+const (
+	MuxResultOK          uint32 = 0
+	MuxResultBadCommand  uint32 = 1
+	MuxResultBadDevice   uint32 = 2
+	MuxResultConnRefused uint32 = 3
+	MuxResultBadVersion  uint32 = 6
+)
+
+//MuxError is returned when usbmuxd answers a request with a result code other than MuxResultOK.
+type MuxError struct {
+	MessageType string
+	Number      uint32
+}
+
+func (e MuxError) Error() string {
+	return fmt.Sprintf("usbmuxd %s failed with error code:%d", e.MessageType, e.Number)
+}
+
+//Is maps the usbmuxd result codes to ErrDeviceNotFound and ErrMuxConnectRefused
+func (e MuxError) Is(target error) bool {
+	switch target {
+	case ErrDeviceNotFound:
+		return e.Number == MuxResultBadDevice
+	case ErrMuxConnectRefused:
+		return e.Number == MuxResultConnRefused
+	}
+	return false
+}
+
+//LockdownError is returned when lockdownd answers a request with an Error.
+type LockdownError struct {
+	Request string
+	//Service is only set for StartService requests
+	Service string
+	Reason  string
+}
+
+func (e LockdownError) Error() string {
+	if e.Service != "" {
+		return fmt.Sprintf("lockdown %s for service:%s failed with reason:'%s'", e.Request, e.Service, e.Reason)
+	}
+	return fmt.Sprintf("lockdown %s failed with reason:'%s'", e.Request, e.Reason)
+}
+
+//Is maps lockdownd error reasons to the sentinel errors of this package
+func (e LockdownError) Is(target error) bool {
+	switch target {
+	case ErrPairingRequired:
+		switch e.Reason {
+		case "InvalidHostID", "PairingDialogResponsePending", "UserDeniedPairing", "InvalidPairRecord", "NotPaired":
+			return true
+		}
+	case ErrPasswordProtected:
+		return e.Reason == "PasswordProtected"
+	case ErrServiceUnavailable:
+		return e.Request == "StartService"
+	case ErrDeveloperImageMissing:
+		return e.Request == "StartService" && e.Reason == "InvalidService" && isDeveloperService(e.Service)
+	}
+	return false
+}
+
+//developerServicePrefixes are the services that are only available after mounting the developer disk image
+var developerServicePrefixes = []string{
+	"com.apple.instruments.",
+	"com.apple.testmanagerd.",
+	"com.apple.debugserver",
+	"com.apple.accessibility.axAuditDaemon.",
+	"com.apple.dt.",
+	"com.apple.mobile.screenshotr",
+}
+
+func isDeveloperService(service string) bool {
+	for _, prefix := range developerServicePrefixes {
+		if strings.HasPrefix(service, prefix) {
+			return true
+		}
+	}
+	return false
+}
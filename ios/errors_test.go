@@ -0,0 +1,52 @@
+package ios_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/usbmuxsim"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceErrors(t *testing.T) {
+	server, _, entry := usbmuxsim.StartPairedDevice(t, "udid0")
+	record, _ := server.PairRecord("udid0")
+	server.DeletePairRecord("udid0")
+
+	_, err := ios.GetDevice("unknown")
+	assert.ErrorIs(t, err, ios.ErrDeviceNotFound)
+
+	_, err = ios.ConnectLockdownWithSession(entry)
+	assert.ErrorIs(t, err, ios.ErrPairingRequired)
+
+	//a pair record the device does not know about
+	otherLockdownd, err := usbmuxsim.NewLockdownd(usbmuxsim.NewDevice("other"), usbmuxsim.DefaultProfile())
+	require.NoError(t, err)
+	unknownRecord, err := otherLockdownd.GeneratePairRecord("buid")
+	require.NoError(t, err)
+	server.SetPairRecord("udid0", unknownRecord)
+	_, err = ios.ConnectLockdownWithSession(entry)
+	assert.ErrorIs(t, err, ios.ErrPairingRequired)
+	var lockdownErr ios.LockdownError
+	require.True(t, errors.As(err, &lockdownErr))
+	assert.Equal(t, "InvalidHostID", lockdownErr.Reason)
+
+	server.SetPairRecord("udid0", record)
+	_, err = ios.ConnectToService(entry, "com.apple.unknown")
+	assert.ErrorIs(t, err, ios.ErrServiceUnavailable)
+	assert.False(t, errors.Is(err, ios.ErrDeveloperImageMissing))
+	_, err = ios.ConnectToService(entry, "com.apple.instruments.remoteserver")
+	assert.ErrorIs(t, err, ios.ErrServiceUnavailable)
+	assert.ErrorIs(t, err, ios.ErrDeveloperImageMissing)
+
+	muxConn, err := ios.NewUsbMuxConnectionSimple()
+	require.NoError(t, err)
+	defer muxConn.Close()
+	err = muxConn.Connect(entry.DeviceID, 1)
+	assert.ErrorIs(t, err, ios.ErrMuxConnectRefused)
+	var muxErr ios.MuxError
+	require.True(t, errors.As(err, &muxErr))
+	assert.Equal(t, ios.MuxResultConnRefused, muxErr.Number)
+}
@@ -0,0 +1,440 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/instruments"
+	"github.com/danielpaulus/go-ios/ios/notificationproxy"
+	log "github.com/sirupsen/logrus"
+)
+
+//Type is the kind of an Event
+type Type string
+
+const (
+	//Attached and Detached are sent by usbmuxd when the device is connected or disconnected
+	Attached Type = "attached"
+	Detached Type = "detached"
+	//Locked is sent once the device finished locking. LockStateChanged is sent whenever the device was locked or unlocked,
+	//the notification does not say which of both happened.
+	Locked           Type = "locked"
+	LockStateChanged Type = "lockStateChanged"
+	AppInstalled     Type = "appInstalled"
+	AppUninstalled   Type = "appUninstalled"
+	//SpringboardStarted is sent once springboard finished starting, f.ex. after a reboot or a respring
+	SpringboardStarted Type = "springboardStarted"
+	//Notification is any other Darwin notification that was requested with Config.Notifications
+	Notification Type = "notification"
+	//AppState is an application state transition reported by instruments, it needs the developer image
+	AppState Type = "appState"
+)
+
+//Types contains all event types
+var Types = []Type{Attached, Detached, Locked, LockStateChanged, AppInstalled, AppUninstalled, SpringboardStarted, Notification, AppState}
+
+//darwinNotifications maps the Darwin notifications observed with notificationproxy to their event type
+var darwinNotifications = map[string]Type{
+	"com.apple.springboard.lockcomplete":       Locked,
+	"com.apple.springboard.lockstate":          LockStateChanged,
+	"com.apple.mobile.application_installed":   AppInstalled,
+	"com.apple.mobile.application_uninstalled": AppUninstalled,
+	"com.apple.springboard.finishedstartup":    SpringboardStarted,
+}
+
+//Event is a single event of a device. Notification is set for all events based on Darwin notifications,
+//ApplicationState only for AppState events.
+type Event struct {
+	Type             Type              `json:"type"`
+	Timestamp        time.Time         `json:"timestamp"`
+	UDID             string            `json:"udid"`
+	DeviceID         int               `json:"deviceId,omitempty"`
+	Notification     string            `json:"notification,omitempty"`
+	ApplicationState *ApplicationState `json:"appState,omitempty"`
+}
+
+//ApplicationState is a state transition of an app, State is f.ex. "Foreground Running" or "Background Task Suspended"
+type ApplicationState struct {
+	Pid            uint64 `json:"pid"`
+	BundleID       string `json:"bundleId"`
+	AppName        string `json:"appName"`
+	ExecutablePath string `json:"executablePath"`
+	State          string `json:"state"`
+}
+
+//Config selects the events a Monitor reports
+type Config struct {
+	//Types are the event types to report, all types if empty. AppState events are only reported when the developer image
+	//is mounted, if they were not requested explicitly, they are skipped without an error otherwise.
+	Types []Type
+	//Notifications are additional Darwin notifications to observe, they are reported as Notification events
+	Notifications []string
+}
+
+//ParseTypes converts names like "attached" or "appState" to Types, the names are not case sensitive
+func ParseTypes(names []string) ([]Type, error) {
+	result := make([]Type, len(names))
+	for i, name := range names {
+		found := false
+		for _, t := range Types {
+			if strings.EqualFold(string(t), name) {
+				result[i] = t
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown event type %s, supported types are %v", name, Types)
+		}
+	}
+	return result, nil
+}
+
+//Monitor multiplexes usbmuxd attach and detach messages, Darwin notifications and app state transitions of a
+//device into one stream of Events. Read them with ReadEvent.
+//Darwin notifications and app state transitions come from services of the device, which end when it is detached.
+//They are started again once the device was attached and lockdownd accepts connections.
+type Monitor struct {
+	device           ios.DeviceEntry
+	types            map[Type]bool
+	notifications    []string
+	appStateRequired bool
+	events           chan Event
+	errs             chan error
+	done             chan struct{}
+	closers          []func()
+	sources          *deviceSources
+	mux              sync.Mutex
+	closeMux         sync.Once
+}
+
+//deviceSources are the event sources connected to one attachment of the device, they are stopped when it is detached
+type deviceSources struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	deviceID int
+	closers  []func()
+}
+
+//NewMonitor starts all sources needed for the event types in config. Attached and Detached events are only
+//reported for device.
+func NewMonitor(device ios.DeviceEntry, config Config) (*Monitor, error) {
+	m := &Monitor{
+		device:           device,
+		types:            map[Type]bool{},
+		appStateRequired: len(config.Types) > 0,
+		events:           make(chan Event, 100),
+		errs:             make(chan error, 3),
+		done:             make(chan struct{}),
+	}
+	types := config.Types
+	if len(types) == 0 {
+		types = Types
+	}
+	for _, t := range types {
+		m.types[t] = true
+	}
+	for notification, t := range darwinNotifications {
+		if m.types[t] {
+			m.notifications = append(m.notifications, notification)
+		}
+	}
+	if m.types[Notification] {
+		m.notifications = append(m.notifications, config.Notifications...)
+	}
+
+	//the device sources are restarted on Attached messages, so usbmuxd is needed for them too
+	sources := m.newDeviceSources(device.DeviceID)
+	err := m.startMux()
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	err = m.startDeviceSources(sources, device)
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+//ReadEvent blocks until the next event was received. It returns the error of a failed source and io.EOF once
+//the monitor was closed.
+func (m *Monitor) ReadEvent() (Event, error) {
+	select {
+	case event := <-m.events:
+		return event, nil
+	case err := <-m.errs:
+		return Event{}, err
+	case <-m.done:
+		return Event{}, io.EOF
+	}
+}
+
+//Close stops all sources
+func (m *Monitor) Close() {
+	m.closeMux.Do(func() {
+		close(m.done)
+		m.mux.Lock()
+		sources := m.sources
+		m.mux.Unlock()
+		m.stopDeviceSources(sources)
+		for _, closer := range m.closers {
+			closer()
+		}
+	})
+}
+
+func (m *Monitor) send(event Event) {
+	if !m.types[event.Type] {
+		return
+	}
+	event.Timestamp = time.Now()
+	if event.UDID == "" {
+		event.UDID = m.device.Properties.SerialNumber
+	}
+	select {
+	case m.events <- event:
+	case <-m.done:
+	}
+}
+
+//fail reports the error of a source, sources fail with errors after Close too, those are ignored
+func (m *Monitor) fail(err error) {
+	select {
+	case <-m.done:
+		return
+	default:
+	}
+	select {
+	case m.errs <- err:
+	default:
+		log.Debugf("dropping event source error: %v", err)
+	}
+}
+
+//sourceFailed reports the error of a device source unless it ended because the device was detached
+func (m *Monitor) sourceFailed(sources *deviceSources, err error) {
+	if sources.ctx.Err() != nil {
+		return
+	}
+	//the connections to the device can end before usbmuxd's Detached message arrived
+	list, listErr := ios.ListDevices()
+	if listErr == nil && !containsDeviceID(list, sources.deviceID) {
+		log.Debugf("ignoring error of detached device %d: %v", sources.deviceID, err)
+		return
+	}
+	m.fail(err)
+}
+
+func containsDeviceID(list ios.DeviceList, deviceID int) bool {
+	for _, device := range list.DeviceList {
+		if device.DeviceID == deviceID {
+			return true
+		}
+	}
+	return false
+}
+
+//newDeviceSources replaces the current device sources, which have to be stopped already
+func (m *Monitor) newDeviceSources(deviceID int) *deviceSources {
+	ctx, cancel := context.WithCancel(context.Background())
+	sources := &deviceSources{ctx: ctx, cancel: cancel, deviceID: deviceID}
+	m.mux.Lock()
+	m.sources = sources
+	m.mux.Unlock()
+	select {
+	case <-m.done:
+		cancel()
+	default:
+	}
+	return sources
+}
+
+//addCloser registers closer with sources, it is called right away if they were stopped already
+func (m *Monitor) addCloser(sources *deviceSources, closer func()) {
+	m.mux.Lock()
+	if sources.ctx.Err() == nil {
+		sources.closers = append(sources.closers, closer)
+		closer = nil
+	}
+	m.mux.Unlock()
+	if closer != nil {
+		closer()
+	}
+}
+
+func (m *Monitor) stopDeviceSources(sources *deviceSources) {
+	if sources == nil {
+		return
+	}
+	m.mux.Lock()
+	sources.cancel()
+	closers := sources.closers
+	sources.closers = nil
+	m.mux.Unlock()
+	for _, closer := range closers {
+		closer()
+	}
+}
+
+//startDeviceSources connects to the services of device needed for the event types of the monitor
+func (m *Monitor) startDeviceSources(sources *deviceSources, device ios.DeviceEntry) error {
+	if len(m.notifications) > 0 {
+		err := m.startNotificationProxy(sources, device)
+		if err != nil {
+			return err
+		}
+	}
+	if m.types[AppState] {
+		err := m.startAppState(sources, device)
+		if err != nil && m.appStateRequired {
+			return err
+		}
+		if err != nil {
+			log.Warnf("not reporting app state events, is the developer image mounted? %v", err)
+		}
+	}
+	return nil
+}
+
+//restartDeviceSources waits until lockdownd of the reattached device accepts connections and starts the sources again
+func (m *Monitor) restartDeviceSources(sources *deviceSources, device ios.DeviceEntry) {
+	if len(m.notifications) == 0 && !m.types[AppState] {
+		return
+	}
+	err := ios.WaitForLockdown(sources.ctx, device)
+	if err == nil {
+		err = m.startDeviceSources(sources, device)
+	}
+	if err != nil {
+		m.sourceFailed(sources, fmt.Errorf("failed restarting event sources after the device was attached: %w", err))
+	}
+}
+
+func (m *Monitor) startMux() error {
+	muxConn, err := ios.NewUsbMuxConnectionSimple()
+	if err != nil {
+		return fmt.Errorf("failed connecting to usbmuxd: %w", err)
+	}
+	attachedReceiver, err := muxConn.Listen()
+	if err != nil {
+		muxConn.Close()
+		return err
+	}
+	m.closers = append(m.closers, muxConn.Close)
+	udid := m.device.Properties.SerialNumber
+	//a device connected over USB and Wi-Fi is listed twice with the same udid, only the entry of the monitored
+	//connection type is followed
+	connectionType := m.device.Properties.ConnectionType
+	go func() {
+		//the DeviceID changes on every attach and Detached messages contain only the DeviceID
+		deviceID := m.device.DeviceID
+		//usbmuxd starts with Attached messages for all connected devices, which are not reported
+		initial := true
+		for {
+			msg, err := attachedReceiver()
+			if err != nil {
+				m.fail(fmt.Errorf("usbmuxd listen failed: %w", err))
+				return
+			}
+			if msg.DeviceAttached() && msg.Properties.SerialNumber == udid && msg.Properties.ConnectionType == connectionType {
+				if initial && msg.DeviceID == deviceID {
+					continue
+				}
+				initial = false
+				deviceID = msg.DeviceID
+				m.mux.Lock()
+				previous := m.sources
+				m.mux.Unlock()
+				m.stopDeviceSources(previous)
+				m.send(Event{Type: Attached, DeviceID: msg.DeviceID})
+				go m.restartDeviceSources(m.newDeviceSources(msg.DeviceID), msg.DeviceEntry())
+			}
+			if msg.DeviceDetached() && msg.DeviceID == deviceID {
+				initial = false
+				m.mux.Lock()
+				sources := m.sources
+				m.mux.Unlock()
+				m.stopDeviceSources(sources)
+				m.send(Event{Type: Detached, DeviceID: msg.DeviceID})
+			}
+		}
+	}()
+	return nil
+}
+
+func (m *Monitor) startNotificationProxy(sources *deviceSources, device ios.DeviceEntry) error {
+	conn, err := notificationproxy.New(device)
+	if err != nil {
+		return fmt.Errorf("failed connecting to notificationproxy: %w", err)
+	}
+	subscription, err := conn.Subscribe(m.notifications...)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	m.addCloser(sources, conn.Close)
+	go func() {
+		for notification := range subscription.Notifications() {
+			t, ok := darwinNotifications[notification]
+			if !ok {
+				t = Notification
+			}
+			m.send(Event{Type: t, Notification: notification})
+		}
+		if err := subscription.Err(); err != nil && !errors.Is(err, io.EOF) {
+			m.sourceFailed(sources, fmt.Errorf("notificationproxy failed: %w", err))
+		}
+	}()
+	return nil
+}
+
+func (m *Monitor) startAppState(sources *deviceSources, device ios.DeviceEntry) error {
+	receive, closeFunc, err := instruments.ListenAppStateNotifications(device)
+	if err != nil {
+		return err
+	}
+	m.addCloser(sources, func() {
+		err := closeFunc()
+		if err != nil {
+			log.Debugf("failed closing app state notifications: %v", err)
+		}
+	})
+	go func() {
+		for {
+			notification, err := receive()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					m.sourceFailed(sources, fmt.Errorf("app state notifications failed: %w", err))
+				}
+				return
+			}
+			state := NewApplicationState(notification)
+			m.send(Event{Type: AppState, ApplicationState: &state})
+		}
+	}()
+	return nil
+}
+
+//NewApplicationState converts an applicationStateNotification of instruments
+func NewApplicationState(notification map[string]interface{}) ApplicationState {
+	state := ApplicationState{}
+	switch pid := notification["pid"].(type) {
+	case uint64:
+		state.Pid = pid
+	case int64:
+		state.Pid = uint64(pid)
+	}
+	state.BundleID, _ = notification["displayID"].(string)
+	state.AppName, _ = notification["appName"].(string)
+	state.ExecutablePath, _ = notification["execName"].(string)
+	state.State, _ = notification["state_description"].(string)
+	return state
+}
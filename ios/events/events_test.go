@@ -0,0 +1,140 @@
+package events_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/events"
+	"github.com/danielpaulus/go-ios/ios/usbmuxsim"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"howett.net/plist"
+)
+
+//startDevice simulates a device with a notification_proxy that relays every notification right after it was observed
+func startDevice(t *testing.T) (*usbmuxsim.Server, *usbmuxsim.Device, ios.DeviceEntry) {
+	server, lockdownd, entry := usbmuxsim.StartPairedDevice(t, "udid0")
+	lockdownd.HandleService("com.apple.mobile.notification_proxy", func(conn net.Conn) {
+		codec := ios.NewPlistCodec()
+		reader := bufio.NewReader(conn)
+		for {
+			request, err := codec.Decode(reader)
+			if err != nil {
+				return
+			}
+			var message map[string]interface{}
+			plist.NewDecoder(bytes.NewReader(request)).Decode(&message)
+			if message["Command"] != "ObserveNotification" {
+				continue
+			}
+			b, _ := codec.Encode(map[string]interface{}{"Command": "RelayNotification", "Name": message["Name"]})
+			conn.Write(b)
+		}
+	})
+	return server, lockdownd.Device(), entry
+}
+
+func TestNotificationEvents(t *testing.T) {
+	_, _, device := startDevice(t)
+	monitor, err := events.NewMonitor(device, events.Config{
+		Types:         []events.Type{events.Locked, events.Notification},
+		Notifications: []string{"com.example.test"},
+	})
+	require.NoError(t, err)
+	defer monitor.Close()
+
+	received := map[events.Type]string{}
+	for i := 0; i < 2; i++ {
+		event, err := monitor.ReadEvent()
+		require.NoError(t, err)
+		assert.Equal(t, "udid0", event.UDID)
+		received[event.Type] = event.Notification
+	}
+	assert.Equal(t, map[events.Type]string{
+		events.Locked:       "com.apple.springboard.lockcomplete",
+		events.Notification: "com.example.test",
+	}, received)
+}
+
+func TestAttachEvents(t *testing.T) {
+	server, simDevice, device := startDevice(t)
+	monitor, err := events.NewMonitor(device, events.Config{Types: []events.Type{events.Attached, events.Detached}})
+	require.NoError(t, err)
+
+	server.Attach(usbmuxsim.NewDevice("other"))
+	server.Detach("udid0")
+	server.Attach(simDevice)
+
+	event, err := monitor.ReadEvent()
+	require.NoError(t, err)
+	assert.Equal(t, events.Detached, event.Type)
+	assert.Equal(t, device.DeviceID, event.DeviceID)
+	event, err = monitor.ReadEvent()
+	require.NoError(t, err)
+	assert.Equal(t, events.Attached, event.Type)
+	assert.Equal(t, simDevice.DeviceID(), event.DeviceID)
+
+	monitor.Close()
+	_, err = monitor.ReadEvent()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestNotificationsAfterReattach(t *testing.T) {
+	server, simDevice, device := startDevice(t)
+	monitor, err := events.NewMonitor(device, events.Config{Types: []events.Type{events.Attached, events.Locked}})
+	require.NoError(t, err)
+	defer monitor.Close()
+
+	event, err := monitor.ReadEvent()
+	require.NoError(t, err)
+	assert.Equal(t, events.Locked, event.Type)
+
+	server.Detach("udid0")
+	server.Attach(simDevice)
+	event, err = monitor.ReadEvent()
+	require.NoError(t, err)
+	assert.Equal(t, events.Attached, event.Type)
+	//the simulated notification_proxy relays lockcomplete again once the monitor observes it on the new connection
+	event, err = monitor.ReadEvent()
+	require.NoError(t, err)
+	assert.Equal(t, events.Locked, event.Type)
+}
+
+func TestNetworkTwinIsIgnored(t *testing.T) {
+	server, simDevice, device := startDevice(t)
+	//usbmuxd lists the Wi-Fi connection of the same device right after Listen, it must not count as a reattach
+	server.Attach(usbmuxsim.NewNetworkDevice("udid0"))
+	monitor, err := events.NewMonitor(device, events.Config{Types: []events.Type{events.Attached, events.Detached, events.Locked}})
+	require.NoError(t, err)
+	defer monitor.Close()
+
+	event, err := monitor.ReadEvent()
+	require.NoError(t, err)
+	require.Equal(t, events.Locked, event.Type)
+
+	server.Detach("udid0")
+	server.Attach(simDevice)
+	event, err = monitor.ReadEvent()
+	require.NoError(t, err)
+	assert.Equal(t, events.Detached, event.Type)
+	assert.Equal(t, device.DeviceID, event.DeviceID)
+	event, err = monitor.ReadEvent()
+	require.NoError(t, err)
+	assert.Equal(t, events.Attached, event.Type)
+	assert.Equal(t, simDevice.DeviceID(), event.DeviceID)
+	event, err = monitor.ReadEvent()
+	require.NoError(t, err)
+	assert.Equal(t, events.Locked, event.Type)
+}
+
+func TestParseTypes(t *testing.T) {
+	types, err := events.ParseTypes([]string{"attached", "APPSTATE"})
+	require.NoError(t, err)
+	assert.Equal(t, []events.Type{events.Attached, events.AppState}, types)
+	_, err = events.ParseTypes([]string{"unknown"})
+	assert.Error(t, err)
+}
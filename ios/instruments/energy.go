@@ -0,0 +1,177 @@
+package instruments
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/diagnostics"
+	dtx "github.com/danielpaulus/go-ios/ios/dtx_codec"
+	log "github.com/sirupsen/logrus"
+)
+
+//EnergySample is the energy impact of a process as shown by the Xcode energy gauge. The costs are unitless
+//energy impact values, Values contains all raw values of the sample.
+type EnergySample struct {
+	Pid               uint64                 `json:"pid"`
+	Cost              float64                `json:"cost"`
+	CPUCost           float64                `json:"cpuCost"`
+	GPUCost           float64                `json:"gpuCost"`
+	NetworkingCost    float64                `json:"networkingCost"`
+	LocationCost      float64                `json:"locationCost"`
+	DisplayCost       float64                `json:"displayCost"`
+	AppStateCost      float64                `json:"appStateCost"`
+	Overhead          float64                `json:"overhead"`
+	ThermalCost       float64                `json:"thermalCost"`
+	SecondsSinceStart float64                `json:"secondsSinceStart"`
+	Values            map[string]interface{} `json:"values"`
+}
+
+//EnergyReport contains the energy samples of all monitored processes and, if available, the battery state at the same time
+type EnergyReport struct {
+	Timestamp time.Time                `json:"timestamp"`
+	Processes []EnergySample           `json:"processes"`
+	Battery   *diagnostics.BatteryInfo `json:"battery,omitempty"`
+	//PowerDraw is the power drawn from the battery in watts
+	PowerDraw float64 `json:"powerDraw,omitempty"`
+}
+
+//EnergyMonitor samples the energy impact of processes using the energy gauge data provider of Xcode
+type EnergyMonitor struct {
+	conn    *dtx.Connection
+	channel *dtx.Channel
+	pids    []interface{}
+	battery *diagnostics.Connection
+}
+
+//NewEnergyMonitor starts energy sampling for pids. If battery is true, every report also contains the battery state
+//read from the diagnostics service.
+func NewEnergyMonitor(device ios.DeviceEntry, pids []uint64, battery bool) (*EnergyMonitor, error) {
+	conn, err := connectInstruments(device)
+	if err != nil {
+		return nil, err
+	}
+	pidList := make([]interface{}, len(pids))
+	for i, pid := range pids {
+		pidList[i] = pid
+	}
+	channel := conn.RequestChannelIdentifier(energyChannel, loggingDispatcher{conn})
+	_, err = channel.MethodCall("startSamplingForPIDs:", pidList)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed starting energy sampling: %w", err)
+	}
+	monitor := &EnergyMonitor{conn: conn, channel: channel, pids: pidList}
+	if battery {
+		monitor.battery, err = diagnostics.New(device)
+		if err != nil {
+			monitor.Close()
+			return nil, fmt.Errorf("failed connecting to diagnostics for battery values: %w", err)
+		}
+	}
+	return monitor, nil
+}
+
+//Sample returns the current energy impact of all monitored processes
+func (e *EnergyMonitor) Sample() (EnergyReport, error) {
+	response, err := e.channel.MethodCall("sampleAttributes:forPIDs:", map[string]interface{}{}, e.pids)
+	if err != nil {
+		return EnergyReport{}, err
+	}
+	report, err := ParseEnergyMessage(response)
+	if err != nil {
+		return EnergyReport{}, err
+	}
+	if e.battery != nil {
+		battery, err := e.battery.BatteryInfo()
+		if err != nil {
+			return EnergyReport{}, err
+		}
+		report.Battery = &battery
+		report.PowerDraw = battery.PowerDraw()
+	}
+	return report, nil
+}
+
+//Stream samples every interval and sends the reports on the returned channel until Close is called or sampling fails.
+//The error that stopped the stream is sent on the error channel, both channels are closed afterwards.
+func (e *EnergyMonitor) Stream(interval time.Duration) (<-chan EnergyReport, <-chan error) {
+	reports := make(chan EnergyReport)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(reports)
+		defer close(errs)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			report, err := e.Sample()
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case reports <- report:
+			case <-e.conn.Closed():
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-e.conn.Closed():
+				return
+			}
+		}
+	}()
+	return reports, errs
+}
+
+//Close stops sampling and closes the connections
+func (e *EnergyMonitor) Close() error {
+	err := e.channel.MethodCallAsync("stopSamplingForPIDs:", e.pids)
+	if err != nil {
+		log.Debugf("failed stopping energy sampling: %v", err)
+	}
+	if e.battery != nil {
+		err = e.battery.Close()
+		if err != nil {
+			log.Debugf("failed closing diagnostics: %v", err)
+		}
+	}
+	return e.conn.Close()
+}
+
+//ParseEnergyMessage decodes the response of sampleAttributes:forPIDs:, a dictionary of the energy values per pid
+func ParseEnergyMessage(msg dtx.Message) (EnergyReport, error) {
+	if len(msg.Payload) != 1 {
+		return EnergyReport{}, fmt.Errorf("unexpected energy payload %+v", msg.Payload)
+	}
+	processes, ok := msg.Payload[0].(map[string]interface{})
+	if !ok {
+		return EnergyReport{}, fmt.Errorf("unexpected energy payload %+v", msg.Payload[0])
+	}
+	report := EnergyReport{Timestamp: time.Now(), Processes: []EnergySample{}}
+	for pid, v := range processes {
+		values, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		sample := EnergySample{
+			Cost:              toFloat64(values["energy.cost"]),
+			CPUCost:           toFloat64(values["energy.CPU.cost"]),
+			GPUCost:           toFloat64(values["energy.GPU.cost"]),
+			NetworkingCost:    toFloat64(values["energy.networking.cost"]),
+			LocationCost:      toFloat64(values["energy.location.cost"]),
+			DisplayCost:       toFloat64(values["energy.display.cost"]),
+			AppStateCost:      toFloat64(values["energy.appstate.cost"]),
+			Overhead:          toFloat64(values["energy.overhead"]),
+			ThermalCost:       toFloat64(values["energy.inducedthermalstate.cost"]),
+			SecondsSinceStart: toFloat64(values["kIDEGaugeSecondsSinceInitialQueryKey"]),
+			Values:            values,
+		}
+		sample.Pid, _ = strconv.ParseUint(pid, 10, 64)
+		report.Processes = append(report.Processes, sample)
+	}
+	sort.Slice(report.Processes, func(i, j int) bool { return report.Processes[i].Pid < report.Processes[j].Pid })
+	return report, nil
+}
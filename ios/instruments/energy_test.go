@@ -0,0 +1,36 @@
+package instruments_test
+
+import (
+	"testing"
+
+	dtx "github.com/danielpaulus/go-ios/ios/dtx_codec"
+	"github.com/danielpaulus/go-ios/ios/instruments"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEnergyMessage(t *testing.T) {
+	msg := dtx.Message{Payload: []interface{}{map[string]interface{}{
+		"42": map[string]interface{}{
+			"energy.cost":                          10.5,
+			"energy.CPU.cost":                      8.0,
+			"energy.networking.cost":               uint64(2),
+			"energy.display.cost":                  0.5,
+			"kIDEGaugeSecondsSinceInitialQueryKey": uint64(3),
+		},
+	}}}
+	report, err := instruments.ParseEnergyMessage(msg)
+	require.NoError(t, err)
+	require.Len(t, report.Processes, 1)
+	sample := report.Processes[0]
+	assert.Equal(t, uint64(42), sample.Pid)
+	assert.Equal(t, 10.5, sample.Cost)
+	assert.Equal(t, 8.0, sample.CPUCost)
+	assert.Equal(t, 2.0, sample.NetworkingCost)
+	assert.Equal(t, 0.5, sample.DisplayCost)
+	assert.Equal(t, 0.0, sample.LocationCost)
+	assert.Equal(t, 3.0, sample.SecondsSinceStart)
+
+	_, err = instruments.ParseEnergyMessage(dtx.Message{Payload: []interface{}{"unexpected"}})
+	assert.Error(t, err)
+}
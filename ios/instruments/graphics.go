@@ -0,0 +1,103 @@
+package instruments
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios"
+	dtx "github.com/danielpaulus/go-ios/ios/dtx_codec"
+	log "github.com/sirupsen/logrus"
+)
+
+//GraphicsSample is a sample of the graphics.opengl instruments channel, sent about once per second.
+//Values contains all raw values of the sample, including the ones without a field.
+type GraphicsSample struct {
+	Timestamp             time.Time              `json:"timestamp"`
+	FPS                   float64                `json:"fps"`
+	DeviceUtilization     float64                `json:"deviceUtilization"`
+	RendererUtilization   float64                `json:"rendererUtilization"`
+	TilerUtilization      float64                `json:"tilerUtilization"`
+	AllocatedSystemMemory uint64                 `json:"allocatedSystemMemory"`
+	InUseSystemMemory     uint64                 `json:"inUseSystemMemory"`
+	Values                map[string]interface{} `json:"values"`
+}
+
+//GraphicsSampler streams frame rate and GPU utilization samples
+type GraphicsSampler struct {
+	conn     *dtx.Connection
+	channel  *dtx.Channel
+	messages chan dtx.Message
+	done     chan struct{}
+}
+
+//NewGraphicsSampler connects to instruments and starts sampling. Read the samples with ReadSample.
+func NewGraphicsSampler(device ios.DeviceEntry) (*GraphicsSampler, error) {
+	conn, err := connectInstruments(device)
+	if err != nil {
+		return nil, err
+	}
+	messages := make(chan dtx.Message, 100)
+	done := make(chan struct{})
+	channel := conn.RequestChannelIdentifier(graphicsChannel, tapDispatcher{conn: conn, messages: messages, done: done})
+	err = channel.MethodCallAsync("startSamplingAtTimeInterval:", 0.0)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed starting graphics sampling: %w", err)
+	}
+	return &GraphicsSampler{conn: conn, channel: channel, messages: messages, done: done}, nil
+}
+
+//ReadSample blocks until the next sample was received and returns io.EOF once the sampler was closed.
+func (g *GraphicsSampler) ReadSample() (GraphicsSample, error) {
+	for {
+		select {
+		case msg := <-g.messages:
+			sample, ok := ParseGraphicsMessage(msg)
+			if !ok {
+				log.Tracef("skipping graphics message %+v", msg.Payload)
+				continue
+			}
+			return sample, nil
+		case <-g.done:
+			return GraphicsSample{}, io.EOF
+		case <-g.conn.Closed():
+			return GraphicsSample{}, io.EOF
+		}
+	}
+}
+
+//Close stops sampling and closes the connection
+func (g *GraphicsSampler) Close() error {
+	close(g.done)
+	err := g.channel.MethodCallAsync("stopSampling")
+	if err != nil {
+		log.Debugf("failed stopping graphics sampling: %v", err)
+	}
+	return g.conn.Close()
+}
+
+//ParseGraphicsMessage decodes a sample of the graphics channel and returns false if msg is no sample
+func ParseGraphicsMessage(msg dtx.Message) (GraphicsSample, bool) {
+	if len(msg.Payload) != 1 {
+		return GraphicsSample{}, false
+	}
+	values, ok := msg.Payload[0].(map[string]interface{})
+	if !ok {
+		return GraphicsSample{}, false
+	}
+	if _, ok := values["CoreAnimationFramesPerSecond"]; !ok {
+		return GraphicsSample{}, false
+	}
+	sample := GraphicsSample{
+		Timestamp:           time.Now(),
+		FPS:                 toFloat64(values["CoreAnimationFramesPerSecond"]),
+		DeviceUtilization:   toFloat64(values["Device Utilization %"]),
+		RendererUtilization: toFloat64(values["Renderer Utilization %"]),
+		TilerUtilization:    toFloat64(values["Tiler Utilization %"]),
+		Values:              values,
+	}
+	sample.AllocatedSystemMemory, _ = toUint64(values["Alloc system memory"])
+	sample.InUseSystemMemory, _ = toUint64(values["In use system memory"])
+	return sample, true
+}
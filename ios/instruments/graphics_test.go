@@ -0,0 +1,33 @@
+package instruments_test
+
+import (
+	"testing"
+
+	dtx "github.com/danielpaulus/go-ios/ios/dtx_codec"
+	"github.com/danielpaulus/go-ios/ios/instruments"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGraphicsMessage(t *testing.T) {
+	values := map[string]interface{}{
+		"CoreAnimationFramesPerSecond": uint64(59),
+		"Device Utilization %":         uint64(23),
+		"Renderer Utilization %":       uint64(20),
+		"Tiler Utilization %":          uint64(5),
+		"Alloc system memory":          uint64(50593792),
+		"In use system memory":         uint64(10731520),
+		"IOGLBundleName":               "Built-In",
+	}
+	sample, ok := instruments.ParseGraphicsMessage(dtx.Message{Payload: []interface{}{values}})
+	assert.True(t, ok)
+	assert.Equal(t, 59.0, sample.FPS)
+	assert.Equal(t, 23.0, sample.DeviceUtilization)
+	assert.Equal(t, 20.0, sample.RendererUtilization)
+	assert.Equal(t, 5.0, sample.TilerUtilization)
+	assert.Equal(t, uint64(50593792), sample.AllocatedSystemMemory)
+	assert.Equal(t, uint64(10731520), sample.InUseSystemMemory)
+	assert.Equal(t, "Built-In", sample.Values["IOGLBundleName"])
+
+	_, ok = instruments.ParseGraphicsMessage(dtx.Message{Payload: []interface{}{map[string]interface{}{"other": uint64(1)}}})
+	assert.False(t, ok)
+}
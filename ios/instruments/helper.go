@@ -20,6 +20,21 @@ func (p loggingDispatcher) Dispatch(m dtx.Message) {
 	log.Debug(m)
 }
 
+//tapDispatcher forwards the messages of a tap channel until done is closed
+type tapDispatcher struct {
+	conn     *dtx.Connection
+	messages chan dtx.Message
+	done     chan struct{}
+}
+
+func (t tapDispatcher) Dispatch(msg dtx.Message) {
+	dtx.SendAckIfNeeded(t.conn, msg)
+	select {
+	case t.messages <- msg:
+	case <-t.done:
+	}
+}
+
 func connectInstruments(device ios.DeviceEntry) (*dtx.Connection, error) {
 	dtxConn, err := dtx.NewConnection(device, serviceName)
 	if err != nil {
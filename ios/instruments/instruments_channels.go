@@ -13,3 +13,7 @@ const watchProcessControlChannel = "com.apple.dt.Xcode.WatchProcessControl"
 
 const assetsChannel = "com.apple.instruments.server.services.assets"
 const activityTraceTapChannel = "com.apple.instruments.server.services.activitytracetap"
+const networkingChannel = "com.apple.instruments.server.services.networking"
+const sysmontapChannel = "com.apple.instruments.server.services.sysmontap"
+const graphicsChannel = "com.apple.instruments.server.services.graphics.opengl"
+const energyChannel = "com.apple.xcode.debug-gauge-data-providers.Energy"
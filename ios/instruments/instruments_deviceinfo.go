@@ -1,6 +1,7 @@
 package instruments
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/danielpaulus/go-ios/ios"
@@ -57,6 +58,38 @@ func (d DeviceInfoService) NetworkInformation() (map[string]interface{}, error)
 	return extractMapPayload(response)
 }
 
+//SysmonProcessAttributes returns the names of all process attributes sysmontap can sample on this device,
+//f.ex. cpuUsage, physFootprint, threadCount or diskBytesRead
+func (d DeviceInfoService) SysmonProcessAttributes() ([]string, error) {
+	return d.stringList("sysmonProcessAttributes")
+}
+
+//SysmonSystemAttributes returns the names of all system attributes sysmontap can sample on this device
+func (d DeviceInfoService) SysmonSystemAttributes() ([]string, error) {
+	return d.stringList("sysmonSystemAttributes")
+}
+
+func (d DeviceInfoService) stringList(selector string) ([]string, error) {
+	response, err := d.channel.MethodCall(selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Payload) != 1 {
+		return nil, fmt.Errorf("unexpected response for %s: %+v", selector, response.Payload)
+	}
+	values, ok := response.Payload[0].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected response for %s: %+v", selector, response.Payload[0])
+	}
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
 func mapToProcInfo(procList []interface{}) []ProcessInfo {
 	result := make([]ProcessInfo, len(procList))
 	for i, procMapInt := range procList {
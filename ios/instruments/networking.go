@@ -0,0 +1,332 @@
+package instruments
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios"
+	dtx "github.com/danielpaulus/go-ios/ios/dtx_codec"
+	log "github.com/sirupsen/logrus"
+)
+
+//Types of NetworkEvent
+const (
+	NetworkInterfaceDetection  = "interfaceDetection"
+	NetworkConnectionDetection = "connectionDetection"
+	NetworkConnectionUpdate    = "connectionUpdate"
+	//NetworkConnectionExpired is not sent by the device, which does not report closed connections.
+	//NetworkStatistics.ExpireConnections creates it for connections without updates for a while.
+	NetworkConnectionExpired = "connectionExpired"
+)
+
+//message types sent by the networking service
+const (
+	networkMessageInterfaceDetection  = 0
+	networkMessageConnectionDetection = 1
+	networkMessageConnectionUpdate    = 2
+)
+
+//NetworkEvent is a single event of the networking service. Depending on Type, one of Interface, Connection or Update is set.
+type NetworkEvent struct {
+	Type       string             `json:"type"`
+	Timestamp  time.Time          `json:"timestamp"`
+	Interface  *NetworkInterface  `json:"interface,omitempty"`
+	Connection *NetworkConnection `json:"connection,omitempty"`
+	Update     *ConnectionUpdate  `json:"update,omitempty"`
+}
+
+//NetworkInterface is sent once for every network interface of the device
+type NetworkInterface struct {
+	Index uint64 `json:"index"`
+	Name  string `json:"name"`
+}
+
+//NetworkConnection is sent when a process opened a TCP or UDP socket
+type NetworkConnection struct {
+	Serial         uint64 `json:"serial"`
+	Pid            uint64 `json:"pid"`
+	LocalAddress   string `json:"localAddress"`
+	RemoteAddress  string `json:"remoteAddress"`
+	InterfaceIndex uint64 `json:"interfaceIndex"`
+	Interface      string `json:"interface,omitempty"`
+	RecvBufferSize uint64 `json:"recvBufferSize"`
+	RecvBufferUsed uint64 `json:"recvBufferUsed"`
+	Kind           uint64 `json:"kind"`
+}
+
+//ConnectionUpdate contains the traffic counters of a connection since it was opened
+type ConnectionUpdate struct {
+	Serial          uint64 `json:"serial"`
+	RxPackets       uint64 `json:"rxPackets"`
+	RxBytes         uint64 `json:"rxBytes"`
+	TxPackets       uint64 `json:"txPackets"`
+	TxBytes         uint64 `json:"txBytes"`
+	RxDuplicates    uint64 `json:"rxDuplicates"`
+	RxOutOfOrder    uint64 `json:"rxOutOfOrder"`
+	TxRetransmitted uint64 `json:"txRetransmitted"`
+	MinRTT          uint64 `json:"minRTT"`
+	AvgRTT          uint64 `json:"avgRTT"`
+	Time            uint64 `json:"time"`
+}
+
+//NetworkMonitor streams interface, connection and traffic events of all processes from the instruments networking service.
+type NetworkMonitor struct {
+	conn       *dtx.Connection
+	channel    *dtx.Channel
+	messages   chan dtx.Message
+	done       chan struct{}
+	interfaces map[uint64]string
+}
+
+//NewNetworkMonitor connects to instruments and starts monitoring. Read the events with ReadEvent.
+func NewNetworkMonitor(device ios.DeviceEntry) (*NetworkMonitor, error) {
+	conn, err := connectInstruments(device)
+	if err != nil {
+		return nil, err
+	}
+	messages := make(chan dtx.Message, 100)
+	done := make(chan struct{})
+	channel := conn.RequestChannelIdentifier(networkingChannel, tapDispatcher{conn: conn, messages: messages, done: done})
+	err = channel.MethodCallAsync("startMonitoring")
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed starting network monitoring: %w", err)
+	}
+	return &NetworkMonitor{conn: conn, channel: channel, messages: messages, done: done, interfaces: map[uint64]string{}}, nil
+}
+
+//ReadEvent blocks until the next event was received and returns io.EOF once the monitor was closed.
+//Connections get the name of their interface if it was detected before.
+func (n *NetworkMonitor) ReadEvent() (NetworkEvent, error) {
+	for {
+		select {
+		case msg := <-n.messages:
+			event, err := ParseNetworkMessage(msg)
+			if err != nil {
+				log.Debugf("skipping network message: %v", err)
+				continue
+			}
+			switch event.Type {
+			case NetworkInterfaceDetection:
+				n.interfaces[event.Interface.Index] = event.Interface.Name
+			case NetworkConnectionDetection:
+				event.Connection.Interface = n.interfaces[event.Connection.InterfaceIndex]
+			}
+			return event, nil
+		case <-n.done:
+			return NetworkEvent{}, io.EOF
+		case <-n.conn.Closed():
+			return NetworkEvent{}, io.EOF
+		}
+	}
+}
+
+//Close stops monitoring and closes the connection
+func (n *NetworkMonitor) Close() error {
+	close(n.done)
+	err := n.channel.MethodCallAsync("stopMonitoring")
+	if err != nil {
+		log.Debugf("failed stopping network monitoring: %v", err)
+	}
+	return n.conn.Close()
+}
+
+//ParseNetworkMessage decodes a message of the networking channel. Its payload is an array of the message type
+//and an array of values. The timestamp of the event is the time it was parsed.
+func ParseNetworkMessage(msg dtx.Message) (NetworkEvent, error) {
+	if len(msg.Payload) != 1 {
+		return NetworkEvent{}, fmt.Errorf("unexpected payload %+v", msg.Payload)
+	}
+	payload, ok := msg.Payload[0].([]interface{})
+	if !ok || len(payload) != 2 {
+		return NetworkEvent{}, fmt.Errorf("unexpected payload %+v", msg.Payload[0])
+	}
+	values, ok := payload[1].([]interface{})
+	if !ok {
+		return NetworkEvent{}, fmt.Errorf("unexpected values %+v", payload[1])
+	}
+	messageType, _ := toUint64(payload[0])
+	event := NetworkEvent{Timestamp: time.Now()}
+	switch messageType {
+	case networkMessageInterfaceDetection:
+		if len(values) < 2 {
+			return NetworkEvent{}, fmt.Errorf("interface detection has %d values", len(values))
+		}
+		index, _ := toUint64(values[0])
+		name, _ := values[1].(string)
+		event.Type = NetworkInterfaceDetection
+		event.Interface = &NetworkInterface{Index: index, Name: name}
+	case networkMessageConnectionDetection:
+		if len(values) < 8 {
+			return NetworkEvent{}, fmt.Errorf("connection detection has %d values", len(values))
+		}
+		numbers := uint64Values(values[2:])
+		local, _ := values[0].([]byte)
+		remote, _ := values[1].([]byte)
+		event.Type = NetworkConnectionDetection
+		event.Connection = &NetworkConnection{
+			LocalAddress:   parseSockaddr(local),
+			RemoteAddress:  parseSockaddr(remote),
+			InterfaceIndex: numbers[0],
+			Pid:            numbers[1],
+			RecvBufferSize: numbers[2],
+			RecvBufferUsed: numbers[3],
+			Serial:         numbers[4],
+			Kind:           numbers[5],
+		}
+	case networkMessageConnectionUpdate:
+		if len(values) < 11 {
+			return NetworkEvent{}, fmt.Errorf("connection update has %d values", len(values))
+		}
+		numbers := uint64Values(values)
+		event.Type = NetworkConnectionUpdate
+		event.Update = &ConnectionUpdate{
+			RxPackets:       numbers[0],
+			RxBytes:         numbers[1],
+			TxPackets:       numbers[2],
+			TxBytes:         numbers[3],
+			RxDuplicates:    numbers[4],
+			RxOutOfOrder:    numbers[5],
+			TxRetransmitted: numbers[6],
+			MinRTT:          numbers[7],
+			AvgRTT:          numbers[8],
+			Serial:          numbers[9],
+			Time:            numbers[10],
+		}
+	default:
+		return NetworkEvent{}, fmt.Errorf("unknown network message type %d", messageType)
+	}
+	return event, nil
+}
+
+func toUint64(value interface{}) (uint64, bool) {
+	switch v := value.(type) {
+	case uint64:
+		return v, true
+	case int64:
+		return uint64(v), true
+	case float64:
+		return uint64(v), true
+	}
+	return 0, false
+}
+
+func uint64Values(values []interface{}) []uint64 {
+	result := make([]uint64, len(values))
+	for i, v := range values {
+		result[i], _ = toUint64(v)
+	}
+	return result
+}
+
+//parseSockaddr converts a BSD sockaddr_in or sockaddr_in6 to "ip:port"
+func parseSockaddr(sockaddr []byte) string {
+	const afInet, afInet6 = 2, 30
+	if len(sockaddr) < 4 {
+		return ""
+	}
+	port := strconv.Itoa(int(binary.BigEndian.Uint16(sockaddr[2:])))
+	switch {
+	case sockaddr[1] == afInet && len(sockaddr) >= 8:
+		return net.JoinHostPort(net.IP(sockaddr[4:8]).String(), port)
+	case sockaddr[1] == afInet6 && len(sockaddr) >= 24:
+		return net.JoinHostPort(net.IP(sockaddr[8:24]).String(), port)
+	}
+	return ""
+}
+
+//ProcessNetworkStatistics are the summed up traffic counters of all connections of a process
+type ProcessNetworkStatistics struct {
+	Pid                uint64 `json:"pid"`
+	OpenConnections    int    `json:"openConnections"`
+	ExpiredConnections int    `json:"expiredConnections"`
+	RxPackets          uint64 `json:"rxPackets"`
+	RxBytes            uint64 `json:"rxBytes"`
+	TxPackets          uint64 `json:"txPackets"`
+	TxBytes            uint64 `json:"txBytes"`
+}
+
+type trackedConnection struct {
+	connection NetworkConnection
+	update     ConnectionUpdate
+	lastSeen   time.Time
+}
+
+//NetworkStatistics aggregates NetworkEvents to per process statistics.
+//The networking service does not report closed connections, use ExpireConnections to drop connections
+//without updates for a while. They are counted as expired, they might still be open but idle.
+type NetworkStatistics struct {
+	connections map[uint64]*trackedConnection
+	processes   map[uint64]*ProcessNetworkStatistics
+}
+
+//NewNetworkStatistics creates an empty NetworkStatistics
+func NewNetworkStatistics() *NetworkStatistics {
+	return &NetworkStatistics{connections: map[uint64]*trackedConnection{}, processes: map[uint64]*ProcessNetworkStatistics{}}
+}
+
+//Add updates the statistics with event
+func (s *NetworkStatistics) Add(event NetworkEvent) {
+	switch event.Type {
+	case NetworkConnectionDetection:
+		if _, ok := s.connections[event.Connection.Serial]; ok {
+			return
+		}
+		s.connections[event.Connection.Serial] = &trackedConnection{connection: *event.Connection, lastSeen: event.Timestamp}
+		s.process(event.Connection.Pid).OpenConnections++
+	case NetworkConnectionUpdate:
+		tracked, ok := s.connections[event.Update.Serial]
+		if !ok {
+			return
+		}
+		//counters of an update are totals since the connection was opened
+		stats := s.process(tracked.connection.Pid)
+		stats.RxPackets += event.Update.RxPackets - tracked.update.RxPackets
+		stats.RxBytes += event.Update.RxBytes - tracked.update.RxBytes
+		stats.TxPackets += event.Update.TxPackets - tracked.update.TxPackets
+		stats.TxBytes += event.Update.TxBytes - tracked.update.TxBytes
+		tracked.update = *event.Update
+		tracked.lastSeen = event.Timestamp
+	}
+}
+
+//ExpireConnections removes all connections that were not updated within idle before now and returns them
+func (s *NetworkStatistics) ExpireConnections(now time.Time, idle time.Duration) []NetworkConnection {
+	var expired []NetworkConnection
+	for serial, tracked := range s.connections {
+		if now.Sub(tracked.lastSeen) < idle {
+			continue
+		}
+		delete(s.connections, serial)
+		stats := s.process(tracked.connection.Pid)
+		stats.OpenConnections--
+		stats.ExpiredConnections++
+		expired = append(expired, tracked.connection)
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].Serial < expired[j].Serial })
+	return expired
+}
+
+//Processes returns the statistics of all processes that opened connections, sorted by pid
+func (s *NetworkStatistics) Processes() []ProcessNetworkStatistics {
+	result := make([]ProcessNetworkStatistics, 0, len(s.processes))
+	for _, stats := range s.processes {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Pid < result[j].Pid })
+	return result
+}
+
+func (s *NetworkStatistics) process(pid uint64) *ProcessNetworkStatistics {
+	stats, ok := s.processes[pid]
+	if !ok {
+		stats = &ProcessNetworkStatistics{Pid: pid}
+		s.processes[pid] = stats
+	}
+	return stats
+}
@@ -0,0 +1,72 @@
+package instruments_test
+
+import (
+	"testing"
+	"time"
+
+	dtx "github.com/danielpaulus/go-ios/ios/dtx_codec"
+	"github.com/danielpaulus/go-ios/ios/instruments"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func networkMessage(messageType uint64, values ...interface{}) dtx.Message {
+	return dtx.Message{Payload: []interface{}{[]interface{}{messageType, values}}}
+}
+
+func TestParseNetworkMessage(t *testing.T) {
+	event, err := instruments.ParseNetworkMessage(networkMessage(0, uint64(4), "en0"))
+	require.NoError(t, err)
+	assert.Equal(t, instruments.NetworkInterfaceDetection, event.Type)
+	assert.Equal(t, &instruments.NetworkInterface{Index: 4, Name: "en0"}, event.Interface)
+
+	local := []byte{16, 2, 0xc3, 0x50, 192, 168, 1, 2, 0, 0, 0, 0, 0, 0, 0, 0}
+	remote := make([]byte, 28)
+	remote[0], remote[1], remote[2], remote[3] = 28, 30, 0x01, 0xbb
+	remote[8], remote[9], remote[23] = 0x20, 0x01, 0x01
+	event, err = instruments.ParseNetworkMessage(networkMessage(1, local, remote, uint64(4), uint64(42), uint64(131072), uint64(0), uint64(7), uint64(1)))
+	require.NoError(t, err)
+	assert.Equal(t, instruments.NetworkConnectionDetection, event.Type)
+	assert.Equal(t, &instruments.NetworkConnection{
+		Serial:         7,
+		Pid:            42,
+		LocalAddress:   "192.168.1.2:50000",
+		RemoteAddress:  "[2001::1]:443",
+		InterfaceIndex: 4,
+		RecvBufferSize: 131072,
+		Kind:           1,
+	}, event.Connection)
+
+	event, err = instruments.ParseNetworkMessage(networkMessage(2, uint64(10), uint64(1000), uint64(5), uint64(500), uint64(0), uint64(0), uint64(1), uint64(20), uint64(30), uint64(7), uint64(99)))
+	require.NoError(t, err)
+	assert.Equal(t, instruments.NetworkConnectionUpdate, event.Type)
+	assert.Equal(t, &instruments.ConnectionUpdate{Serial: 7, RxPackets: 10, RxBytes: 1000, TxPackets: 5, TxBytes: 500, TxRetransmitted: 1, MinRTT: 20, AvgRTT: 30, Time: 99}, event.Update)
+
+	_, err = instruments.ParseNetworkMessage(networkMessage(5))
+	assert.Error(t, err)
+	_, err = instruments.ParseNetworkMessage(dtx.Message{Payload: []interface{}{"unexpected"}})
+	assert.Error(t, err)
+}
+
+func TestNetworkStatistics(t *testing.T) {
+	start := time.Unix(1634551872, 0)
+	stats := instruments.NewNetworkStatistics()
+	for serial, pid := range map[uint64]uint64{1: 42, 2: 42, 3: 7} {
+		stats.Add(instruments.NetworkEvent{Type: instruments.NetworkConnectionDetection, Timestamp: start, Connection: &instruments.NetworkConnection{Serial: serial, Pid: pid}})
+	}
+	//counters are totals per connection, so the second update of connection 1 only adds the difference
+	stats.Add(instruments.NetworkEvent{Type: instruments.NetworkConnectionUpdate, Timestamp: start, Update: &instruments.ConnectionUpdate{Serial: 1, RxBytes: 100, TxBytes: 10}})
+	stats.Add(instruments.NetworkEvent{Type: instruments.NetworkConnectionUpdate, Timestamp: start.Add(time.Minute), Update: &instruments.ConnectionUpdate{Serial: 1, RxBytes: 150, TxBytes: 20}})
+	stats.Add(instruments.NetworkEvent{Type: instruments.NetworkConnectionUpdate, Timestamp: start, Update: &instruments.ConnectionUpdate{Serial: 2, RxBytes: 50}})
+	stats.Add(instruments.NetworkEvent{Type: instruments.NetworkConnectionUpdate, Timestamp: start, Update: &instruments.ConnectionUpdate{Serial: 99, RxBytes: 50}})
+
+	expired := stats.ExpireConnections(start.Add(time.Minute), 30*time.Second)
+	require.Len(t, expired, 2)
+	assert.Equal(t, uint64(2), expired[0].Serial)
+	assert.Equal(t, uint64(3), expired[1].Serial)
+
+	assert.Equal(t, []instruments.ProcessNetworkStatistics{
+		{Pid: 7, ExpiredConnections: 1},
+		{Pid: 42, OpenConnections: 1, ExpiredConnections: 1, RxBytes: 200, TxBytes: 20},
+	}, stats.Processes())
+}
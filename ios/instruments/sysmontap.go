@@ -0,0 +1,270 @@
+package instruments
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios"
+	dtx "github.com/danielpaulus/go-ios/ios/dtx_codec"
+	log "github.com/sirupsen/logrus"
+)
+
+//SysmontapConfig configures the sampling of sysmontap. Empty attribute lists sample all attributes the device supports,
+//see DeviceInfoService.SysmonProcessAttributes and DeviceInfoService.SysmonSystemAttributes. Attributes the device
+//does not support are skipped.
+type SysmontapConfig struct {
+	Interval          time.Duration
+	ProcessAttributes []string
+	SystemAttributes  []string
+}
+
+//DefaultSysmontapConfig samples CPU, memory, disk and thread attributes of all processes every second
+func DefaultSysmontapConfig() SysmontapConfig {
+	return SysmontapConfig{
+		Interval:          time.Second,
+		ProcessAttributes: []string{"pid", "name", "cpuUsage", "physFootprint", "memResidentSize", "diskBytesRead", "diskBytesWritten", "threadCount"},
+	}
+}
+
+//SysmonSample is one batch of samples received from sysmontap
+type SysmonSample struct {
+	Timestamp      time.Time              `json:"timestamp"`
+	CPUCount       uint64                 `json:"cpuCount,omitempty"`
+	EnabledCPUs    uint64                 `json:"enabledCPUs,omitempty"`
+	SystemCPUUsage map[string]float64     `json:"systemCPUUsage,omitempty"`
+	System         map[string]interface{} `json:"system,omitempty"`
+	Processes      []ProcessSample        `json:"processes,omitempty"`
+}
+
+//ProcessSample contains the sampled attributes of a process. The most used attributes are available as fields,
+//all sampled attributes are in Attributes.
+type ProcessSample struct {
+	Pid              uint64                 `json:"pid"`
+	Name             string                 `json:"name"`
+	CPUUsage         float64                `json:"cpuUsage"`
+	PhysFootprint    uint64                 `json:"physFootprint"`
+	MemResidentSize  uint64                 `json:"memResidentSize"`
+	DiskBytesRead    uint64                 `json:"diskBytesRead"`
+	DiskBytesWritten uint64                 `json:"diskBytesWritten"`
+	ThreadCount      uint64                 `json:"threadCount"`
+	Attributes       map[string]interface{} `json:"attributes"`
+}
+
+//Sysmontap samples system and per process CPU and memory usage using the sysmontap instruments channel.
+type Sysmontap struct {
+	conn     *dtx.Connection
+	channel  *dtx.Channel
+	messages chan dtx.Message
+	done     chan struct{}
+	config   SysmontapConfig
+	pending  []SysmonSample
+}
+
+//NewSysmontap connects to instruments, configures sysmontap with config and starts sampling. Read the samples with ReadSample.
+func NewSysmontap(device ios.DeviceEntry, config SysmontapConfig) (*Sysmontap, error) {
+	conn, err := connectInstruments(device)
+	if err != nil {
+		return nil, err
+	}
+	deviceInfo := DeviceInfoService{channel: conn.RequestChannelIdentifier(deviceInfoServiceName, loggingDispatcher{conn}), conn: conn}
+	processAttributes, err := deviceInfo.SysmonProcessAttributes()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed getting sysmon process attributes: %w", err)
+	}
+	systemAttributes, err := deviceInfo.SysmonSystemAttributes()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed getting sysmon system attributes: %w", err)
+	}
+	//values are sent in the order of the requested attributes, so unsupported attributes must not be requested
+	config.ProcessAttributes = supportedAttributes(config.ProcessAttributes, processAttributes)
+	config.SystemAttributes = supportedAttributes(config.SystemAttributes, systemAttributes)
+	if config.Interval <= 0 {
+		config.Interval = time.Second
+	}
+
+	messages := make(chan dtx.Message, 100)
+	done := make(chan struct{})
+	channel := conn.RequestChannelIdentifier(sysmontapChannel, tapDispatcher{conn: conn, messages: messages, done: done})
+	_, err = channel.MethodCall("setConfig:", map[string]interface{}{
+		"ur":             uint64(config.Interval / time.Millisecond),
+		"bm":             uint64(0),
+		"cpuUsage":       true,
+		"physFootprint":  true,
+		"sampleInterval": uint64(config.Interval),
+		"procAttrs":      toInterfaceSlice(config.ProcessAttributes),
+		"sysAttrs":       toInterfaceSlice(config.SystemAttributes),
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed configuring sysmontap: %w", err)
+	}
+	_, err = channel.MethodCall("start")
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed starting sysmontap: %w", err)
+	}
+	return &Sysmontap{conn: conn, channel: channel, messages: messages, done: done, config: config}, nil
+}
+
+//Config returns the configuration sysmontap was started with, including the attributes that were selected automatically
+func (s *Sysmontap) Config() SysmontapConfig {
+	return s.config
+}
+
+//ReadSample blocks until the next sample was received and returns io.EOF once sysmontap was closed.
+func (s *Sysmontap) ReadSample() (SysmonSample, error) {
+	for {
+		if len(s.pending) > 0 {
+			sample := s.pending[0]
+			s.pending = s.pending[1:]
+			return sample, nil
+		}
+		select {
+		case msg := <-s.messages:
+			s.pending = ParseSysmontapMessage(msg, s.config)
+		case <-s.done:
+			return SysmonSample{}, io.EOF
+		case <-s.conn.Closed():
+			return SysmonSample{}, io.EOF
+		}
+	}
+}
+
+//Close stops sampling and closes the connection
+func (s *Sysmontap) Close() error {
+	close(s.done)
+	err := s.channel.MethodCallAsync("stop")
+	if err != nil {
+		log.Debugf("failed stopping sysmontap: %v", err)
+	}
+	return s.conn.Close()
+}
+
+//ParseSysmontapMessage decodes the samples contained in a sysmontap message. Process and system values are
+//arrays in the order of the attributes in config.
+func ParseSysmontapMessage(msg dtx.Message, config SysmontapConfig) []SysmonSample {
+	var rows []interface{}
+	for _, payload := range msg.Payload {
+		switch p := payload.(type) {
+		case []interface{}:
+			rows = append(rows, p...)
+		case map[string]interface{}:
+			rows = append(rows, p)
+		}
+	}
+	var samples []SysmonSample
+	for _, row := range rows {
+		values, ok := row.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		_, hasProcesses := values["Processes"]
+		_, hasSystem := values["System"]
+		if !hasProcesses && !hasSystem {
+			log.Tracef("skipping sysmontap row %+v", values)
+			continue
+		}
+		samples = append(samples, parseSysmonRow(values, config))
+	}
+	return samples
+}
+
+func parseSysmonRow(values map[string]interface{}, config SysmontapConfig) SysmonSample {
+	sample := SysmonSample{Timestamp: time.Now()}
+	sample.CPUCount, _ = toUint64(values["CPUCount"])
+	sample.EnabledCPUs, _ = toUint64(values["EnabledCPUs"])
+	if usage, ok := values["SystemCPUUsage"].(map[string]interface{}); ok {
+		sample.SystemCPUUsage = map[string]float64{}
+		for k, v := range usage {
+			sample.SystemCPUUsage[k] = toFloat64(v)
+		}
+	}
+	if system, ok := values["System"].([]interface{}); ok {
+		sample.System = zipAttributes(config.SystemAttributes, system)
+	}
+	if processes, ok := values["Processes"].(map[string]interface{}); ok {
+		for pid, v := range processes {
+			attributes, ok := v.([]interface{})
+			if !ok {
+				continue
+			}
+			process := newProcessSample(zipAttributes(config.ProcessAttributes, attributes))
+			if process.Pid == 0 {
+				process.Pid, _ = strconv.ParseUint(pid, 10, 64)
+			}
+			sample.Processes = append(sample.Processes, process)
+		}
+		sort.Slice(sample.Processes, func(i, j int) bool { return sample.Processes[i].Pid < sample.Processes[j].Pid })
+	}
+	return sample
+}
+
+func newProcessSample(attributes map[string]interface{}) ProcessSample {
+	process := ProcessSample{Attributes: attributes}
+	process.Pid, _ = toUint64(attributes["pid"])
+	process.Name, _ = attributes["name"].(string)
+	process.CPUUsage = toFloat64(attributes["cpuUsage"])
+	process.PhysFootprint, _ = toUint64(attributes["physFootprint"])
+	process.MemResidentSize, _ = toUint64(attributes["memResidentSize"])
+	process.DiskBytesRead, _ = toUint64(attributes["diskBytesRead"])
+	process.DiskBytesWritten, _ = toUint64(attributes["diskBytesWritten"])
+	process.ThreadCount, _ = toUint64(attributes["threadCount"])
+	return process
+}
+
+func supportedAttributes(requested []string, supported []string) []string {
+	if len(requested) == 0 {
+		return supported
+	}
+	isSupported := make(map[string]bool, len(supported))
+	for _, name := range supported {
+		isSupported[name] = true
+	}
+	var result []string
+	for _, name := range requested {
+		if !isSupported[name] {
+			log.Warnf("sysmon attribute %s is not supported by the device", name)
+			continue
+		}
+		result = append(result, name)
+	}
+	return result
+}
+
+func zipAttributes(names []string, values []interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(values))
+	for i, v := range values {
+		if i >= len(names) {
+			break
+		}
+		result[names[i]] = v
+	}
+	return result
+}
+
+func toFloat64(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return 0
+}
+
+func toInterfaceSlice(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
@@ -0,0 +1,46 @@
+package instruments_test
+
+import (
+	"testing"
+
+	dtx "github.com/danielpaulus/go-ios/ios/dtx_codec"
+	"github.com/danielpaulus/go-ios/ios/instruments"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSysmontapMessage(t *testing.T) {
+	config := instruments.SysmontapConfig{
+		ProcessAttributes: []string{"pid", "name", "cpuUsage", "physFootprint", "threadCount"},
+		SystemAttributes:  []string{"vmFreeCount", "threadCount"},
+	}
+	msg := dtx.Message{Payload: []interface{}{[]interface{}{
+		map[string]interface{}{"Type": uint64(7), "CPUCount": uint64(6)},
+		map[string]interface{}{
+			"CPUCount":       uint64(6),
+			"EnabledCPUs":    uint64(6),
+			"SystemCPUUsage": map[string]interface{}{"CPU_TotalLoad": 12.5, "CPU_UserLoad": 10.0},
+			"System":         []interface{}{uint64(1000), uint64(900)},
+			"Processes": map[string]interface{}{
+				"42": []interface{}{uint64(42), "Maps", 3.5, uint64(1024), uint64(12)},
+				"1":  []interface{}{uint64(1), "launchd", 0.1, uint64(512)},
+			},
+		},
+	}}}
+
+	samples := instruments.ParseSysmontapMessage(msg, config)
+	require.Len(t, samples, 1)
+	sample := samples[0]
+	assert.Equal(t, uint64(6), sample.CPUCount)
+	assert.Equal(t, map[string]float64{"CPU_TotalLoad": 12.5, "CPU_UserLoad": 10}, sample.SystemCPUUsage)
+	assert.Equal(t, map[string]interface{}{"vmFreeCount": uint64(1000), "threadCount": uint64(900)}, sample.System)
+	require.Len(t, sample.Processes, 2)
+	assert.Equal(t, "launchd", sample.Processes[0].Name)
+	maps := sample.Processes[1]
+	assert.Equal(t, uint64(42), maps.Pid)
+	assert.Equal(t, "Maps", maps.Name)
+	assert.Equal(t, 3.5, maps.CPUUsage)
+	assert.Equal(t, uint64(1024), maps.PhysFootprint)
+	assert.Equal(t, uint64(12), maps.ThreadCount)
+	assert.Equal(t, 3.5, maps.Attributes["cpuUsage"])
+}
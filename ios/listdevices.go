@@ -85,11 +85,11 @@ func NewReadDevices() ReadDevicesType {
 func (muxConn *UsbMuxConnection) ListDevices() (DeviceList, error) {
 	err := muxConn.Send(NewReadDevices())
 	if err != nil {
-		return DeviceList{}, fmt.Errorf("Failed sending to usbmux requesting devicelist: %v", err)
+		return DeviceList{}, fmt.Errorf("Failed sending to usbmux requesting devicelist: %w", err)
 	}
 	response, err := muxConn.ReadMessage()
 	if err != nil {
-		return DeviceList{}, fmt.Errorf("Failed getting devicelist: %v", err)
+		return DeviceList{}, fmt.Errorf("Failed getting devicelist: %w", err)
 	}
 	return DeviceListfromBytes(response.Payload), nil
 }
@@ -72,8 +72,8 @@ func (muxConn *UsbMuxConnection) Listen() (func() (AttachedMessage, error), erro
 	if err != nil {
 		return nil, err
 	}
-	if !MuxResponsefromBytes(response.Payload).IsSuccessFull() {
-		return nil, fmt.Errorf("Listen command to usbmuxd failed: %x", response.Payload)
+	if muxResponse := MuxResponsefromBytes(response.Payload); !muxResponse.IsSuccessFull() {
+		return nil, fmt.Errorf("Listen command to usbmuxd failed: %x %w", response.Payload, MuxError{"Listen", muxResponse.Number})
 	}
 
 	return func() (AttachedMessage, error) {
@@ -1,6 +1,7 @@
 package ios
 
 import (
+	"context"
 	"net"
 
 	log "github.com/sirupsen/logrus"
@@ -64,6 +65,17 @@ func (lockDownConn *LockDownConnection) ReadMessage() ([]byte, error) {
 	return resp, err
 }
 
+// ReadMessageContext is like ReadMessage but returns ctx.Err() when ctx ends before a message was received.
+func (lockDownConn *LockDownConnection) ReadMessageContext(ctx context.Context) ([]byte, error) {
+	var resp []byte
+	err := RunWithContext(ctx, lockDownConn.deviceConnection, func() error {
+		var err error
+		resp, err = lockDownConn.ReadMessage()
+		return err
+	})
+	return resp, err
+}
+
 func (lockDownConn *LockDownConnection) Conn() net.Conn {
 	return lockDownConn.deviceConnection.Conn()
 }
@@ -221,7 +221,7 @@ func (lockDownConn *LockDownConnection) SetValueForDomain(key string, domain str
 	resp, err := lockDownConn.ReadMessage()
 	response := getValueResponsefromBytes(resp)
 	if response.Error != "" {
-		return fmt.Errorf("Failed setting '%s' to '%s' with err: %s", key, value, response.Error)
+		return fmt.Errorf("Failed setting '%s' to '%v': %w", key, value, LockdownError{Request: "SetValue", Reason: response.Error})
 	}
 	return err
 }
@@ -2,7 +2,9 @@ package notificationproxy
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -13,13 +15,32 @@ import (
 
 const serviceName = "com.apple.mobile.notification_proxy"
 
+const springboardFinishedStartup = "com.apple.springboard.finishedstartup"
+
+//connectPollInterval is the time between attempts to connect to a device whose lockdownd is not ready yet
+const connectPollInterval = 250 * time.Millisecond
+
+//Connection to notification_proxy. Any number of Subscriptions can observe notifications on the same connection.
 type Connection struct {
-	deviceConn          ios.DeviceConnectionInterface
-	plistCodec          ios.PlistCodec
-	alreadyObserving    map[string]interface{}
-	notificationChannel chan string
-	proxyDeathChannel   chan interface{}
-	mux                 sync.Mutex
+	deviceConn       ios.DeviceConnectionInterface
+	plistCodec       ios.PlistCodec
+	alreadyObserving map[string]interface{}
+	subscriptions    map[*Subscription]struct{}
+	readerDone       chan struct{}
+	readerErr        error
+	mux              sync.Mutex
+	sendMux          sync.Mutex
+}
+
+//Subscription receives the notifications it was created for on Notifications until Unsubscribe is called or the
+//connection ends. Err returns why the connection ended after the channel was closed.
+type Subscription struct {
+	conn          *Connection
+	notifications map[string]bool
+	channel       chan string
+	done          chan struct{}
+	once          sync.Once
+	deliverMux    sync.Mutex
 }
 
 //Close sends a Shutdown command to notification proxy and closes the DeviceConnectionInterface
@@ -30,7 +51,7 @@ func (c *Connection) Close() {
 	if err != nil {
 		log.Debug(err)
 	}
-	err = c.deviceConn.Send(bytes)
+	err = c.send(bytes)
 	if err != nil {
 		log.Debug(err)
 	}
@@ -43,7 +64,7 @@ func New(device ios.DeviceEntry) (*Connection, error) {
 		return &Connection{}, err
 	}
 	c := &Connection{deviceConn: deviceConn, plistCodec: ios.NewPlistCodec(), alreadyObserving: make(map[string]interface{}),
-		notificationChannel: make(chan string), proxyDeathChannel: make(chan interface{}),
+		subscriptions: make(map[*Subscription]struct{}), readerDone: make(chan struct{}),
 	}
 	go read(c)
 	return c, nil
@@ -56,11 +77,38 @@ func WaitUntilSpringboardStarted(device ios.DeviceEntry) error {
 		return err
 	}
 	defer c.Close()
-	return c.Observe("com.apple.springboard.finishedstartup", time.Minute*5)
+	return c.Observe(springboardFinishedStartup, time.Minute*5)
+}
+
+//WaitUntilSpringboardStartedContext waits for springboard to restart until ctx ends. The notification is only sent
+//once, so call this before springboard finished starting, f.ex. right after the device was attached again.
+//Connecting is retried until lockdownd accepts it, so the notification is observed as early as possible.
+func WaitUntilSpringboardStartedContext(ctx context.Context, device ios.DeviceEntry) error {
+	for {
+		c, err := New(device)
+		if err == nil {
+			defer c.Close()
+			return c.ObserveContext(ctx, springboardFinishedStartup)
+		}
+		log.Debugf("notification proxy not ready yet: %v", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed connecting to %s: %v: %w", serviceName, err, ctx.Err())
+		case <-time.After(connectPollInterval):
+		}
+	}
 }
 
-func read(c *Connection) error {
+func read(c *Connection) {
 	log.Debug("notificationproxy start reading")
+	err := readNotifications(c)
+	c.mux.Lock()
+	c.readerErr = err
+	c.mux.Unlock()
+	close(c.readerDone)
+}
+
+func readNotifications(c *Connection) error {
 	reader := c.deviceConn.Reader()
 	for {
 		messageBytes, err := c.plistCodec.Decode(reader)
@@ -75,10 +123,10 @@ func read(c *Connection) error {
 		if command, ok := message["Command"].(string); ok {
 			switch command {
 			case "RelayNotification":
-				c.notificationChannel <- message["Name"].(string)
+				name, _ := message["Name"].(string)
+				c.dispatch(name)
 			case "ProxyDeath":
-				var signal interface{}
-				c.proxyDeathChannel <- signal
+				return errors.New("ProxyDeath")
 			default:
 				log.Debugf("Unknown message: %x", messageBytes)
 			}
@@ -88,6 +136,21 @@ func read(c *Connection) error {
 	}
 }
 
+//dispatch delivers notification to all subscriptions for it, it blocks until they received it or unsubscribed
+func (c *Connection) dispatch(notification string) {
+	c.mux.Lock()
+	var receivers []*Subscription
+	for subscription := range c.subscriptions {
+		if subscription.notifications[notification] {
+			receivers = append(receivers, subscription)
+		}
+	}
+	c.mux.Unlock()
+	for _, subscription := range receivers {
+		subscription.deliver(notification)
+	}
+}
+
 func plistFromBytes(plistBytes []byte) (map[string]interface{}, error) {
 	var message map[string]interface{}
 	decoder := plist.NewDecoder(bytes.NewReader(plistBytes))
@@ -96,27 +159,140 @@ func plistFromBytes(plistBytes []byte) (map[string]interface{}, error) {
 	return message, err
 }
 
-//Observe waits for a notification up to a timeout. Currently supports only one listener per notification
+//Observe waits for a notification up to a timeout
 func (c *Connection) Observe(notification string, timeout time.Duration) error {
-	if yes := c.newNotification(notification); yes {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := c.ObserveContext(ctx, notification)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errors.New("Timeout")
+	}
+	return err
+}
+
+//ObserveContext waits for a notification until ctx ends and returns ctx.Err() then
+func (c *Connection) ObserveContext(ctx context.Context, notification string) error {
+	subscription, err := c.Subscribe(notification)
+	if err != nil {
+		return err
+	}
+	defer subscription.Unsubscribe()
+	select {
+	case _, ok := <-subscription.Notifications():
+		if !ok {
+			return subscription.Err()
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+//Subscribe starts observing notifications and returns a Subscription that receives them.
+//Subscriptions are independent of each other, several of them can observe the same notification.
+func (c *Connection) Subscribe(notifications ...string) (*Subscription, error) {
+	subscription := &Subscription{
+		conn:          c,
+		notifications: make(map[string]bool, len(notifications)),
+		channel:       make(chan string, 10),
+		done:          make(chan struct{}),
+	}
+	for _, notification := range notifications {
+		subscription.notifications[notification] = true
+	}
+	c.mux.Lock()
+	c.subscriptions[subscription] = struct{}{}
+	c.mux.Unlock()
+	go func() {
+		select {
+		case <-c.readerDone:
+			subscription.Unsubscribe()
+		case <-subscription.done:
+		}
+	}()
+	for _, notification := range notifications {
+		if yes := c.newNotification(notification); !yes {
+			continue
+		}
 		err := c.startObserving(notification)
 		if err != nil {
-			return err
+			subscription.Unsubscribe()
+			return nil, err
 		}
 	}
-	for {
-		select {
-		case remoteNotification := <-c.notificationChannel:
-			if notification == remoteNotification {
-				return nil
-			}
-		case <-c.proxyDeathChannel:
-			return errors.New("ProxyDeath")
-		case <-time.After(timeout):
-			return errors.New("Timeout")
+	return subscription, nil
+}
+
+//SubscribeFunc calls handler for every received notification until the returned Subscription is unsubscribed
+//or the connection ends. Calls to handler do not overlap.
+func (c *Connection) SubscribeFunc(handler func(notification string), notifications ...string) (*Subscription, error) {
+	subscription, err := c.Subscribe(notifications...)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for notification := range subscription.Notifications() {
+			handler(notification)
 		}
+	}()
+	return subscription, nil
+}
+
+//PostNotification posts a Darwin notification on the device, like notify_post does
+func (c *Connection) PostNotification(notification string) error {
+	request := notificationProxyRequest{Command: "PostNotification", Name: notification}
+	bytes, err := c.plistCodec.Encode(request)
+	if err != nil {
+		return err
 	}
+	return c.send(bytes)
+}
 
+//Notifications returns the channel the notifications are delivered on, it is closed after Unsubscribe
+//or when the connection ends.
+func (s *Subscription) Notifications() <-chan string {
+	return s.channel
+}
+
+//Unsubscribe stops delivering notifications. The device keeps sending the observed notifications, because
+//notification_proxy has no request to stop observing them.
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.conn.mux.Lock()
+		delete(s.conn.subscriptions, s)
+		s.conn.mux.Unlock()
+		//closing done first unblocks a pending deliver, which holds deliverMux
+		close(s.done)
+		s.deliverMux.Lock()
+		defer s.deliverMux.Unlock()
+		close(s.channel)
+	})
+}
+
+func (s *Subscription) deliver(notification string) {
+	s.deliverMux.Lock()
+	defer s.deliverMux.Unlock()
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	select {
+	case s.channel <- notification:
+	case <-s.done:
+	}
+}
+
+//Err returns the error that ended the connection, f.ex. "ProxyDeath" or a read error after Close, or nil while it is open
+func (s *Subscription) Err() error {
+	select {
+	case <-s.conn.readerDone:
+	default:
+		return nil
+	}
+	s.conn.mux.Lock()
+	defer s.conn.mux.Unlock()
+	return s.conn.readerErr
 }
 
 func (c *Connection) startObserving(notification string) error {
@@ -125,7 +301,13 @@ func (c *Connection) startObserving(notification string) error {
 	if err != nil {
 		return err
 	}
-	return c.deviceConn.Send(bytes)
+	return c.send(bytes)
+}
+
+func (c *Connection) send(message []byte) error {
+	c.sendMux.Lock()
+	defer c.sendMux.Unlock()
+	return c.deviceConn.Send(message)
 }
 
 func (c *Connection) newNotification(notification string) bool {
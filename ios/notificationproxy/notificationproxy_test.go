@@ -0,0 +1,135 @@
+package notificationproxy_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/notificationproxy"
+	"github.com/danielpaulus/go-ios/ios/usbmuxsim"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"howett.net/plist"
+)
+
+//startNotificationProxy simulates a notification_proxy that relays posted notifications if they are observed
+//and answers Shutdown with ProxyDeath
+func startNotificationProxy(t *testing.T) ios.DeviceEntry {
+	_, lockdownd, entry := usbmuxsim.StartPairedDevice(t, "udid0")
+	lockdownd.HandleService("com.apple.mobile.notification_proxy", func(conn net.Conn) {
+		codec := ios.NewPlistCodec()
+		reader := bufio.NewReader(conn)
+		observed := map[string]bool{}
+		for {
+			request, err := codec.Decode(reader)
+			if err != nil {
+				return
+			}
+			var message map[string]interface{}
+			plist.NewDecoder(bytes.NewReader(request)).Decode(&message)
+			name, _ := message["Name"].(string)
+			switch message["Command"] {
+			case "ObserveNotification":
+				observed[name] = true
+			case "PostNotification":
+				if observed[name] {
+					b, _ := codec.Encode(map[string]interface{}{"Command": "RelayNotification", "Name": name})
+					conn.Write(b)
+				}
+			case "Shutdown":
+				b, _ := codec.Encode(map[string]interface{}{"Command": "ProxyDeath"})
+				conn.Write(b)
+				return
+			}
+		}
+	})
+	return entry
+}
+
+func receive(t *testing.T, subscription *notificationproxy.Subscription) string {
+	select {
+	case notification := <-subscription.Notifications():
+		return notification
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification received")
+		return ""
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	conn, err := notificationproxy.New(startNotificationProxy(t))
+	require.NoError(t, err)
+
+	first, err := conn.Subscribe("com.example.a", "com.example.b")
+	require.NoError(t, err)
+	second, err := conn.Subscribe("com.example.b")
+	require.NoError(t, err)
+	handled := make(chan string, 1)
+	_, err = conn.SubscribeFunc(func(notification string) { handled <- notification }, "com.example.a")
+	require.NoError(t, err)
+
+	require.NoError(t, conn.PostNotification("com.example.a"))
+	require.NoError(t, conn.PostNotification("com.example.b"))
+	assert.Equal(t, "com.example.a", receive(t, first))
+	assert.Equal(t, "com.example.b", receive(t, first))
+	assert.Equal(t, "com.example.b", receive(t, second))
+	assert.Equal(t, "com.example.a", <-handled)
+
+	second.Unsubscribe()
+	_, open := <-second.Notifications()
+	assert.False(t, open)
+	assert.NoError(t, second.Err())
+
+	require.NoError(t, conn.PostNotification("com.example.b"))
+	assert.Equal(t, "com.example.b", receive(t, first))
+
+	conn.Close()
+	_, open = <-first.Notifications()
+	assert.False(t, open)
+	assert.Error(t, first.Err())
+}
+
+func TestObserveTimeout(t *testing.T) {
+	conn, err := notificationproxy.New(startNotificationProxy(t))
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.EqualError(t, conn.Observe("com.example.a", 50*time.Millisecond), "Timeout")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		conn.PostNotification("com.example.a")
+	}()
+	assert.NoError(t, conn.Observe("com.example.a", 5*time.Second))
+}
+
+func TestWaitUntilSpringboardStartedRetriesConnecting(t *testing.T) {
+	_, lockdownd, entry := usbmuxsim.StartPairedDevice(t, "udid0")
+	//notification_proxy only becomes available after a while, like right after a reboot
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		lockdownd.HandleService("com.apple.mobile.notification_proxy", func(conn net.Conn) {
+			codec := ios.NewPlistCodec()
+			request, err := codec.Decode(bufio.NewReader(conn))
+			if err != nil {
+				return
+			}
+			var message map[string]interface{}
+			plist.NewDecoder(bytes.NewReader(request)).Decode(&message)
+			b, _ := codec.Encode(map[string]interface{}{"Command": "RelayNotification", "Name": message["Name"]})
+			conn.Write(b)
+			codec.Decode(bufio.NewReader(conn))
+		})
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, notificationproxy.WaitUntilSpringboardStartedContext(ctx, entry))
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, _, other := usbmuxsim.StartPairedDevice(t, "udid1")
+	assert.ErrorIs(t, notificationproxy.WaitUntilSpringboardStartedContext(ctx, other), context.DeadlineExceeded)
+}
@@ -3,14 +3,14 @@ package nskeyedarchiver
 import (
 	"fmt"
 
-	log "github.com/sirupsen/logrus"
 	plist "howett.net/plist"
 )
 
 //Unarchive extracts NSKeyedArchiver Plists, either in XML or Binary format, and returns an array of the archived objects converted to usable Go Types.
 // Primitives will be extracted just like regular Plist primitives (string, float64, int64, []uint8 etc.).
 // NSArray, NSMutableArray, NSSet and NSMutableSet will transformed into []interface{}
-// NSDictionary and NSMutableDictionary will be transformed into map[string] interface{}. I might add non string keys later.
+// NSDictionary and NSMutableDictionary will be transformed into map[string] interface{}. Non string keys like the pids
+// of sysmontap samples are converted to strings, f.ex. "42".
 func Unarchive(xml []byte) ([]interface{}, error) {
 	SetupDecoders()
 	plist, err := plistFromBytes(xml)
@@ -167,18 +167,12 @@ func extractDictionary(object map[string]interface{}, objects []interface{}) (ma
 	if mapSize == 0 {
 		return result, nil
 	}
-	if _, ok := keys[0].(string); !ok {
-		log.Warn("non string key dict found, lazy decoding by converting keys to strings :-), fix later")
-		for i := 0; i < mapSize; i++ {
-			key := keys[i].(uint64)
-			result[fmt.Sprintf("uint64{%d}", key)] = values[i]
-		}
-
-		return result, nil
-	}
-
 	for i := 0; i < mapSize; i++ {
-		result[keys[i].(string)] = values[i]
+		if key, ok := keys[i].(string); ok {
+			result[key] = values[i]
+			continue
+		}
+		result[fmt.Sprint(keys[i])] = values[i]
 	}
 
 	return result, nil
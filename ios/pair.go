@@ -191,10 +191,10 @@ func Pair(device DeviceEntry) error {
 	}
 	response := getLockdownPairResponsefromBytes(resp)
 	if isPairingDialogOpen(response) {
-		return fmt.Errorf("Please accept the PairingDialog on the device and run pairing again!")
+		return fmt.Errorf("Please accept the PairingDialog on the device and run pairing again! %w", LockdownError{Request: "Pair", Reason: response.Error})
 	}
 	if response.Error != "" {
-		return fmt.Errorf("Lockdown error: %s", response.Error)
+		return fmt.Errorf("Lockdown error: %w", LockdownError{Request: "Pair", Reason: response.Error})
 	}
 	usbmuxConn, err = NewUsbMuxConnectionSimple()
 	defer usbmuxConn.Close()
@@ -62,7 +62,7 @@ func pairRecordDatafromBytes(plistBytes []byte) (PairRecordData, error) {
 	}
 	if data.PairRecordData == nil {
 		resp := MuxResponsefromBytes(plistBytes)
-		return data, fmt.Errorf("ReadPair failed with errorcode '%d', is the device paired?", resp.Number)
+		return data, fmt.Errorf("ReadPair failed with errorcode '%d', is the device paired? %w", resp.Number, ErrPairingRequired)
 	}
 	return data, nil
 }
@@ -0,0 +1,292 @@
+package pcap
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Values of Packet.IO
+const (
+	IOInbound  uint8 = 0
+	IOOutbound uint8 = 1
+)
+
+// Expression is a compiled capture filter in a subset of the tcpdump/BPF syntax. Supported are
+// "host", "net", "port" and "portrange" with optional "src" or "dst" qualifiers, the protocols "ether", "ip", "ip6",
+// "arp", "tcp", "udp", "icmp" and "icmp6", the directions "inbound" and "outbound" as well as "and", "or", "not"
+// (also "&&", "||", "!") and parentheses. A protocol directly followed by another primitive like in
+// "tcp port 443" restricts that primitive to the protocol.
+// Instead of compiling to BPF byte code, expressions are evaluated in go on the decoded packets.
+type Expression struct {
+	source string
+	root   node
+}
+
+// node is a part of the syntax tree of an Expression
+type node func(p *decodedPacket) bool
+
+// decodedPacket holds the fields of a packet that can be used in expressions
+type decodedPacket struct {
+	packet   Packet
+	layers   map[gopacket.LayerType]bool
+	srcIP    net.IP
+	dstIP    net.IP
+	hasPorts bool
+	srcPort  int
+	dstPort  int
+}
+
+// decode extracts the fields of p. If a layer fails to decode, expressions only see the layers before it,
+// so a truncated TCP packet still matches "host" but not "port".
+func decode(p Packet) *decodedPacket {
+	d := &decodedPacket{packet: p, layers: map[gopacket.LayerType]bool{}}
+	packet := gopacket.NewPacket(p.Data, layers.LayerTypeEthernet, gopacket.DecodeOptions{Lazy: true, NoCopy: true})
+	for _, layer := range packet.Layers() {
+		d.layers[layer.LayerType()] = true
+		switch l := layer.(type) {
+		case *layers.IPv4:
+			d.srcIP, d.dstIP = l.SrcIP, l.DstIP
+		case *layers.IPv6:
+			d.srcIP, d.dstIP = l.SrcIP, l.DstIP
+		case *layers.TCP:
+			d.hasPorts, d.srcPort, d.dstPort = true, int(l.SrcPort), int(l.DstPort)
+		case *layers.UDP:
+			d.hasPorts, d.srcPort, d.dstPort = true, int(l.SrcPort), int(l.DstPort)
+		}
+	}
+	return d
+}
+
+// ParseExpression compiles a filter expression like "tcp port 443 and not host 10.0.0.1".
+func ParseExpression(expression string) (*Expression, error) {
+	p := &expressionParser{tokens: tokenize(expression)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty filter expression")
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter expression '%s': %w", expression, err)
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("invalid filter expression '%s': unexpected '%s'", expression, p.peek())
+	}
+	return &Expression{source: expression, root: root}, nil
+}
+
+// Matches returns true if the packet matches the expression
+func (e *Expression) Matches(p Packet) bool {
+	return e.root(decode(p))
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+func tokenize(expression string) []string {
+	for _, op := range []string{"(", ")", "&&", "||", "!"} {
+		expression = strings.ReplaceAll(expression, op, " "+op+" ")
+	}
+	return strings.Fields(expression)
+}
+
+type expressionParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *expressionParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *expressionParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *expressionParser) next() (string, error) {
+	if p.done() {
+		return "", fmt.Errorf("unexpected end of expression")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	return token, nil
+}
+
+func (p *expressionParser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "or" || p.peek() == "||" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(d *decodedPacket) bool { return l(d) || right(d) }
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "and" || p.peek() == "&&" {
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(d *decodedPacket) bool { return l(d) && right(d) }
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseNot() (node, error) {
+	if p.peek() == "not" || p.peek() == "!" {
+		p.pos++
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return func(d *decodedPacket) bool { return !inner(d) }, nil
+	}
+	if p.peek() == "(" {
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if token, err := p.next(); err != nil || token != ")" {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		return inner, nil
+	}
+	return p.parsePrimitive()
+}
+
+var protocolLayers = map[string]gopacket.LayerType{
+	"ether": layers.LayerTypeEthernet,
+	"ip":    layers.LayerTypeIPv4,
+	"ip6":   layers.LayerTypeIPv6,
+	"arp":   layers.LayerTypeARP,
+	"tcp":   layers.LayerTypeTCP,
+	"udp":   layers.LayerTypeUDP,
+	"icmp":  layers.LayerTypeICMPv4,
+	"icmp6": layers.LayerTypeICMPv6,
+}
+
+func (p *expressionParser) parsePrimitive() (node, error) {
+	token, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if layerType, ok := protocolLayers[token]; ok {
+		protocol := func(d *decodedPacket) bool { return d.layers[layerType] }
+		switch p.peek() {
+		case "src", "dst", "host", "net", "port", "portrange":
+			qualified, err := p.parsePrimitive()
+			if err != nil {
+				return nil, err
+			}
+			return func(d *decodedPacket) bool { return protocol(d) && qualified(d) }, nil
+		}
+		return protocol, nil
+	}
+	switch token {
+	case "inbound":
+		return func(d *decodedPacket) bool { return d.packet.IO == IOInbound }, nil
+	case "outbound":
+		return func(d *decodedPacket) bool { return d.packet.IO == IOOutbound }, nil
+	}
+
+	src, dst := true, true
+	switch token {
+	case "src":
+		dst = false
+		token, err = p.next()
+	case "dst":
+		src = false
+		token, err = p.next()
+	}
+	if err != nil {
+		return nil, err
+	}
+	value, err := p.next()
+	if err != nil {
+		return nil, fmt.Errorf("'%s' needs a value", token)
+	}
+	switch token {
+	case "host":
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid host '%s', only IP addresses are supported", value)
+		}
+		return ipNode(src, dst, ip.Equal), nil
+	case "net":
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid net '%s': %w", value, err)
+		}
+		return ipNode(src, dst, network.Contains), nil
+	case "port":
+		port, err := parsePort(value)
+		if err != nil {
+			return nil, err
+		}
+		return portNode(src, dst, port, port), nil
+	case "portrange":
+		bounds := strings.SplitN(value, "-", 2)
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("invalid portrange '%s', use f.ex. 8000-8080", value)
+		}
+		from, err := parsePort(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		to, err := parsePort(bounds[1])
+		if err != nil {
+			return nil, err
+		}
+		return portNode(src, dst, from, to), nil
+	}
+	return nil, fmt.Errorf("unknown primitive '%s'", token)
+}
+
+func parsePort(value string) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid port '%s'", value)
+	}
+	return port, nil
+}
+
+func ipNode(src bool, dst bool, match func(net.IP) bool) node {
+	return func(d *decodedPacket) bool {
+		if d.srcIP == nil {
+			return false
+		}
+		return (src && match(d.srcIP)) || (dst && match(d.dstIP))
+	}
+}
+
+func portNode(src bool, dst bool, from int, to int) node {
+	inRange := func(port int) bool { return port >= from && port <= to }
+	return func(d *decodedPacket) bool {
+		if !d.hasPorts {
+			return false
+		}
+		return (src && inRange(d.srcPort)) || (dst && inRange(d.dstPort))
+	}
+}
@@ -16,31 +16,34 @@ import (
 )
 
 var (
-	// IOSPacketHeader default is -1
-	Pid              = int32(-2)
-	ProcName         string
+	// Pid and ProcName filter the packets written by Start, default for Pid is -2 which means no filtering.
+	// Deprecated: use NewSession with a Filter instead
+	Pid      = int32(-2)
+	ProcName string
+	// PacketHeaderSize is the size of the IOSPacketHeader of iOS versions before 15
 	PacketHeaderSize = uint32(95)
 )
 
 // IOSPacketHeader :)
 // ref: https://github.com/gofmt/iOSSniffer/blob/master/pkg/sniffer/sniffer.go#L44
 type IOSPacketHeader struct {
-	HdrSize        uint32  `struc:"uint32,big"`
-	Version        uint8   `struc:"uint8,big"`
-	PacketSize     uint32  `struc:"uint32,big"`
-	Type           uint8   `struc:"uint8,big"`
-	Unit           uint16  `struc:"uint16,big"`
-	IO             uint8   `struc:"uint8,big"`
-	ProtocolFamily uint32  `struc:"uint32,big"`
-	FramePreLength uint32  `struc:"uint32,big"`
-	FramePstLength uint32  `struc:"uint32,big"`
-	IFName         string  `struc:"[16]byte"`
-	Pid            int32   `struc:"int32,little"`
-	ProcName       string  `struc:"[17]byte"`
-	Unknown        uint32  `struc:"uint32,little"`
-	Pid2           int32   `struc:"int32,little"`
-	ProcName2      string  `struc:"[17]byte"`
-	Unknown2       [8]byte `struc:"[8]byte"`
+	HdrSize        uint32 `struc:"uint32,big"`
+	Version        uint8  `struc:"uint8,big"`
+	PacketSize     uint32 `struc:"uint32,big"`
+	Type           uint8  `struc:"uint8,big"`
+	Unit           uint16 `struc:"uint16,big"`
+	IO             uint8  `struc:"uint8,big"`
+	ProtocolFamily uint32 `struc:"uint32,big"`
+	FramePreLength uint32 `struc:"uint32,big"`
+	FramePstLength uint32 `struc:"uint32,big"`
+	IFName         string `struc:"[16]byte"`
+	Pid            int32  `struc:"int32,little"`
+	ProcName       string `struc:"[17]byte"`
+	Unknown        uint32 `struc:"uint32,little"`
+	Pid2           int32  `struc:"int32,little"`
+	ProcName2      string `struc:"[17]byte"`
+	Seconds        uint32 `struc:"uint32,big"`
+	Microseconds   uint32 `struc:"uint32,big"`
 }
 
 func (iph *IOSPacketHeader) ToString() string {
@@ -53,44 +56,163 @@ func (iph *IOSPacketHeader) ToString() string {
 	return fmt.Sprintf("%v", *iph)
 }
 
-func Start(device ios.DeviceEntry) error {
-	intf, err := ios.ConnectToService(device, "com.apple.pcapd")
+// Packet is a single captured packet together with the metadata pcapd sends for it.
+type Packet struct {
+	Timestamp time.Time
+	Interface string
+	// IO is the direction of the packet as sent by pcapd
+	IO          uint8
+	Pid         int32
+	ProcessName string
+	// EffectivePid and EffectiveProcessName identify the process the traffic was sent on behalf of
+	EffectivePid         int32
+	EffectiveProcessName string
+	// Data contains the packet starting with the ethernet header
+	Data []byte
+}
+
+// Comment returns the process metadata of the packet in a human readable form
+func (p Packet) Comment() string {
+	return fmt.Sprintf("interface=%s io=%d pid=%d process=%s epid=%d eprocess=%s", p.Interface, p.IO, p.Pid, p.ProcessName, p.EffectivePid, p.EffectiveProcessName)
+}
+
+// Filter selects captured packets, empty fields match every packet.
+type Filter struct {
+	Pid      int32
+	ProcName string
+	// Expression is an optional capture filter like "tcp port 443", see ParseExpression
+	Expression *Expression
+}
+
+// Matches returns true if the packet was sent or received by the process selected by the filter
+func (f Filter) Matches(p Packet) bool {
+	if f.Pid > 0 && p.Pid != f.Pid && p.EffectivePid != f.Pid {
+		return false
+	}
+	if f.ProcName != "" && !strings.HasPrefix(p.ProcessName, f.ProcName) && !strings.HasPrefix(p.EffectiveProcessName, f.ProcName) {
+		return false
+	}
+	if f.Expression != nil && !f.Expression.Matches(p) {
+		return false
+	}
+	return true
+}
+
+// Session is a running packet capture. Packets are delivered on the Packets channel, which is closed
+// when the capture stops. Err returns the reason afterwards.
+type Session struct {
+	conn    ios.DeviceConnectionInterface
+	filter  Filter
+	packets chan Packet
+	err     error
+	closed  chan struct{}
+}
+
+// NewSession starts capturing the network traffic of the device and delivers the packets matching filter.
+func NewSession(device ios.DeviceEntry, filter Filter) (*Session, error) {
+	conn, err := ios.ConnectToService(device, "com.apple.pcapd")
 	if err != nil {
-		return err
+		return nil, err
+	}
+	s := &Session{conn: conn, filter: filter, packets: make(chan Packet, 100), closed: make(chan struct{})}
+	go s.read()
+	return s, nil
+}
+
+// Packets returns the channel the captured packets are sent to
+func (s *Session) Packets() <-chan Packet {
+	return s.packets
+}
+
+// Err returns the error that stopped the capture, it is nil if the Session was closed with Close.
+// Only call it after the Packets channel was closed.
+func (s *Session) Err() error {
+	return s.err
+}
+
+// Close stops the capture
+func (s *Session) Close() error {
+	select {
+	case <-s.closed:
+		return nil
+	default:
 	}
+	close(s.closed)
+	return s.conn.Close()
+}
+
+func (s *Session) read() {
+	defer close(s.packets)
 	plistCodec := ios.NewPlistCodec()
+	for {
+		packet, err := readPacket(plistCodec, s.conn.Reader())
+		if err != nil {
+			select {
+			case <-s.closed:
+			default:
+				s.err = err
+			}
+			return
+		}
+		if !s.filter.Matches(packet) {
+			continue
+		}
+		select {
+		case s.packets <- packet:
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+func readPacket(plistCodec ios.PlistCodec, r io.Reader) (Packet, error) {
+	b, err := plistCodec.Decode(r)
+	if err != nil {
+		return Packet{}, err
+	}
+	decodedBytes, err := fromBytes(b)
+	if err != nil {
+		return Packet{}, err
+	}
+	return parsePacket(decodedBytes)
+}
+
+// Start captures packets and writes them to a dump-<timestamp>.pcap file in the current directory.
+// The packets can be filtered using the Pid and ProcName variables.
+func Start(device ios.DeviceEntry) error {
 	fname := fmt.Sprintf("dump-%d.pcap", time.Now().Unix())
 	if Pid > 0 {
 		fname = fmt.Sprintf("dump-%d-%d.pcap", Pid, time.Now().Unix())
 	} else if ProcName != "" {
 		fname = fmt.Sprintf("dump-%s-%d.pcap", ProcName, time.Now().Unix())
 	}
-	f, err := createPcap(fname)
+	f, err := os.OpenFile(fname, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0755)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 	log.Info("Create pcap file: ", fname)
-	for {
-		b, err := plistCodec.Decode(intf.Reader())
-		if err != nil {
-			return err
-		}
-		decodedBytes, err := fromBytes(b)
-		if err != nil {
-			return err
-		}
-		packet, err := getPacket(decodedBytes)
+	writer, err := NewPcapWriter(f)
+	if err != nil {
+		return err
+	}
+	return Capture(device, Filter{Pid: Pid, ProcName: ProcName}, writer)
+}
+
+// Capture writes all packets matching filter to writer until the connection to the device breaks.
+func Capture(device ios.DeviceEntry, filter Filter, writer PacketWriter) error {
+	session, err := NewSession(device, filter)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	for packet := range session.Packets() {
+		err = writer.WritePacket(packet)
 		if err != nil {
 			return err
 		}
-		if len(packet) > 0 {
-			err = writePacket(f, packet)
-			if err != nil {
-				return err
-			}
-		}
 	}
+	return session.Err()
 }
 
 func fromBytes(data []byte) ([]byte, error) {
@@ -99,97 +221,63 @@ func fromBytes(data []byte) ([]byte, error) {
 	return result, err
 }
 
-// struct pcap_hdr_s {
-//         guint32 magic_number;   /* magic number */
-//         guint16 version_major;  /* major version number */
-//         guint16 version_minor;  /* minor version number */
-//         gint32  thiszone;       /* GMT to local correction */
-//         guint32 sigfigs;        /* accuracy of timestamps */
-//         guint32 snaplen;        /* max length of captured packets, in octets */
-//         guint32 network;        /* data link type */
-// } pcap_hdr_t;
-
-// typedef struct pcaprec_hdr_s {
-//         guint32 ts_sec;         /* timestamp seconds */
-//         guint32 ts_usec;        /* timestamp microseconds */
-//         guint32 incl_len;       /* number of octets of packet saved in file */
-//         guint32 orig_len;       /* actual length of packet */
-// } pcaprec_hdr_t;
-
-// ref: https://www.wireshark.org/~martinm/mac_pcap_sample_code.c
-type PcaprecHdrS struct {
-	TsSec   int `struc:"uint32,little"` /* timestamp seconds */
-	TsUsec  int `struc:"uint32,little"` /* timestamp microseconds */
-	InclLen int `struc:"uint32,little"` /* number of octets of packet saved in file */
-	OrigLen int `struc:"uint32,little"` /* actual length of packet */
-}
-
-func createPcap(name string) (*os.File, error) {
-	f, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0755)
+// getPacket returns the packet data if it matches the Pid and ProcName variables
+func getPacket(buf []byte) ([]byte, error) {
+	packet, err := parsePacket(buf)
 	if err != nil {
-		return nil, err
+		return []byte{}, err
 	}
-	// Write `pcap_hdr_s` with little endin to file.
-	f.Write([]byte{
-		0xd4, 0xc3, 0xb2, 0xa1, 0x02, 0x00, 0x04, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0xff, 0xff, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00})
-	return f, nil
-}
-
-func writePacket(f *os.File, packet []byte) error {
-	now := time.Now()
-	phs := &PcaprecHdrS{
-		int(now.Unix()),
-		int(now.UnixNano()/1e3 - now.Unix()*1e6),
-		len(packet),
-		len(packet),
-	}
-	var buf bytes.Buffer
-	err := struc.Pack(&buf, phs)
-	if err != nil {
-		return err
+	if !(Filter{Pid: Pid, ProcName: ProcName}).Matches(packet) {
+		return []byte{}, nil
 	}
-	f.Write(buf.Bytes())
-	f.Write(packet)
-	return nil
+	return packet.Data, nil
 }
 
-func getPacket(buf []byte) ([]byte, error) {
+func parsePacket(buf []byte) (Packet, error) {
 	iph := IOSPacketHeader{}
 	preader := bytes.NewReader(buf)
-	struc.Unpack(preader, &iph)
+	err := struc.Unpack(preader, &iph)
+	if err != nil {
+		return Packet{}, fmt.Errorf("failed decoding packet header: %w", err)
+	}
 
 	// support ios 15 beta4
 	if iph.HdrSize > PacketHeaderSize {
 		buf := make([]byte, iph.HdrSize-PacketHeaderSize)
 		_, err := io.ReadFull(preader, buf)
 		if err != nil {
-			return []byte{}, err
+			return Packet{}, err
 		}
 	}
 
-	// Only return specific packet
-	if Pid > 0 {
-		if iph.Pid != Pid && iph.Pid2 != Pid {
-			return []byte{}, nil
-		}
-	}
-
-	if ProcName != "" {
-		if !strings.HasPrefix(iph.ProcName, ProcName) && !strings.HasPrefix(iph.ProcName2, ProcName) {
-			return []byte{}, nil
-		}
-	}
-
-	//log.Info("IOSPacketHeader: ", iph.ToString())
-	packet, err := ioutil.ReadAll(preader)
+	data, err := ioutil.ReadAll(preader)
 	if err != nil {
-		return packet, err
+		return Packet{}, err
 	}
 	if iph.FramePreLength == 0 {
 		ext := []byte{0xbe, 0xfe, 0xbe, 0xfe, 0xbe, 0xfe, 0xbe, 0xfe, 0xbe, 0xfe, 0xbe, 0xfe, 0x08, 0x00}
-		return append(ext, packet...), nil
+		data = append(ext, data...)
+	}
+	timestamp := time.Now()
+	if iph.Seconds != 0 {
+		timestamp = time.Unix(int64(iph.Seconds), int64(iph.Microseconds)*int64(time.Microsecond))
+	}
+	return Packet{
+		Timestamp:            timestamp,
+		Interface:            cString(iph.IFName),
+		IO:                   iph.IO,
+		Pid:                  iph.Pid,
+		ProcessName:          cString(iph.ProcName),
+		EffectivePid:         iph.Pid2,
+		EffectiveProcessName: cString(iph.ProcName2),
+		Data:                 data,
+	}, nil
+}
+
+// cString returns s up to the first NUL byte
+func cString(s string) string {
+	if i := strings.IndexByte(s, 0); i >= 0 {
+		return s[:i]
 	}
-	return packet, nil
+	return s
 }
@@ -0,0 +1,207 @@
+package pcap_test
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/pcap"
+	"github.com/danielpaulus/go-ios/ios/usbmuxsim"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/lunixbochs/struc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func devicePacket(t *testing.T, pid int32, procName string, ifName string, data []byte) []byte {
+	header := pcap.IOSPacketHeader{
+		HdrSize:        pcap.PacketHeaderSize,
+		Version:        2,
+		PacketSize:     uint32(len(data)),
+		IFName:         ifName,
+		Pid:            pid,
+		ProcName:       procName,
+		Pid2:           pid,
+		ProcName2:      procName,
+		FramePreLength: 14,
+		Seconds:        1634551872,
+		Microseconds:   500,
+	}
+	var buf bytes.Buffer
+	require.NoError(t, struc.Pack(&buf, &header))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func startPcapd(t *testing.T, packets ...[]byte) ios.DeviceEntry {
+	_, lockdownd, entry := usbmuxsim.StartPairedDevice(t, "udid0")
+	lockdownd.HandleService("com.apple.pcapd", func(conn net.Conn) {
+		codec := ios.NewPlistCodec()
+		for _, p := range packets {
+			b, _ := codec.Encode(p)
+			conn.Write(b)
+		}
+	})
+	return entry
+}
+
+func TestSession(t *testing.T) {
+	frame := bytes.Repeat([]byte{0xab}, 60)
+	device := startPcapd(t,
+		devicePacket(t, 1, "kernel_task", "en0", frame),
+		devicePacket(t, 42, "MobileSafari", "pdp_ip0", frame),
+	)
+
+	session, err := pcap.NewSession(device, pcap.Filter{ProcName: "Mobile"})
+	require.NoError(t, err)
+	defer session.Close()
+	packet := <-session.Packets()
+	assert.Equal(t, pcap.Packet{
+		Timestamp:            time.Unix(1634551872, 500000),
+		Interface:            "pdp_ip0",
+		Pid:                  42,
+		ProcessName:          "MobileSafari",
+		EffectivePid:         42,
+		EffectiveProcessName: "MobileSafari",
+		Data:                 frame,
+	}, packet)
+
+	var out bytes.Buffer
+	writer, err := pcap.NewPcapngWriter(&out)
+	require.NoError(t, err)
+	require.NoError(t, writer.WritePacket(packet))
+	require.NoError(t, writer.WritePacket(packet))
+
+	reader, err := pcapgo.NewNgReader(bytes.NewReader(out.Bytes()), pcapgo.DefaultNgReaderOptions)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		data, ci, err := reader.ReadPacketData()
+		require.NoError(t, err)
+		assert.Equal(t, frame, data)
+		assert.True(t, packet.Timestamp.Equal(ci.Timestamp))
+	}
+	assert.Equal(t, 1, reader.NInterfaces())
+	intf, err := reader.Interface(0)
+	require.NoError(t, err)
+	assert.Equal(t, "pdp_ip0", intf.Name)
+	assert.Contains(t, out.String(), "pid=42 process=MobileSafari")
+
+	//the simulated pcapd closes the connection after sending all packets
+	_, ok := <-session.Packets()
+	assert.False(t, ok)
+	assert.Error(t, session.Err())
+}
+
+func TestPcapWriter(t *testing.T) {
+	var out bytes.Buffer
+	writer, err := pcap.NewPcapWriter(&out)
+	require.NoError(t, err)
+	timestamp := time.Unix(1634551872, 1000)
+	require.NoError(t, writer.WritePacket(pcap.Packet{Timestamp: timestamp, Data: []byte{1, 2, 3}}))
+
+	reader, err := pcapgo.NewReader(bytes.NewReader(out.Bytes()))
+	require.NoError(t, err)
+	data, ci, err := reader.ReadPacketData()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, data)
+	assert.True(t, timestamp.Equal(ci.Timestamp))
+}
+
+func ipPacket(t *testing.T, io uint8, src string, dst string, transport gopacket.SerializableLayer) pcap.Packet {
+	eth := &layers.Ethernet{SrcMAC: net.HardwareAddr{0, 0, 0, 0, 0, 1}, DstMAC: net.HardwareAddr{0, 0, 0, 0, 0, 2}, EthernetType: layers.EthernetTypeIPv4}
+	ip := &layers.IPv4{Version: 4, TTL: 64, SrcIP: net.ParseIP(src).To4(), DstIP: net.ParseIP(dst).To4()}
+	switch l := transport.(type) {
+	case *layers.TCP:
+		ip.Protocol = layers.IPProtocolTCP
+		require.NoError(t, l.SetNetworkLayerForChecksum(ip))
+	case *layers.UDP:
+		ip.Protocol = layers.IPProtocolUDP
+		require.NoError(t, l.SetNetworkLayerForChecksum(ip))
+	}
+	buf := gopacket.NewSerializeBuffer()
+	err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}, eth, ip, transport)
+	require.NoError(t, err)
+	return pcap.Packet{IO: io, Data: buf.Bytes()}
+}
+
+func TestExpression(t *testing.T) {
+	https := ipPacket(t, pcap.IOOutbound, "10.0.0.2", "17.253.1.1", &layers.TCP{SrcPort: 50000, DstPort: 443})
+	dns := ipPacket(t, pcap.IOInbound, "10.0.0.1", "10.0.0.2", &layers.UDP{SrcPort: 53, DstPort: 50001})
+
+	cases := []struct {
+		expression string
+		https      bool
+		dns        bool
+	}{
+		{"tcp port 443", true, false},
+		{"udp port 443", false, false},
+		{"port 53", false, true},
+		{"src port 53", false, true},
+		{"dst port 53", false, false},
+		{"portrange 50000-50001", true, true},
+		{"host 10.0.0.1", false, true},
+		{"dst host 10.0.0.2", false, true},
+		{"net 17.0.0.0/8", true, false},
+		{"ip and not ip6", true, true},
+		{"outbound", true, false},
+		{"inbound && udp", false, true},
+		{"not (tcp or icmp)", false, true},
+		{"!tcp || port 443", true, true},
+		{"tcp and (port 80 or port 443) and outbound", true, false},
+	}
+	for _, c := range cases {
+		expression, err := pcap.ParseExpression(c.expression)
+		require.NoError(t, err, c.expression)
+		assert.Equal(t, c.https, expression.Matches(https), c.expression)
+		assert.Equal(t, c.dns, expression.Matches(dns), c.expression)
+	}
+
+	for _, invalid := range []string{"", "tcp port", "host example.com", "port 70000", "(tcp", "tcp)", "portrange 1", "foo"} {
+		_, err := pcap.ParseExpression(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	expression, err := pcap.ParseExpression("port 443")
+	require.NoError(t, err)
+	https.Pid, https.ProcessName = 42, "MobileSafari"
+	assert.True(t, pcap.Filter{ProcName: "Mobile", Expression: expression}.Matches(https))
+	assert.False(t, pcap.Filter{Pid: 1, Expression: expression}.Matches(https))
+	assert.False(t, pcap.Filter{ProcName: "Mobile", Expression: expression}.Matches(dns))
+}
+
+func TestRotatingWriter(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Unix(1634551872, 0)
+	data := bytes.Repeat([]byte{1}, 100)
+
+	writer, err := pcap.NewRotatingWriter(filepath.Join(dir, "dump.pcap"), false, 250, time.Minute)
+	require.NoError(t, err)
+	//two packets fill the first file, the third starts a new one and the fourth is more than a minute later
+	for _, offset := range []time.Duration{0, time.Second, 2 * time.Second, 2 * time.Minute} {
+		require.NoError(t, writer.WritePacket(pcap.Packet{Timestamp: start.Add(offset), Data: data}))
+	}
+	require.NoError(t, writer.Close())
+
+	for file, count := range map[string]int{"dump-000.pcap": 2, "dump-001.pcap": 1, "dump-002.pcap": 1} {
+		f, err := os.Open(filepath.Join(dir, file))
+		require.NoError(t, err)
+		reader, err := pcapgo.NewReader(f)
+		require.NoError(t, err)
+		packets := 0
+		for {
+			_, _, err := reader.ReadPacketData()
+			if err != nil {
+				break
+			}
+			packets++
+		}
+		f.Close()
+		assert.Equal(t, count, packets, file)
+	}
+}
@@ -0,0 +1,267 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lunixbochs/struc"
+)
+
+// PacketWriter writes captured packets in a capture file format
+type PacketWriter interface {
+	WritePacket(p Packet) error
+}
+
+// struct pcap_hdr_s {
+//         guint32 magic_number;   /* magic number */
+//         guint16 version_major;  /* major version number */
+//         guint16 version_minor;  /* minor version number */
+//         gint32  thiszone;       /* GMT to local correction */
+//         guint32 sigfigs;        /* accuracy of timestamps */
+//         guint32 snaplen;        /* max length of captured packets, in octets */
+//         guint32 network;        /* data link type */
+// } pcap_hdr_t;
+
+// typedef struct pcaprec_hdr_s {
+//         guint32 ts_sec;         /* timestamp seconds */
+//         guint32 ts_usec;        /* timestamp microseconds */
+//         guint32 incl_len;       /* number of octets of packet saved in file */
+//         guint32 orig_len;       /* actual length of packet */
+// } pcaprec_hdr_t;
+
+// ref: https://www.wireshark.org/~martinm/mac_pcap_sample_code.c
+type PcaprecHdrS struct {
+	TsSec   int `struc:"uint32,little"` /* timestamp seconds */
+	TsUsec  int `struc:"uint32,little"` /* timestamp microseconds */
+	InclLen int `struc:"uint32,little"` /* number of octets of packet saved in file */
+	OrigLen int `struc:"uint32,little"` /* actual length of packet */
+}
+
+// PcapWriter writes packets in the classic libpcap format, which drops all process metadata.
+type PcapWriter struct {
+	w io.Writer
+}
+
+// NewPcapWriter writes the pcap file header to w and returns a PcapWriter for it
+func NewPcapWriter(w io.Writer) (*PcapWriter, error) {
+	// Write `pcap_hdr_s` with little endian, link type ethernet
+	_, err := w.Write([]byte{
+		0xd4, 0xc3, 0xb2, 0xa1, 0x02, 0x00, 0x04, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0xff, 0xff, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00})
+	if err != nil {
+		return nil, err
+	}
+	return &PcapWriter{w: w}, nil
+}
+
+// WritePacket writes a record header and the packet data in one write, so readers on pipes never see partial packets
+func (p *PcapWriter) WritePacket(packet Packet) error {
+	phs := &PcaprecHdrS{
+		int(packet.Timestamp.Unix()),
+		packet.Timestamp.Nanosecond() / 1e3,
+		len(packet.Data),
+		len(packet.Data),
+	}
+	var buf bytes.Buffer
+	err := struc.Pack(&buf, phs)
+	if err != nil {
+		return err
+	}
+	buf.Write(packet.Data)
+	_, err = p.w.Write(buf.Bytes())
+	return err
+}
+
+// RotatingWriter writes packets to a series of capture files and starts a new file once the current one
+// reached MaxSize bytes or covers more than MaxDuration, measured with the packet timestamps.
+// Files are named after the path with a running index before the extension, f.ex. dump-000.pcap, dump-001.pcap.
+type RotatingWriter struct {
+	path        string
+	pcapng      bool
+	maxSize     int64
+	maxDuration time.Duration
+	index       int
+	file        *os.File
+	counter     *countingWriter
+	writer      PacketWriter
+	start       time.Time
+}
+
+// NewRotatingWriter creates a RotatingWriter writing pcapng if pcapng is true and pcap otherwise.
+// A maxSize or maxDuration of zero disables rotation by size or duration.
+func NewRotatingWriter(path string, pcapng bool, maxSize int64, maxDuration time.Duration) (*RotatingWriter, error) {
+	r := &RotatingWriter{path: path, pcapng: pcapng, maxSize: maxSize, maxDuration: maxDuration}
+	err := r.rotate()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// FileName returns the name of the file the next packet is written to
+func (r *RotatingWriter) FileName() string {
+	return r.file.Name()
+}
+
+// WritePacket writes packet to the current file, after starting a new file if a limit was reached
+func (r *RotatingWriter) WritePacket(packet Packet) error {
+	if r.start.IsZero() {
+		r.start = packet.Timestamp
+	}
+	full := r.maxSize > 0 && r.counter.n >= r.maxSize
+	expired := r.maxDuration > 0 && packet.Timestamp.Sub(r.start) >= r.maxDuration
+	if full || expired {
+		err := r.rotate()
+		if err != nil {
+			return err
+		}
+		r.start = packet.Timestamp
+	}
+	return r.writer.WritePacket(packet)
+}
+
+// Close closes the current file
+func (r *RotatingWriter) Close() error {
+	return r.file.Close()
+}
+
+func (r *RotatingWriter) rotate() error {
+	if r.file != nil {
+		err := r.file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	ext := filepath.Ext(r.path)
+	name := fmt.Sprintf("%s-%03d%s", strings.TrimSuffix(r.path, ext), r.index, ext)
+	r.index++
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	r.file = f
+	r.counter = &countingWriter{w: f}
+	if r.pcapng {
+		r.writer, err = NewPcapngWriter(r.counter)
+	} else {
+		r.writer, err = NewPcapWriter(r.counter)
+	}
+	return err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// pcapng block types and option codes, see https://datatracker.ietf.org/doc/draft-ietf-opsawg-pcapng/
+const (
+	pcapngSectionHeaderBlock   uint32 = 0x0A0D0D0A
+	pcapngInterfaceBlock       uint32 = 0x00000001
+	pcapngEnhancedPacketBlock  uint32 = 0x00000006
+	pcapngByteOrderMagic       uint32 = 0x1A2B3C4D
+	pcapngOptionEnd            uint16 = 0
+	pcapngOptionComment        uint16 = 1
+	pcapngOptionInterfaceName  uint16 = 2
+	pcapngLinkTypeEthernet     uint16 = 1
+	pcapngSnapLen              uint32 = 0xFFFF
+	pcapngMaxInterfaceNameSize        = 16
+)
+
+// PcapngWriter writes packets in the pcapng format. Every device interface gets its own interface block
+// with the interface name and the process metadata of each packet is stored as packet comment.
+type PcapngWriter struct {
+	w          io.Writer
+	interfaces map[string]uint32
+}
+
+// NewPcapngWriter writes the pcapng section header to w and returns a PcapngWriter for it
+func NewPcapngWriter(w io.Writer) (*PcapngWriter, error) {
+	body := make([]byte, 16)
+	binary.LittleEndian.PutUint32(body, pcapngByteOrderMagic)
+	binary.LittleEndian.PutUint16(body[4:], 1)
+	binary.LittleEndian.PutUint16(body[6:], 0)
+	//section length is not specified
+	binary.LittleEndian.PutUint64(body[8:], 0xFFFFFFFFFFFFFFFF)
+	_, err := w.Write(pcapngBlock(pcapngSectionHeaderBlock, body))
+	if err != nil {
+		return nil, err
+	}
+	return &PcapngWriter{w: w, interfaces: map[string]uint32{}}, nil
+}
+
+// WritePacket writes an enhanced packet block for packet, preceded by an interface block the first time
+// a packet of an interface is written.
+func (p *PcapngWriter) WritePacket(packet Packet) error {
+	interfaceID, ok := p.interfaces[packet.Interface]
+	if !ok {
+		interfaceID = uint32(len(p.interfaces))
+		body := make([]byte, 8)
+		binary.LittleEndian.PutUint16(body, pcapngLinkTypeEthernet)
+		binary.LittleEndian.PutUint32(body[4:], pcapngSnapLen)
+		name := packet.Interface
+		if len(name) > pcapngMaxInterfaceNameSize {
+			name = name[:pcapngMaxInterfaceNameSize]
+		}
+		body = appendPcapngOption(body, pcapngOptionInterfaceName, []byte(name))
+		body = appendPcapngOption(body, pcapngOptionEnd, nil)
+		_, err := p.w.Write(pcapngBlock(pcapngInterfaceBlock, body))
+		if err != nil {
+			return err
+		}
+		p.interfaces[packet.Interface] = interfaceID
+	}
+
+	//default timestamp resolution is microseconds
+	timestamp := uint64(packet.Timestamp.UnixNano() / 1e3)
+	body := make([]byte, 20, 20+len(packet.Data)+128)
+	binary.LittleEndian.PutUint32(body, interfaceID)
+	binary.LittleEndian.PutUint32(body[4:], uint32(timestamp>>32))
+	binary.LittleEndian.PutUint32(body[8:], uint32(timestamp))
+	binary.LittleEndian.PutUint32(body[12:], uint32(len(packet.Data)))
+	binary.LittleEndian.PutUint32(body[16:], uint32(len(packet.Data)))
+	body = append(body, pad32(packet.Data)...)
+	body = appendPcapngOption(body, pcapngOptionComment, []byte(packet.Comment()))
+	body = appendPcapngOption(body, pcapngOptionEnd, nil)
+	_, err := p.w.Write(pcapngBlock(pcapngEnhancedPacketBlock, body))
+	return err
+}
+
+// pcapngBlock frames body with the block type and the total length before and after it
+func pcapngBlock(blockType uint32, body []byte) []byte {
+	totalLength := uint32(12 + len(body))
+	block := make([]byte, 8, totalLength)
+	binary.LittleEndian.PutUint32(block, blockType)
+	binary.LittleEndian.PutUint32(block[4:], totalLength)
+	block = append(block, body...)
+	return append(block, byte(totalLength), byte(totalLength>>8), byte(totalLength>>16), byte(totalLength>>24))
+}
+
+func appendPcapngOption(body []byte, code uint16, value []byte) []byte {
+	header := make([]byte, 4)
+	binary.LittleEndian.PutUint16(header, code)
+	binary.LittleEndian.PutUint16(header[2:], uint16(len(value)))
+	body = append(body, header...)
+	return append(body, pad32(value)...)
+}
+
+// pad32 pads b with zeros to a multiple of 4 bytes
+func pad32(b []byte) []byte {
+	if len(b)%4 == 0 {
+		return b
+	}
+	return append(b[:len(b):len(b)], make([]byte, 4-len(b)%4)...)
+}
@@ -0,0 +1,103 @@
+package ios
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//lockdownPollInterval is the time between attempts to start a lockdown session on a device that just reconnected
+const lockdownPollInterval = time.Second
+
+//ReconnectWatcher detects a device disconnecting and connecting again, f.ex. after a reboot.
+//Create it with WatchReconnect before triggering the disconnect, otherwise the Detached message can be missed.
+type ReconnectWatcher struct {
+	muxConn        *UsbMuxConnection
+	udid           string
+	connectionType string
+	deviceID       int
+}
+
+//WatchReconnect issues a Listen command to usbmuxd for device. Call WaitForReconnect once the action that
+//disconnects the device was started and Close when done.
+func WatchReconnect(device DeviceEntry) (*ReconnectWatcher, error) {
+	muxConn, err := NewUsbMuxConnectionSimple()
+	if err != nil {
+		return nil, fmt.Errorf("failed connecting to usbmuxd: %w", err)
+	}
+	_, err = muxConn.Listen()
+	if err != nil {
+		muxConn.Close()
+		return nil, err
+	}
+	return &ReconnectWatcher{muxConn: muxConn, udid: device.Properties.SerialNumber, connectionType: device.Properties.ConnectionType, deviceID: device.DeviceID}, nil
+}
+
+//WaitForReconnect blocks until the device was detached and attached again and a lockdown session can be started.
+//It returns the DeviceEntry of the reattached device, which has a new DeviceID. If ctx ends first, ctx.Err() is returned.
+func (w *ReconnectWatcher) WaitForReconnect(ctx context.Context) (DeviceEntry, error) {
+	device, err := w.WaitForAttach(ctx)
+	if err != nil {
+		return DeviceEntry{}, err
+	}
+	err = WaitForLockdown(ctx, device)
+	if err != nil {
+		return DeviceEntry{}, err
+	}
+	return device, nil
+}
+
+//WaitForAttach blocks until the device was detached and attached again and returns the DeviceEntry of the
+//reattached device. Unlike WaitForReconnect it does not wait for lockdownd, which is not ready yet right after a reboot.
+func (w *ReconnectWatcher) WaitForAttach(ctx context.Context) (DeviceEntry, error) {
+	detached := false
+	for {
+		msg, err := w.muxConn.ReadMessageContext(ctx)
+		if err != nil {
+			return DeviceEntry{}, err
+		}
+		attached, err := attachedFromBytes(msg.Payload)
+		if err != nil {
+			return DeviceEntry{}, err
+		}
+		log.Debugf("reconnect watcher received %+v", attached)
+		//usbmuxd sends Attached messages for all connected devices right after Listen, they only update the DeviceID.
+		//A device connected over USB and Wi-Fi is listed twice, only the entry with the same connection type is followed.
+		if attached.DeviceAttached() && attached.Properties.SerialNumber == w.udid && attached.Properties.ConnectionType == w.connectionType {
+			if !detached {
+				w.deviceID = attached.DeviceID
+				continue
+			}
+			return attached.DeviceEntry(), nil
+		}
+		if attached.DeviceDetached() && attached.DeviceID == w.deviceID {
+			log.Debugf("device %s detached", w.udid)
+			detached = true
+		}
+	}
+}
+
+//Close closes the connection to usbmuxd
+func (w *ReconnectWatcher) Close() {
+	w.muxConn.Close()
+}
+
+//WaitForLockdown tries to start a lockdown session with device every second until it succeeds or ctx ends.
+//Right after a reboot the device is attached before lockdownd accepts connections.
+func WaitForLockdown(ctx context.Context, device DeviceEntry) error {
+	for {
+		lockdown, err := ConnectLockdownWithSession(device)
+		if err == nil {
+			lockdown.Close()
+			return nil
+		}
+		log.Debugf("lockdown not ready yet: %v", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("lockdown did not become ready: %w", ctx.Err())
+		case <-time.After(lockdownPollInterval):
+		}
+	}
+}
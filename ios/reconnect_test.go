@@ -0,0 +1,48 @@
+package ios_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/usbmuxsim"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForReconnect(t *testing.T) {
+	server, lockdownd, entry := usbmuxsim.StartPairedDevice(t, "udid0")
+	device := lockdownd.Device()
+	server.Attach(usbmuxsim.NewDevice("other"))
+	server.Attach(usbmuxsim.NewNetworkDevice("udid0"))
+	watcher, err := ios.WatchReconnect(entry)
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		server.Detach("other")
+		server.Detach("udid0")
+		server.Attach(device)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reconnected, err := watcher.WaitForReconnect(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "udid0", reconnected.Properties.SerialNumber)
+	assert.Equal(t, device.DeviceID(), reconnected.DeviceID)
+	assert.NotEqual(t, entry.DeviceID, reconnected.DeviceID)
+}
+
+func TestWaitForReconnectTimeout(t *testing.T) {
+	_, _, entry := usbmuxsim.StartPairedDevice(t, "udid0")
+	watcher, err := ios.WatchReconnect(entry)
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = watcher.WaitForReconnect(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
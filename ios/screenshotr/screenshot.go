@@ -1,6 +1,7 @@
 package screenshotr
 
 import (
+	"context"
 	"errors"
 	"io"
 
@@ -60,6 +61,21 @@ func (screenShotrConn *Connection) readVersion(reader io.Reader) error {
 	return nil
 }
 
+//TakeScreenshotContext is like TakeScreenshot but returns ctx.Err() if ctx ends before the screenshot was received.
+//The connection should be closed after it was interrupted.
+func (screenShotrConn *Connection) TakeScreenshotContext(ctx context.Context) ([]uint8, error) {
+	var screenshot []uint8
+	err := ios.RunWithContext(ctx, screenShotrConn.deviceConn, func() error {
+		var err error
+		screenshot, err = screenShotrConn.TakeScreenshot()
+		return err
+	})
+	if err != nil {
+		return make([]uint8, 0), err
+	}
+	return screenshot, nil
+}
+
 //TakeScreenshot uses Screenshotr to get a screenshot as a byteslice
 func (screenShotrConn *Connection) TakeScreenshot() ([]uint8, error) {
 	reader := screenShotrConn.deviceConn.Reader()
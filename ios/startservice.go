@@ -45,7 +45,11 @@ func (lockDownConn *LockDownConnection) StartService(serviceName string) (StartS
 	}
 	response := getStartServiceResponsefromBytes(resp)
 	if response.Error != "" {
-		return StartServiceResponse{}, fmt.Errorf("Could not start service:%s with reason:'%s'. Have you mounted the Developer Image?", serviceName, response.Error)
+		err := LockdownError{Request: "StartService", Service: serviceName, Reason: response.Error}
+		if isDeveloperService(serviceName) {
+			return StartServiceResponse{}, fmt.Errorf("Have you mounted the Developer Image? %w", err)
+		}
+		return StartServiceResponse{}, err
 	}
 	log.WithFields(log.Fields{"Port": response.Port, "Request": response.Request, "Service": response.Service, "EnableServiceSSL": response.EnableServiceSSL}).Debug("Service started on device")
 	return response, nil
@@ -29,6 +29,7 @@ type StartSessionResponse struct {
 	EnableSessionSSL bool
 	Request          string
 	SessionID        string
+	Error            string
 }
 
 func startSessionResponsefromBytes(plistBytes []byte) StartSessionResponse {
@@ -52,6 +53,9 @@ func (lockDownConn *LockDownConnection) StartSession(pairRecord PairRecord) (Sta
 		return StartSessionResponse{}, err
 	}
 	response := startSessionResponsefromBytes(resp)
+	if response.Error != "" {
+		return response, LockdownError{Request: "StartSession", Reason: response.Error}
+	}
 	lockDownConn.sessionID = response.SessionID
 	if response.EnableSessionSSL {
 		err = lockDownConn.deviceConnection.EnableSessionSsl(pairRecord)
@@ -0,0 +1,130 @@
+package syslog
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//LogEntry is a single parsed syslog line like
+//"Oct 18 10:11:12 iPhone SpringBoard(FrontBoard)[58] <Notice>: message"
+type LogEntry struct {
+	Timestamp  time.Time `json:"timestamp"`
+	DeviceName string    `json:"deviceName"`
+	Process    string    `json:"process"`
+	//Subsystem is the library or framework in parentheses after the process name, it is empty for most lines
+	Subsystem string `json:"subsystem,omitempty"`
+	Pid       int    `json:"pid"`
+	Level     string `json:"level"`
+	Message   string `json:"message"`
+}
+
+var logLinePattern = regexp.MustCompile(`(?s)^(\w{3} [ \d]\d \d{2}:\d{2}:\d{2}) (\S+) ([^\[(]+?)(?:\(([^)]*)\))?\[(\d+)\](?: <(\w+)>)?: (.*)$`)
+
+const timestampLayout = "Jan _2 15:04:05"
+
+//ParseLogEntry parses a raw syslog line as returned by Connection.ReadLogMessage. Syslog timestamps do not contain a year,
+//the current year is used unless that would put the entry into the future.
+func ParseLogEntry(line string) (LogEntry, error) {
+	line = strings.TrimRight(line, "\x00\n")
+	groups := logLinePattern.FindStringSubmatch(line)
+	if groups == nil {
+		return LogEntry{}, fmt.Errorf("invalid syslog line: %s", line)
+	}
+	timestamp, err := time.ParseInLocation(timestampLayout, groups[1], time.Local)
+	if err != nil {
+		return LogEntry{}, fmt.Errorf("invalid timestamp in syslog line: %w", err)
+	}
+	now := time.Now()
+	timestamp = timestamp.AddDate(now.Year(), 0, 0)
+	if timestamp.After(now.Add(24 * time.Hour)) {
+		timestamp = timestamp.AddDate(-1, 0, 0)
+	}
+	pid, err := strconv.Atoi(groups[5])
+	if err != nil {
+		return LogEntry{}, fmt.Errorf("invalid pid in syslog line: %w", err)
+	}
+	return LogEntry{
+		Timestamp:  timestamp,
+		DeviceName: groups[2],
+		Process:    groups[3],
+		Subsystem:  groups[4],
+		Pid:        pid,
+		Level:      groups[6],
+		Message:    groups[7],
+	}, nil
+}
+
+//String formats the entry like the device does. Entries that could not be parsed only contain the Message.
+func (e LogEntry) String() string {
+	if e.Process == "" {
+		return e.Message
+	}
+	process := e.Process
+	if e.Subsystem != "" {
+		process = fmt.Sprintf("%s(%s)", process, e.Subsystem)
+	}
+	level := ""
+	if e.Level != "" {
+		level = fmt.Sprintf(" <%s>", e.Level)
+	}
+	return fmt.Sprintf("%s %s %s[%d]%s: %s", e.Timestamp.Format(timestampLayout), e.DeviceName, process, e.Pid, level, e.Message)
+}
+
+//levels orders the syslog levels by severity
+var levels = map[string]int{
+	"debug":   0,
+	"info":    1,
+	"notice":  2,
+	"warning": 3,
+	"error":   4,
+	"fault":   5,
+}
+
+//Filter selects LogEntries. Empty fields match everything.
+type Filter struct {
+	Process string
+	Pid     int
+	//Match is applied to the message
+	Match *regexp.Regexp
+	//MinLevel drops all entries less severe than this level, f.ex. "Warning" keeps Warning, Error and Fault
+	MinLevel string
+}
+
+//NewFilter creates a Filter and validates the regular expression and level.
+func NewFilter(process string, pid int, match string, minLevel string) (Filter, error) {
+	filter := Filter{Process: process, Pid: pid, MinLevel: minLevel}
+	if match != "" {
+		re, err := regexp.Compile(match)
+		if err != nil {
+			return Filter{}, fmt.Errorf("invalid match expression: %w", err)
+		}
+		filter.Match = re
+	}
+	if _, ok := levels[strings.ToLower(minLevel)]; minLevel != "" && !ok {
+		return Filter{}, fmt.Errorf("unknown log level '%s'", minLevel)
+	}
+	return filter, nil
+}
+
+//Matches returns true if entry passes all criteria of the Filter
+func (f Filter) Matches(entry LogEntry) bool {
+	if f.Process != "" && f.Process != entry.Process {
+		return false
+	}
+	if f.Pid != 0 && f.Pid != entry.Pid {
+		return false
+	}
+	if f.Match != nil && !f.Match.MatchString(entry.Message) {
+		return false
+	}
+	if f.MinLevel != "" {
+		level, ok := levels[strings.ToLower(entry.Level)]
+		if !ok || level < levels[strings.ToLower(f.MinLevel)] {
+			return false
+		}
+	}
+	return true
+}
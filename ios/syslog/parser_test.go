@@ -0,0 +1,78 @@
+package syslog_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios/syslog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLogEntry(t *testing.T) {
+	entry, err := syslog.ParseLogEntry("Oct  3 10:11:12 iPhone SpringBoard(FrontBoard)[58] <Notice>: [app<com.apple.Maps>] launched\n\x00")
+	require.NoError(t, err)
+	assert.Equal(t, "iPhone", entry.DeviceName)
+	assert.Equal(t, "SpringBoard", entry.Process)
+	assert.Equal(t, "FrontBoard", entry.Subsystem)
+	assert.Equal(t, 58, entry.Pid)
+	assert.Equal(t, "Notice", entry.Level)
+	assert.Equal(t, "[app<com.apple.Maps>] launched", entry.Message)
+	assert.Equal(t, time.October, entry.Timestamp.Month())
+	assert.Equal(t, 3, entry.Timestamp.Day())
+	assert.Equal(t, 10, entry.Timestamp.Hour())
+	assert.False(t, entry.Timestamp.After(time.Now().Add(24*time.Hour)))
+
+	entry, err = syslog.ParseLogEntry("Oct 13 10:11:12 iPad kernel[0]: first line\nsecond line\x00")
+	require.NoError(t, err)
+	assert.Equal(t, "kernel", entry.Process)
+	assert.Equal(t, "", entry.Subsystem)
+	assert.Equal(t, "", entry.Level)
+	assert.Equal(t, "first line\nsecond line", entry.Message)
+
+	entry, err = syslog.ParseLogEntry("Oct 13 10:11:12 iPad Some Process(libsystem_info.dylib)[123] <Error>: failed")
+	require.NoError(t, err)
+	assert.Equal(t, "Some Process", entry.Process)
+	assert.Equal(t, "libsystem_info.dylib", entry.Subsystem)
+
+	_, err = syslog.ParseLogEntry("not a syslog line")
+	assert.Error(t, err)
+}
+
+func TestLogEntryString(t *testing.T) {
+	for _, line := range []string{
+		"Oct  3 10:11:12 iPhone SpringBoard(FrontBoard)[58] <Notice>: [app<com.apple.Maps>] launched",
+		"Oct 13 10:11:12 iPad kernel[0]: first line\nsecond line",
+	} {
+		entry, err := syslog.ParseLogEntry(line + "\n\x00")
+		require.NoError(t, err)
+		assert.Equal(t, line, entry.String())
+	}
+	assert.Equal(t, "not a syslog line", syslog.LogEntry{Message: "not a syslog line"}.String())
+}
+
+func TestFilter(t *testing.T) {
+	entry := syslog.LogEntry{Process: "SpringBoard", Pid: 58, Level: "Warning", Message: "hello world"}
+
+	filter, err := syslog.NewFilter("", 0, "", "")
+	require.NoError(t, err)
+	assert.True(t, filter.Matches(entry))
+
+	filter, err = syslog.NewFilter("SpringBoard", 58, "wor.d", "notice")
+	require.NoError(t, err)
+	assert.True(t, filter.Matches(entry))
+
+	filter, _ = syslog.NewFilter("kernel", 0, "", "")
+	assert.False(t, filter.Matches(entry))
+	filter, _ = syslog.NewFilter("", 1, "", "")
+	assert.False(t, filter.Matches(entry))
+	filter, _ = syslog.NewFilter("", 0, "^world", "")
+	assert.False(t, filter.Matches(entry))
+	filter, _ = syslog.NewFilter("", 0, "", "Error")
+	assert.False(t, filter.Matches(entry))
+
+	_, err = syslog.NewFilter("", 0, "(", "")
+	assert.Error(t, err)
+	_, err = syslog.NewFilter("", 0, "", "verbose")
+	assert.Error(t, err)
+}
@@ -2,8 +2,10 @@ package syslog
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"io"
+	"strings"
 
 	"github.com/danielpaulus/go-ios/ios"
 )
@@ -26,6 +28,15 @@ func New(device ios.DeviceEntry) (*Connection, error) {
 	return &Connection{deviceConn: deviceConn}, nil
 }
 
+//NewContext is like New but gives up connecting to the syslog service once ctx is done.
+func NewContext(ctx context.Context, device ios.DeviceEntry) (*Connection, error) {
+	deviceConn, err := ios.ConnectToServiceContext(ctx, device, serviceName)
+	if err != nil {
+		return &Connection{}, err
+	}
+	return &Connection{deviceConn: deviceConn}, nil
+}
+
 //ReadLogMessage this is a blocking function that will return individual log messages received from syslog.
 //Call it in an endless for loop in a separate go routine.
 func (sysLogConn *Connection) ReadLogMessage() (string, error) {
@@ -37,6 +48,32 @@ func (sysLogConn *Connection) ReadLogMessage() (string, error) {
 	return logmsg, nil
 }
 
+//ReadLogEntry reads the next log message and parses it into a LogEntry. Messages that cannot be parsed are
+//returned as a LogEntry that only has the Message set.
+func (sysLogConn *Connection) ReadLogEntry() (LogEntry, error) {
+	logmsg, err := sysLogConn.ReadLogMessage()
+	if err != nil {
+		return LogEntry{}, err
+	}
+	entry, err := ParseLogEntry(logmsg)
+	if err != nil {
+		return LogEntry{Message: strings.TrimRight(logmsg, "\x00\n")}, nil
+	}
+	return entry, nil
+}
+
+//ReadLogMessageContext is like ReadLogMessage but returns ctx.Err() once ctx is done.
+//The connection should be closed after it was interrupted.
+func (sysLogConn *Connection) ReadLogMessageContext(ctx context.Context) (string, error) {
+	var logmsg string
+	err := ios.RunWithContext(ctx, sysLogConn.deviceConn, func() error {
+		var err error
+		logmsg, err = sysLogConn.ReadLogMessage()
+		return err
+	})
+	return logmsg, err
+}
+
 //Encode returns only and error because syslog is read only.
 func (sysLogConn *Connection) Encode(message interface{}) ([]byte, error) {
 	return nil, errors.New("Syslog is readonly")
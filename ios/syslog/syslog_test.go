@@ -0,0 +1,35 @@
+package syslog_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios/syslog"
+	"github.com/danielpaulus/go-ios/ios/usbmuxsim"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLogMessageContext(t *testing.T) {
+	_, lockdownd, entry := usbmuxsim.StartPairedDevice(t, "udid0")
+	stop := make(chan struct{})
+	defer close(stop)
+	lockdownd.HandleService("com.apple.syslog_relay", func(conn net.Conn) {
+		conn.Write([]byte("first message\x00"))
+		<-stop
+	})
+	conn, err := syslog.NewContext(context.Background(), entry)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	msg, err := conn.ReadLogMessageContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "first message\x00", msg)
+
+	_, err = conn.ReadLogMessageContext(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
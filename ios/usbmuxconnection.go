@@ -1,16 +1,32 @@
 package ios
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"io"
+	"os"
 	"reflect"
 )
 
 //DefaultUsbmuxdSocket this is the unix domain socket address to connect to.
 const DefaultUsbmuxdSocket = "/var/run/usbmuxd"
 
+//UsbmuxdSocketEnv is the name of the env variable that can be used to point go-ios to a different usbmuxd,
+//f.ex. "unix:/tmp/usbmuxd" or "tcp:127.0.0.1:27015". Useful for testing against the usbmuxsim package.
+const UsbmuxdSocketEnv = "USBMUXD_SOCKET_ADDRESS"
+
+//GetUsbmuxdSocket returns the socket address from the USBMUXD_SOCKET_ADDRESS env variable if it is set
+//and DefaultUsbmuxdSocket otherwise.
+func GetUsbmuxdSocket() string {
+	socket := os.Getenv(UsbmuxdSocketEnv)
+	if socket == "" {
+		return DefaultUsbmuxdSocket
+	}
+	return socket
+}
+
 //UsbMuxConnection can send and read messages to the usbmuxd process to manage pairrecors, listen for device changes
 //and connect to services on the phone. Usually messages follow a  request-response pattern. there is a tag integer
 //in the message header, that is increased with every sent message.
@@ -27,8 +43,9 @@ func NewUsbMuxConnection(deviceConn DeviceConnectionInterface) *UsbMuxConnection
 }
 
 // NewUsbMuxConnectionSimple creates a new UsbMuxConnection with a connection to /var/run/usbmuxd
+// or the socket specified in the USBMUXD_SOCKET_ADDRESS env variable
 func NewUsbMuxConnectionSimple() (*UsbMuxConnection, error) {
-	deviceConn, err := NewDeviceConnection(DefaultUsbmuxdSocket)
+	deviceConn, err := NewDeviceConnection(GetUsbmuxdSocket())
 	muxConn := &UsbMuxConnection{tag: 0, deviceConn: deviceConn}
 	return muxConn, err
 }
@@ -106,6 +123,17 @@ func (muxConn *UsbMuxConnection) ReadMessage() (UsbMuxMessage, error) {
 	return msg, nil
 }
 
+//ReadMessageContext is like ReadMessage but returns ctx.Err() when ctx ends before a message was received.
+func (muxConn *UsbMuxConnection) ReadMessageContext(ctx context.Context) (UsbMuxMessage, error) {
+	var msg UsbMuxMessage
+	err := RunWithContext(ctx, muxConn.deviceConn, func() error {
+		var err error
+		msg, err = muxConn.ReadMessage()
+		return err
+	})
+	return msg, err
+}
+
 //encode serializes a MuxMessage struct to a Plist and writes it to the io.Writer.
 func (muxConn *UsbMuxConnection) encode(message interface{}, writer io.Writer) error {
 	log.Tracef("UsbMux send %v  on  %v", reflect.TypeOf(message), &muxConn.deviceConn)
@@ -0,0 +1,303 @@
+package usbmuxsim
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios/afc"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+//AfcService serves the AFC protocol from an afero.Fs, f.ex. an afero.MemMapFs. Register its Handle method
+//for "com.apple.afc" or any other service that speaks AFC using Lockdownd.HandleService.
+//It supports the operations afc.Connection uses: directories, stat, file descriptors with read, write,
+//seek, tell and truncate, rename, remove and setting the modification time.
+type AfcService struct {
+	fs          afero.Fs
+	maxReadSize int
+}
+
+//NewAfcService creates an AfcService for fs
+func NewAfcService(fs afero.Fs) *AfcService {
+	return &AfcService{fs: fs}
+}
+
+//SetMaxReadSize limits the number of bytes a single read returns, clients have to send more reads for the rest.
+//0 means no limit. Call it before the first connection is handled.
+func (s *AfcService) SetMaxReadSize(maxReadSize int) {
+	s.maxReadSize = maxReadSize
+}
+
+//afcSession contains the open files of one connection
+type afcSession struct {
+	fs          afero.Fs
+	files       map[uint64]afero.File
+	nextFD      uint64
+	maxReadSize int
+}
+
+//Handle serves AFC requests on conn until it is closed
+func (s *AfcService) Handle(conn net.Conn) {
+	session := &afcSession{fs: s.fs, files: map[uint64]afero.File{}, nextFD: 1, maxReadSize: s.maxReadSize}
+	defer func() {
+		for _, f := range session.files {
+			f.Close()
+		}
+	}()
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	for {
+		request, err := afc.Decode(reader)
+		if err != nil {
+			return
+		}
+		operation, headerPayload, payload, err := session.handle(request)
+		if err != nil {
+			log.Tracef("usbmuxsim: afc operation %d failed: %v", request.Header.Operation, err)
+			operation, headerPayload, payload = afc.Afc_operation_status, statusPayload(afcStatus(err)), nil
+		}
+		response := afc.AfcPacket{
+			Header: afc.AfcPacketHeader{
+				Magic:         afc.Afc_magic,
+				Entire_length: afc.Afc_header_size + uint64(len(headerPayload)+len(payload)),
+				This_length:   afc.Afc_header_size + uint64(len(headerPayload)),
+				Packet_num:    request.Header.Packet_num,
+				Operation:     operation,
+			},
+			HeaderPayload: headerPayload,
+			Payload:       payload,
+		}
+		err = afc.Encode(response, writer)
+		if err == nil {
+			err = writer.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+//handle executes request and returns the operation and payloads of the response
+func (s *afcSession) handle(request afc.AfcPacket) (uint64, []byte, []byte, error) {
+	data := request.HeaderPayload
+	ok := statusPayload(afc.Afc_Err_Success)
+	switch request.Header.Operation {
+	case afc.Afc_operation_read_dir:
+		infos, err := afero.ReadDir(s.fs, cString(data))
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		names := []string{".", ".."}
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		return afc.Afc_operation_data, nil, nullSeparated(names), nil
+	case afc.Afc_operation_file_info:
+		info, err := s.fs.Stat(cString(data))
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		return afc.Afc_operation_data, nil, statPayload(info), nil
+	case afc.Afc_operation_file_open:
+		if len(data) < 8 {
+			return 0, nil, nil, afc.ErrInvalidArgument
+		}
+		flag, err := openFlag(binary.LittleEndian.Uint64(data))
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		f, err := s.fs.OpenFile(cString(data[8:]), flag, 0644)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		fd := s.nextFD
+		s.nextFD++
+		s.files[fd] = f
+		return afc.Afc_operation_file_open_result, uint64Payload(fd), nil, nil
+	case afc.Afc_operation_file_read:
+		f, err := s.file(data, 16)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		size := binary.LittleEndian.Uint64(data[8:])
+		if s.maxReadSize > 0 && size > uint64(s.maxReadSize) {
+			size = uint64(s.maxReadSize)
+		}
+		buffer := make([]byte, size)
+		n, err := io.ReadFull(f, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, nil, nil, err
+		}
+		return afc.Afc_operation_data, nil, buffer[:n], nil
+	case afc.Afc_operation_file_write:
+		f, err := s.file(data, 8)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		_, err = f.Write(request.Payload)
+		return afc.Afc_operation_status, ok, nil, err
+	case afc.Afc_operation_file_seek:
+		f, err := s.file(data, 24)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		_, err = f.Seek(int64(binary.LittleEndian.Uint64(data[16:])), int(binary.LittleEndian.Uint64(data[8:])))
+		return afc.Afc_operation_status, ok, nil, err
+	case afc.Afc_operation_file_tell:
+		f, err := s.file(data, 8)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		position, err := f.Seek(0, io.SeekCurrent)
+		return afc.Afc_operation_file_tell_result, uint64Payload(uint64(position)), nil, err
+	case afc.Afc_operation_file_set_size:
+		f, err := s.file(data, 16)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		return afc.Afc_operation_status, ok, nil, f.Truncate(int64(binary.LittleEndian.Uint64(data[8:])))
+	case afc.Afc_operation_file_close:
+		f, err := s.file(data, 8)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		delete(s.files, binary.LittleEndian.Uint64(data))
+		return afc.Afc_operation_status, ok, nil, f.Close()
+	case afc.Afc_operation_make_dir:
+		return afc.Afc_operation_status, ok, nil, s.fs.MkdirAll(cString(data), 0755)
+	case afc.Afc_operation_remove_path:
+		name := cString(data)
+		if infos, err := afero.ReadDir(s.fs, name); err == nil && len(infos) > 0 {
+			return 0, nil, nil, afc.StatusError(afc.Afc_Err_DirNotEmpty)
+		}
+		return afc.Afc_operation_status, ok, nil, s.fs.Remove(name)
+	case afc.AFC_OP_REMOVE_PATH_AND_CONTENTS:
+		name := cString(data)
+		if _, err := s.fs.Stat(name); err != nil {
+			return 0, nil, nil, err
+		}
+		return afc.Afc_operation_status, ok, nil, s.fs.RemoveAll(name)
+	case afc.Afc_operation_rename_path:
+		names := bytes.SplitN(data, []byte{0}, 3)
+		if len(names) < 2 {
+			return 0, nil, nil, afc.ErrInvalidArgument
+		}
+		return afc.Afc_operation_status, ok, nil, s.fs.Rename(string(names[0]), string(names[1]))
+	case afc.Afc_operation_set_file_time:
+		if len(data) < 8 {
+			return 0, nil, nil, afc.ErrInvalidArgument
+		}
+		mtime := time.Unix(0, int64(binary.LittleEndian.Uint64(data)))
+		return afc.Afc_operation_status, ok, nil, s.fs.Chtimes(cString(data[8:]), mtime, mtime)
+	}
+	return 0, nil, nil, afc.ErrUnknownPacketType
+}
+
+//file returns the open file for the descriptor at the start of data, which has to be at least length bytes
+func (s *afcSession) file(data []byte, length int) (afero.File, error) {
+	if len(data) < length {
+		return nil, afc.ErrInvalidArgument
+	}
+	f, ok := s.files[binary.LittleEndian.Uint64(data)]
+	if !ok {
+		return nil, afc.ErrInvalidArgument
+	}
+	return f, nil
+}
+
+func openFlag(mode uint64) (int, error) {
+	switch mode {
+	case afc.Afc_Mode_RDONLY:
+		return os.O_RDONLY, nil
+	case afc.Afc_Mode_RW:
+		return os.O_RDWR | os.O_CREATE, nil
+	case afc.Afc_Mode_WRONLY:
+		return os.O_WRONLY | os.O_CREATE | os.O_TRUNC, nil
+	case afc.Afc_Mode_WR:
+		return os.O_RDWR | os.O_CREATE | os.O_TRUNC, nil
+	case afc.Afc_Mode_APPEND:
+		return os.O_WRONLY | os.O_CREATE | os.O_APPEND, nil
+	case afc.Afc_Mode_RDAPPEND:
+		return os.O_RDWR | os.O_CREATE | os.O_APPEND, nil
+	}
+	return 0, fmt.Errorf("invalid afc file mode %d: %w", mode, afc.ErrInvalidArgument)
+}
+
+//afcStatus converts errors of afero.Fs to afc status codes
+func afcStatus(err error) uint64 {
+	var statusError afc.StatusError
+	switch {
+	case errors.As(err, &statusError):
+		return uint64(statusError)
+	case errors.Is(err, fs.ErrNotExist):
+		return afc.Afc_Err_ObjectNotFound
+	case errors.Is(err, fs.ErrExist):
+		return afc.Afc_Err_ObjectExists
+	case errors.Is(err, fs.ErrPermission):
+		return afc.Afc_Err_PermDenied
+	}
+	return afc.Afc_Err_UnknownError
+}
+
+func statPayload(info os.FileInfo) []byte {
+	format := "S_IFREG"
+	if info.IsDir() {
+		format = "S_IFDIR"
+	}
+	mtime := fmt.Sprint(info.ModTime().UnixNano())
+	values := map[string]string{
+		"st_size":      fmt.Sprint(info.Size()),
+		"st_blocks":    fmt.Sprint((info.Size() + 511) / 512),
+		"st_nlink":     "1",
+		"st_ifmt":      format,
+		"st_mtime":     mtime,
+		"st_birthtime": mtime,
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var fields []string
+	for _, k := range keys {
+		fields = append(fields, k, values[k])
+	}
+	return nullSeparated(fields)
+}
+
+func nullSeparated(values []string) []byte {
+	var buffer bytes.Buffer
+	for _, v := range values {
+		buffer.WriteString(v)
+		buffer.WriteByte(0)
+	}
+	return buffer.Bytes()
+}
+
+func cString(data []byte) string {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
+	}
+	return path.Clean("/" + string(data))
+}
+
+func statusPayload(status uint64) []byte {
+	return uint64Payload(status)
+}
+
+func uint64Payload(value uint64) []byte {
+	payload := make([]byte, 8)
+	binary.LittleEndian.PutUint64(payload, value)
+	return payload
+}
@@ -0,0 +1,85 @@
+package usbmuxsim
+
+import (
+	"net"
+	"sync"
+
+	"github.com/danielpaulus/go-ios/ios"
+)
+
+//ServiceHandler gets the raw connection of a client that successfully issued a Connect command for
+//the port it is registered for. The connection is closed when the handler returns.
+type ServiceHandler func(conn net.Conn)
+
+//Device is a fake iOS device. Register ServiceHandlers for the ports clients should be able to connect to
+//and attach it to a Server.
+type Device struct {
+	udid       string
+	deviceID   int
+	properties ios.DeviceProperties
+	services   map[uint16]ServiceHandler
+	mux        sync.Mutex
+}
+
+//NewDevice creates a fake USB device with the given udid
+func NewDevice(udid string) *Device {
+	return &Device{
+		udid: udid,
+		properties: ios.DeviceProperties{
+			ConnectionSpeed: 480000000,
+			ConnectionType:  "USB",
+			ProductID:       4776,
+			SerialNumber:    udid,
+		},
+		services: map[uint16]ServiceHandler{},
+	}
+}
+
+//NewNetworkDevice creates a fake device connected over Wi-Fi. usbmuxd lists a device that is connected over USB
+//and the network twice, attach a NewDevice and a NewNetworkDevice with the same udid to simulate that.
+func NewNetworkDevice(udid string) *Device {
+	return &Device{
+		udid: udid,
+		properties: ios.DeviceProperties{
+			ConnectionType: "Network",
+			SerialNumber:   udid,
+		},
+		services: map[uint16]ServiceHandler{},
+	}
+}
+
+//UDID returns the udid of the device
+func (d *Device) UDID() string {
+	return d.udid
+}
+
+//DeviceID returns the id that was assigned when the device was attached to a Server
+func (d *Device) DeviceID() int {
+	return d.deviceID
+}
+
+//HandleService registers a ServiceHandler for connections to the given port. The port is
+//in host byte order, as it is returned in StartServiceResponse.Port for example.
+func (d *Device) HandleService(port uint16, handler ServiceHandler) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.services[port] = handler
+}
+
+func (d *Device) serviceHandler(port uint16) (ServiceHandler, bool) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	handler, ok := d.services[port]
+	return handler, ok
+}
+
+func (d *Device) deviceEntry() ios.DeviceEntry {
+	props := d.properties
+	props.DeviceID = d.deviceID
+	return ios.DeviceEntry{DeviceID: d.deviceID, MessageType: "Attached", Properties: props}
+}
+
+func (d *Device) attachedMessage() ios.AttachedMessage {
+	entry := d.deviceEntry()
+	return ios.AttachedMessage{MessageType: "Attached", DeviceID: entry.DeviceID, Properties: entry.Properties}
+}
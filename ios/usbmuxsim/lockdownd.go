@@ -0,0 +1,321 @@
+package usbmuxsim
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"howett.net/plist"
+)
+
+//LockdownPort is the port lockdownd listens on, in host byte order
+const LockdownPort uint16 = 62078
+
+//firstServicePort is the first port assigned to services that are not part of the Profile
+const firstServicePort uint16 = 49152
+
+type lockdownRequest struct {
+	Request    string
+	Key        string
+	Domain     string
+	Value      interface{}
+	Service    string
+	HostID     string
+	SessionID  string
+	PairRecord ios.FullPairRecordData
+}
+
+//Lockdownd is a fake lockdownd for a simulated Device. It answers QueryType, GetValue, SetValue, StartSession,
+//StopSession, StartService and Pair requests based on a Profile and uses real TLS for sessions and services.
+type Lockdownd struct {
+	device        *Device
+	profile       Profile
+	deviceKey     *rsa.PrivateKey
+	deviceCert    []byte
+	hostIDs       map[string]bool
+	pairingDialog bool
+	nextPort      uint16
+	mux           sync.Mutex
+}
+
+//NewLockdownd creates a Lockdownd with a freshly generated device key and registers it on the LockdownPort of device.
+func NewLockdownd(device *Device, profile Profile) (*Lockdownd, error) {
+	profile = profile.normalize()
+	deviceKey, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+	values := profile.Values[""]
+	if _, ok := values["UniqueDeviceID"]; !ok {
+		values["UniqueDeviceID"] = device.UDID()
+	}
+	values["DevicePublicKey"] = publicKeyToPEM(&deviceKey.PublicKey)
+	l := &Lockdownd{
+		device:    device,
+		profile:   profile,
+		deviceKey: deviceKey,
+		hostIDs:   map[string]bool{},
+		nextPort:  firstServicePort,
+	}
+	device.HandleService(LockdownPort, l.handleConnection)
+	return l, nil
+}
+
+//Device returns the simulated Device lockdownd runs on
+func (l *Lockdownd) Device() *Device {
+	return l.device
+}
+
+//GeneratePairRecord creates a complete PairRecord the same way a host does during pairing and
+//trusts it, so it can be used to start sessions right away.
+func (l *Lockdownd) GeneratePairRecord(systemBUID string) (ios.PairRecord, error) {
+	rootKey, err := generateKey()
+	if err != nil {
+		return ios.PairRecord{}, err
+	}
+	rootCert, err := createCert(&rootKey.PublicKey, nil, rootKey)
+	if err != nil {
+		return ios.PairRecord{}, err
+	}
+	hostKey, err := generateKey()
+	if err != nil {
+		return ios.PairRecord{}, err
+	}
+	hostCert, err := createCert(&hostKey.PublicKey, rootCert, rootKey)
+	if err != nil {
+		return ios.PairRecord{}, err
+	}
+	deviceCert, err := createCert(&l.deviceKey.PublicKey, rootCert, rootKey)
+	if err != nil {
+		return ios.PairRecord{}, err
+	}
+	record := ios.PairRecord{
+		HostID:            strings.ToUpper(uuid.New().String()),
+		SystemBUID:        systemBUID,
+		HostCertificate:   certToPEM(hostCert),
+		HostPrivateKey:    privateKeyToPEM(hostKey),
+		DeviceCertificate: certToPEM(deviceCert),
+		RootCertificate:   certToPEM(rootCert),
+		RootPrivateKey:    privateKeyToPEM(rootKey),
+		EscrowBag:         []byte("escrowbag"),
+	}
+	if wifiMac, ok := l.profile.Values[""]["WiFiAddress"].(string); ok {
+		record.WiFiMACAddress = wifiMac
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.hostIDs[record.HostID] = true
+	l.deviceCert = record.DeviceCertificate
+	return record, nil
+}
+
+//SetPairingDialogPending simulates the trust dialog on the device. As long as it is pending, Pair requests fail
+//with PairingDialogResponsePending.
+func (l *Lockdownd) SetPairingDialogPending(pending bool) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.pairingDialog = pending
+}
+
+//Value returns the value stored for key in domain, use the empty domain for values without domain.
+func (l *Lockdownd) Value(domain string, key string) (interface{}, bool) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	v, ok := l.profile.Values[domain][key]
+	return v, ok
+}
+
+//HandleService registers handler for the service with the given name. The handler receives the
+//connection after the SSL handshake if the ServiceProfile requires SSL. Services not contained in the
+//Profile get a free port and use SSL.
+func (l *Lockdownd) HandleService(name string, handler ServiceHandler) {
+	l.mux.Lock()
+	service, ok := l.profile.Services[name]
+	if !ok {
+		service = ServiceProfile{Port: l.nextPort, EnableServiceSSL: true}
+		l.nextPort++
+		l.profile.Services[name] = service
+	}
+	l.mux.Unlock()
+	l.device.HandleService(service.Port, func(conn net.Conn) {
+		if !service.EnableServiceSSL {
+			handler(conn)
+			return
+		}
+		tlsConn, err := l.serverTLSConn(conn)
+		if err != nil {
+			log.Debugf("usbmuxsim: ssl handshake for %s failed: %v", name, err)
+			return
+		}
+		if service.HandshakeOnly {
+			handler(conn)
+			return
+		}
+		handler(tlsConn)
+	})
+}
+
+func (l *Lockdownd) serverTLSConn(conn net.Conn) (*tls.Conn, error) {
+	l.mux.Lock()
+	deviceCert := l.deviceCert
+	l.mux.Unlock()
+	if deviceCert == nil {
+		return nil, errors.New("device is not paired")
+	}
+	cert, err := tls.X509KeyPair(deviceCert, privateKeyToPEM(l.deviceKey))
+	if err != nil {
+		return nil, err
+	}
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.NoClientCert,
+		//TLS 1.3 sends session tickets after the handshake, which would break services that
+		//only use SSL for the handshake and then go back to plaintext
+		MaxVersion:             tls.VersionTLS12,
+		SessionTicketsDisabled: true,
+	}
+	tlsConn := tls.Server(conn, conf)
+	return tlsConn, tlsConn.Handshake()
+}
+
+func (l *Lockdownd) handleConnection(rawConn net.Conn) {
+	codec := ios.NewPlistCodec()
+	var conn net.Conn = rawConn
+	var tlsConn *tls.Conn
+	for {
+		payload, err := codec.Decode(conn)
+		if err != nil {
+			if tlsConn != nil && errors.Is(err, io.EOF) {
+				//the host disabled SSL after StopSession, answer with our close_notify and continue in plaintext
+				tlsConn.CloseWrite()
+				//CloseWrite sets the write deadline to now, undo that to keep using the connection
+				rawConn.SetWriteDeadline(time.Time{})
+				conn, tlsConn = rawConn, nil
+				continue
+			}
+			return
+		}
+		var req lockdownRequest
+		_, err = plist.Unmarshal(payload, &req)
+		if err != nil {
+			log.Debugf("usbmuxsim: failed decoding lockdown request %x: %v", payload, err)
+			return
+		}
+		log.Tracef("usbmuxsim: lockdown request %s", req.Request)
+		response, enableSSL := l.handleRequest(req)
+		msg, _ := codec.Encode(response)
+		if _, err := conn.Write(msg); err != nil {
+			return
+		}
+		if enableSSL {
+			tlsConn, err = l.serverTLSConn(rawConn)
+			if err != nil {
+				log.Debugf("usbmuxsim: lockdown ssl handshake failed: %v", err)
+				return
+			}
+			conn = tlsConn
+		}
+	}
+}
+
+//handleRequest returns the response for req and whether SSL has to be enabled after sending it
+func (l *Lockdownd) handleRequest(req lockdownRequest) (map[string]interface{}, bool) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	response := map[string]interface{}{"Request": req.Request}
+	switch req.Request {
+	case "QueryType":
+		response["Type"] = "com.apple.mobile.lockdown"
+	case "GetValue":
+		response["Key"] = req.Key
+		if req.Domain != "" {
+			response["Domain"] = req.Domain
+		}
+		values := l.profile.Values[req.Domain]
+		if req.Key == "" {
+			if values == nil {
+				values = map[string]interface{}{}
+			}
+			response["Value"] = values
+			break
+		}
+		value, ok := values[req.Key]
+		if !ok {
+			response["Error"] = "MissingValue"
+			break
+		}
+		response["Value"] = value
+	case "SetValue":
+		response["Key"] = req.Key
+		response["Domain"] = req.Domain
+		if l.profile.Values[req.Domain] == nil {
+			l.profile.Values[req.Domain] = map[string]interface{}{}
+		}
+		l.profile.Values[req.Domain][req.Key] = req.Value
+	case "StartSession":
+		if !l.hostIDs[req.HostID] {
+			response["Error"] = "InvalidHostID"
+			break
+		}
+		response["SessionID"] = strings.ToUpper(uuid.New().String())
+		response["EnableSessionSSL"] = true
+		return response, true
+	case "StopSession":
+	case "StartService":
+		service, ok := l.profile.Services[req.Service]
+		if !ok {
+			response["Error"] = "InvalidService"
+			break
+		}
+		response["Service"] = req.Service
+		response["Port"] = service.Port
+		response["EnableServiceSSL"] = service.EnableServiceSSL
+	case "Pair":
+		if l.pairingDialog {
+			response["Error"] = "PairingDialogResponsePending"
+			break
+		}
+		if err := l.verifyDeviceCertificate(req.PairRecord.DeviceCertificate); err != nil {
+			response["Error"] = "InvalidPairRecord"
+			log.Debugf("usbmuxsim: invalid device certificate: %v", err)
+			break
+		}
+		l.hostIDs[req.PairRecord.HostID] = true
+		l.deviceCert = req.PairRecord.DeviceCertificate
+		escrowBag := make([]byte, 32)
+		rand.Read(escrowBag)
+		response["EscrowBag"] = escrowBag
+	default:
+		response["Error"] = "InvalidRequest"
+	}
+	return response, false
+}
+
+//verifyDeviceCertificate checks that the certificate a host sent during pairing was created for our device key
+func (l *Lockdownd) verifyDeviceCertificate(certPEM []byte) error {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return errors.New("no PEM data found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return err
+	}
+	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok || !bytes.Equal(x509.MarshalPKCS1PublicKey(publicKey), x509.MarshalPKCS1PublicKey(&l.deviceKey.PublicKey)) {
+		return errors.New("certificate does not match the device key")
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package usbmuxsim_test
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/usbmuxsim"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProfile = `
+values:
+  "":
+    DeviceName: test device
+    ProductVersion: "14.7.1"
+    WiFiAddress: "aa:bb:cc:dd:ee:ff"
+  com.apple.international:
+    Language: en
+    Locale: en_US
+  com.apple.Accessibility:
+    AssistiveTouchEnabledByiTunes: 0
+services:
+  com.apple.echo:
+    port: 1234
+    enableServiceSSL: true
+`
+
+//startLockdownd starts a simulator with a single device running a fake lockdownd and points go-ios at it.
+func startLockdownd(t *testing.T, paired bool) (*usbmuxsim.Server, *usbmuxsim.Lockdownd, ios.DeviceEntry) {
+	profile, err := usbmuxsim.ParseYAMLProfile([]byte(testProfile))
+	require.NoError(t, err)
+	server := startServer(t, "tcp:127.0.0.1:0")
+	server.SetBUID("buid")
+	device := usbmuxsim.NewDevice("udid0")
+	lockdownd, err := usbmuxsim.NewLockdownd(device, profile)
+	require.NoError(t, err)
+	if paired {
+		record, err := lockdownd.GeneratePairRecord("buid")
+		require.NoError(t, err)
+		server.SetPairRecord("udid0", record)
+	}
+	server.Attach(device)
+	t.Setenv(ios.UsbmuxdSocketEnv, server.Socket())
+
+	entry, err := ios.GetDevice("udid0")
+	require.NoError(t, err)
+	return server, lockdownd, entry
+}
+
+func TestLockdownValues(t *testing.T) {
+	_, lockdownd, device := startLockdownd(t, true)
+
+	values, err := ios.GetValues(device)
+	require.NoError(t, err)
+	assert.Equal(t, "test device", values.Value.DeviceName)
+	assert.Equal(t, "udid0", values.Value.UniqueDeviceID)
+
+	version, err := ios.GetProductVersion(device)
+	require.NoError(t, err)
+	assert.Equal(t, "14.7.1", version.String())
+
+	language, err := ios.GetLanguage(device)
+	require.NoError(t, err)
+	assert.Equal(t, ios.LanguageConfiguration{Language: "en", Locale: "en_US"}, language)
+
+	require.NoError(t, ios.SetLanguage(device, ios.LanguageConfiguration{Language: "de", Locale: "de_DE"}))
+	language, err = ios.GetLanguage(device)
+	require.NoError(t, err)
+	assert.Equal(t, ios.LanguageConfiguration{Language: "de", Locale: "de_DE"}, language)
+
+	enabled, err := ios.GetAssistiveTouch(device)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+	require.NoError(t, ios.SetAssistiveTouch(device, true))
+	value, _ := lockdownd.Value("com.apple.Accessibility", "AssistiveTouchEnabledByiTunes")
+	assert.Equal(t, true, value)
+}
+
+func TestLockdownSessionWithoutPairing(t *testing.T) {
+	startLockdownd(t, false)
+	device, err := ios.GetDevice("udid0")
+	require.NoError(t, err)
+	_, err = ios.ConnectLockdownWithSession(device)
+	assert.Error(t, err)
+}
+
+func TestLockdownDisableSessionSSL(t *testing.T) {
+	_, _, device := startLockdownd(t, true)
+	pairRecord, err := ios.ReadPairRecord("udid0")
+	require.NoError(t, err)
+
+	lockdown, err := ios.ConnectLockdownWithSession(device)
+	require.NoError(t, err)
+	defer lockdown.Close()
+	lockdown.StopSession()
+	lockdown.DisableSessionSSL()
+
+	_, err = lockdown.StartSession(pairRecord)
+	require.NoError(t, err)
+	version, err := lockdown.GetProductVersion()
+	require.NoError(t, err)
+	assert.Equal(t, "14.7.1", version)
+}
+
+func TestPair(t *testing.T) {
+	server, lockdownd, device := startLockdownd(t, false)
+
+	lockdownd.SetPairingDialogPending(true)
+	assert.Error(t, ios.Pair(device))
+	_, ok := server.PairRecord("udid0")
+	assert.False(t, ok)
+
+	lockdownd.SetPairingDialogPending(false)
+	require.NoError(t, ios.Pair(device))
+	record, ok := server.PairRecord("udid0")
+	require.True(t, ok)
+	assert.Equal(t, "buid", record.SystemBUID)
+	assert.Equal(t, "aa:bb:cc:dd:ee:ff", record.WiFiMACAddress)
+
+	version, err := ios.GetProductVersion(device)
+	require.NoError(t, err)
+	assert.Equal(t, "14.7.1", version.String())
+}
+
+func TestConnectToService(t *testing.T) {
+	_, lockdownd, device := startLockdownd(t, true)
+	lockdownd.HandleService("com.apple.echo", func(conn net.Conn) {
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte(line))
+	})
+
+	conn, err := ios.ConnectToService(device, "com.apple.echo")
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.Send([]byte("hello\n")))
+	line, err := bufio.NewReader(conn.Reader()).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", line)
+
+	_, err = ios.ConnectToService(device, "com.apple.unknown")
+	assert.Error(t, err)
+}
+
+func TestLoadProfile(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "profile.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"values": {"": {"DeviceName": "json", "Count": 1, "Ratio": 0.5}}}`), 0644))
+	profile, err := usbmuxsim.LoadProfile(jsonPath)
+	require.NoError(t, err)
+	assert.Equal(t, "json", profile.Values[""]["DeviceName"])
+	assert.Equal(t, int64(1), profile.Values[""]["Count"])
+	assert.Equal(t, 0.5, profile.Values[""]["Ratio"])
+
+	yamlPath := filepath.Join(dir, "profile.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, []byte(testProfile), 0644))
+	profile, err = usbmuxsim.LoadProfile(yamlPath)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(1234), profile.Services["com.apple.echo"].Port)
+}
+
+func TestLockdowndsDoNotShareProfile(t *testing.T) {
+	profile := usbmuxsim.DefaultProfile()
+	first, err := usbmuxsim.NewLockdownd(usbmuxsim.NewDevice("udid0"), profile)
+	require.NoError(t, err)
+	second, err := usbmuxsim.NewLockdownd(usbmuxsim.NewDevice("udid1"), profile)
+	require.NoError(t, err)
+
+	udid, _ := first.Value("", "UniqueDeviceID")
+	assert.Equal(t, "udid0", udid)
+	udid, _ = second.Value("", "UniqueDeviceID")
+	assert.Equal(t, "udid1", udid)
+	firstKey, _ := first.Value("", "DevicePublicKey")
+	secondKey, _ := second.Value("", "DevicePublicKey")
+	assert.NotEqual(t, firstKey, secondKey)
+	_, ok := profile.Values[""]["UniqueDeviceID"]
+	assert.False(t, ok)
+}
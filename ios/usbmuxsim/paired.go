@@ -0,0 +1,38 @@
+package usbmuxsim
+
+import (
+	"testing"
+
+	"github.com/danielpaulus/go-ios/ios"
+)
+
+//StartPairedDevice starts a Server on a local tcp socket and attaches a Device with the given udid. The device runs
+//a Lockdownd with the DefaultProfile that trusts the pair record stored on the server, and go-ios is pointed at the
+//server with the USBMUXD_SOCKET_ADDRESS env variable. Services can be registered on the returned Lockdownd at any
+//time. The server is closed when the test ends.
+func StartPairedDevice(t testing.TB, udid string) (*Server, *Lockdownd, ios.DeviceEntry) {
+	t.Helper()
+	server := NewServer()
+	err := server.Start("tcp:127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed starting usbmuxsim: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	device := NewDevice(udid)
+	lockdownd, err := NewLockdownd(device, DefaultProfile())
+	if err != nil {
+		t.Fatalf("failed creating lockdownd: %v", err)
+	}
+	record, err := lockdownd.GeneratePairRecord("buid")
+	if err != nil {
+		t.Fatalf("failed generating pair record: %v", err)
+	}
+	server.SetPairRecord(udid, record)
+	server.Attach(device)
+	t.Setenv(ios.UsbmuxdSocketEnv, server.Socket())
+	entry, err := ios.GetDevice(udid)
+	if err != nil {
+		t.Fatalf("failed getting simulated device: %v", err)
+	}
+	return server, lockdownd, entry
+}
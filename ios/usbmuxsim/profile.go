@@ -0,0 +1,128 @@
+package usbmuxsim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+//ServiceProfile describes how a fake lockdownd answers StartService requests for a service.
+type ServiceProfile struct {
+	Port             uint16 `json:"port" yaml:"port"`
+	EnableServiceSSL bool   `json:"enableServiceSSL" yaml:"enableServiceSSL"`
+	//HandshakeOnly has to be set for DTX based services that only use SSL for the handshake
+	HandshakeOnly bool `json:"handshakeOnly" yaml:"handshakeOnly"`
+}
+
+//Profile contains the data a fake lockdownd serves. Values maps lockdown domains to key value pairs,
+//values for requests without a domain are stored under the empty domain "".
+type Profile struct {
+	Values   map[string]map[string]interface{} `json:"values" yaml:"values"`
+	Services map[string]ServiceProfile         `json:"services" yaml:"services"`
+}
+
+//DefaultProfile returns a Profile with the basic values go-ios needs for most commands.
+func DefaultProfile() Profile {
+	return Profile{
+		Values: map[string]map[string]interface{}{
+			"": {
+				"DeviceName":     "Simulated iPhone",
+				"DeviceClass":    "iPhone",
+				"ProductType":    "iPhone12,1",
+				"ProductVersion": "15.0",
+				"BuildVersion":   "19A346",
+				"WiFiAddress":    "00:00:00:00:00:00",
+			},
+		},
+		Services: map[string]ServiceProfile{},
+	}
+}
+
+//LoadProfile reads a Profile from a JSON file or a YAML file if the file ends with .yaml or .yml
+func LoadProfile(path string) (Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Profile{}, err
+	}
+	ext := filepath.Ext(path)
+	if ext == ".yaml" || ext == ".yml" {
+		return ParseYAMLProfile(data)
+	}
+	return ParseJSONProfile(data)
+}
+
+//ParseJSONProfile parses a Profile from JSON. Integer numbers are kept as integers so they end up
+//as plist integers and not reals.
+func ParseJSONProfile(data []byte) (Profile, error) {
+	var profile Profile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&profile)
+	if err != nil {
+		return Profile{}, fmt.Errorf("failed parsing json profile: %w", err)
+	}
+	for _, values := range profile.Values {
+		for k, v := range values {
+			values[k] = convertJSONNumbers(v)
+		}
+	}
+	return profile.normalize(), nil
+}
+
+//ParseYAMLProfile parses a Profile from YAML.
+func ParseYAMLProfile(data []byte) (Profile, error) {
+	var profile Profile
+	err := yaml.Unmarshal(data, &profile)
+	if err != nil {
+		return Profile{}, fmt.Errorf("failed parsing yaml profile: %w", err)
+	}
+	return profile.normalize(), nil
+}
+
+//normalize returns a copy of p with all maps initialized. The maps are copied, so Lockdownds created
+//from the same Profile do not share values.
+func (p Profile) normalize() Profile {
+	values := make(map[string]map[string]interface{}, len(p.Values)+1)
+	for domain, domainValues := range p.Values {
+		copied := make(map[string]interface{}, len(domainValues))
+		for k, v := range domainValues {
+			copied[k] = v
+		}
+		values[domain] = copied
+	}
+	if values[""] == nil {
+		values[""] = map[string]interface{}{}
+	}
+	services := make(map[string]ServiceProfile, len(p.Services))
+	for name, service := range p.Services {
+		services[name] = service
+	}
+	return Profile{Values: values, Services: services}
+}
+
+func convertJSONNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, inner := range v {
+			v[key] = convertJSONNumbers(inner)
+		}
+		return v
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = convertJSONNumbers(inner)
+		}
+		return v
+	default:
+		return value
+	}
+}
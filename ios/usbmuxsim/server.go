@@ -0,0 +1,323 @@
+//Package usbmuxsim contains an in-process usbmuxd simulator. It serves the usbmuxd plist protocol
+//on a unix or tcp socket and lets tests attach and detach fake devices, so the ios package can be
+//tested without a real device. Point go-ios at the simulator using the USBMUXD_SOCKET_ADDRESS env variable
+//or by dialing Server.Socket() directly.
+package usbmuxsim
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/danielpaulus/go-ios/ios"
+	log "github.com/sirupsen/logrus"
+	"howett.net/plist"
+)
+
+//request contains all fields of the usbmuxd requests the simulator understands
+type request struct {
+	MessageType    string
+	DeviceID       int
+	PortNumber     uint16
+	PairRecordID   string
+	PairRecordData []byte
+}
+
+type buidResponse struct {
+	BUID string
+}
+
+//Server is a fake usbmuxd. Create it with NewServer, start it with Start and
+//attach devices with Attach.
+type Server struct {
+	network      string
+	address      string
+	listener     net.Listener
+	buid         string
+	devices      []*Device
+	nextDeviceID int
+	pairRecords  map[string][]byte
+	listeners    map[*ios.UsbMuxConnection]struct{}
+	conns        map[net.Conn]struct{}
+	mux          sync.Mutex
+	wg           sync.WaitGroup
+}
+
+//NewServer creates a new Server without any devices or pair records.
+func NewServer() *Server {
+	return &Server{
+		buid:         "00000000-0000-0000-0000-000000000000",
+		nextDeviceID: 1,
+		pairRecords:  map[string][]byte{},
+		listeners:    map[*ios.UsbMuxConnection]struct{}{},
+		conns:        map[net.Conn]struct{}{},
+	}
+}
+
+//Start listens on the given socket and serves connections in the background until Close is called.
+//The socket has the format "network:address" f.ex. "unix:/tmp/usbmuxd" or "tcp:127.0.0.1:0".
+func (s *Server) Start(socket string) error {
+	pos := strings.Index(socket, ":")
+	if pos < 0 {
+		return fmt.Errorf("invalid socket: %s", socket)
+	}
+	network, address := socket[0:pos], socket[pos+1:]
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return fmt.Errorf("usbmuxsim: failed listening on %s with err: %w", socket, err)
+	}
+	s.network = network
+	s.address = listener.Addr().String()
+	s.listener = listener
+	s.wg.Add(1)
+	go s.acceptLoop()
+	return nil
+}
+
+//Socket returns the address the server listens on in the same format Start accepts. It can be used as
+//value for the USBMUXD_SOCKET_ADDRESS env variable.
+func (s *Server) Socket() string {
+	return s.network + ":" + s.address
+}
+
+//Close stops the server and closes all open connections.
+func (s *Server) Close() error {
+	if s.listener == nil {
+		return nil
+	}
+	err := s.listener.Close()
+	s.mux.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mux.Unlock()
+	s.wg.Wait()
+	if s.network == "unix" {
+		os.Remove(s.address)
+	}
+	return err
+}
+
+//SetBUID sets the host BUID returned for ReadBUID requests.
+func (s *Server) SetBUID(buid string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.buid = buid
+}
+
+//SetPairRecord stores the pair record for udid so it will be returned for ReadPairRecord requests.
+func (s *Server) SetPairRecord(udid string, pairRecord ios.PairRecord) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.pairRecords[udid] = ios.ToPlistBytes(pairRecord)
+}
+
+//DeletePairRecord removes the pair record of udid, so clients get ios.MuxResultBadDevice for ReadPairRecord requests.
+func (s *Server) DeletePairRecord(udid string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.pairRecords, udid)
+}
+
+//PairRecord returns the pair record stored for udid, either set with SetPairRecord or saved by a client
+//with a SavePairRecord request.
+func (s *Server) PairRecord(udid string) (ios.PairRecord, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	data, ok := s.pairRecords[udid]
+	if !ok {
+		return ios.PairRecord{}, false
+	}
+	return ios.PairRecordfromBytes(data), true
+}
+
+//Attach adds the device to the server, assigns a DeviceID to it and sends an Attached message
+//to all clients that issued a Listen command. It returns the DeviceID.
+func (s *Server) Attach(device *Device) int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	device.deviceID = s.nextDeviceID
+	s.nextDeviceID++
+	s.devices = append(s.devices, device)
+	s.notifyListeners(device.attachedMessage())
+	return device.deviceID
+}
+
+//Detach removes the device with the given udid and sends a Detached message to all clients that
+//issued a Listen command. If several devices have the udid, the one attached first is removed.
+//It returns false if no such device was attached.
+func (s *Server) Detach(udid string) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for i, device := range s.devices {
+		if device.udid != udid {
+			continue
+		}
+		s.devices = append(s.devices[:i], s.devices[i+1:]...)
+		s.notifyListeners(ios.AttachedMessage{MessageType: "Detached", DeviceID: device.deviceID})
+		return true
+	}
+	return false
+}
+
+//Devices returns DeviceEntries for all currently attached devices
+func (s *Server) Devices() []ios.DeviceEntry {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	result := make([]ios.DeviceEntry, len(s.devices))
+	for i, device := range s.devices {
+		result[i] = device.deviceEntry()
+	}
+	return result
+}
+
+//notifyListeners has to be called with s.mux locked
+func (s *Server) notifyListeners(msg ios.AttachedMessage) {
+	for muxConn := range s.listeners {
+		err := muxConn.SendMuxMessage(buildMuxMessage(0, msg))
+		if err != nil {
+			log.Debugf("usbmuxsim: failed notifying listener: %v", err)
+			muxConn.Close()
+			delete(s.listeners, muxConn)
+		}
+	}
+}
+
+func (s *Server) deviceByID(deviceID int) (*Device, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, device := range s.devices {
+		if device.deviceID == deviceID {
+			return device, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mux.Lock()
+		s.conns[conn] = struct{}{}
+		s.mux.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handleConnection(conn)
+			s.mux.Lock()
+			delete(s.conns, conn)
+			s.mux.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+func (s *Server) handleConnection(conn net.Conn) {
+	muxConn := ios.NewUsbMuxConnection(ios.NewDeviceConnectionWithConn(conn))
+	for {
+		msg, err := muxConn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req request
+		decoder := plist.NewDecoder(bytes.NewReader(msg.Payload))
+		err = decoder.Decode(&req)
+		if err != nil {
+			log.Debugf("usbmuxsim: failed decoding request %x: %v", msg.Payload, err)
+			return
+		}
+		tag := msg.Header.Tag
+		log.Tracef("usbmuxsim: received %s", req.MessageType)
+		switch req.MessageType {
+		case "ListDevices":
+			err = muxConn.SendMuxMessage(buildMuxMessage(tag, ios.DeviceList{DeviceList: s.Devices()}))
+		case "ReadBUID":
+			s.mux.Lock()
+			buid := s.buid
+			s.mux.Unlock()
+			err = muxConn.SendMuxMessage(buildMuxMessage(tag, buidResponse{BUID: buid}))
+		case "ReadPairRecord":
+			s.mux.Lock()
+			data, ok := s.pairRecords[req.PairRecordID]
+			s.mux.Unlock()
+			if !ok {
+				err = sendResult(muxConn, tag, ios.MuxResultBadDevice)
+				break
+			}
+			err = muxConn.SendMuxMessage(buildMuxMessage(tag, ios.PairRecordData{PairRecordData: data}))
+		case "SavePairRecord":
+			s.mux.Lock()
+			s.pairRecords[req.PairRecordID] = req.PairRecordData
+			s.mux.Unlock()
+			err = sendResult(muxConn, tag, ios.MuxResultOK)
+		case "Listen":
+			s.handleListen(tag, muxConn)
+			return
+		case "Connect":
+			s.handleConnect(tag, req, muxConn, conn)
+			return
+		default:
+			err = sendResult(muxConn, tag, ios.MuxResultBadCommand)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) handleListen(tag uint32, muxConn *ios.UsbMuxConnection) {
+	if sendResult(muxConn, tag, ios.MuxResultOK) != nil {
+		return
+	}
+	s.mux.Lock()
+	for _, device := range s.devices {
+		if muxConn.SendMuxMessage(buildMuxMessage(0, device.attachedMessage())) != nil {
+			s.mux.Unlock()
+			return
+		}
+	}
+	s.listeners[muxConn] = struct{}{}
+	s.mux.Unlock()
+
+	//Listen connections never receive anything, so this blocks until the connection is closed
+	_, _ = muxConn.ReadMessage()
+	s.mux.Lock()
+	delete(s.listeners, muxConn)
+	s.mux.Unlock()
+}
+
+func (s *Server) handleConnect(tag uint32, req request, muxConn *ios.UsbMuxConnection, conn net.Conn) {
+	device, ok := s.deviceByID(req.DeviceID)
+	if !ok {
+		sendResult(muxConn, tag, ios.MuxResultBadDevice)
+		return
+	}
+	//clients send the port in network byte order
+	port := ios.Ntohs(req.PortNumber)
+	handler, ok := device.serviceHandler(port)
+	if !ok {
+		sendResult(muxConn, tag, ios.MuxResultConnRefused)
+		return
+	}
+	if sendResult(muxConn, tag, ios.MuxResultOK) != nil {
+		return
+	}
+	handler(conn)
+}
+
+func sendResult(muxConn *ios.UsbMuxConnection, tag uint32, number uint32) error {
+	return muxConn.SendMuxMessage(buildMuxMessage(tag, ios.MuxResponse{MessageType: "Result", Number: number}))
+}
+
+func buildMuxMessage(tag uint32, data interface{}) ios.UsbMuxMessage {
+	payload := ios.ToPlistBytes(data)
+	header := ios.UsbMuxHeader{Length: 16 + uint32(len(payload)), Request: 8, Version: 1, Tag: tag}
+	return ios.UsbMuxMessage{Header: header, Payload: payload}
+}
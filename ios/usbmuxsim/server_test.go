@@ -0,0 +1,137 @@
+package usbmuxsim_test
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/usbmuxsim"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startServer(t *testing.T, socket string) *usbmuxsim.Server {
+	server := usbmuxsim.NewServer()
+	require.NoError(t, server.Start(socket))
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func connect(t *testing.T, server *usbmuxsim.Server) *ios.UsbMuxConnection {
+	deviceConn, err := ios.NewDeviceConnection(server.Socket())
+	require.NoError(t, err)
+	muxConn := ios.NewUsbMuxConnection(deviceConn)
+	t.Cleanup(muxConn.Close)
+	return muxConn
+}
+
+func TestListDevices(t *testing.T) {
+	for _, socket := range []string{"tcp:127.0.0.1:0", "unix:" + filepath.Join(t.TempDir(), "usbmuxd")} {
+		server := startServer(t, socket)
+		server.Attach(usbmuxsim.NewDevice("udid0"))
+		server.Attach(usbmuxsim.NewDevice("udid1"))
+
+		muxConn := connect(t, server)
+		list, err := muxConn.ListDevices()
+		require.NoError(t, err)
+		assert.Equal(t, "udid0\nudid1\n", list.String())
+		assert.Equal(t, 2, list.DeviceList[1].DeviceID)
+
+		server.Detach("udid0")
+		list, err = muxConn.ListDevices()
+		require.NoError(t, err)
+		assert.Equal(t, "udid1\n", list.String())
+	}
+}
+
+func TestListen(t *testing.T) {
+	server := startServer(t, "tcp:127.0.0.1:0")
+	server.Attach(usbmuxsim.NewDevice("udid0"))
+
+	muxConn := connect(t, server)
+	receiver, err := muxConn.Listen()
+	require.NoError(t, err)
+
+	msg, err := receiver()
+	require.NoError(t, err)
+	assert.True(t, msg.DeviceAttached())
+	assert.Equal(t, "udid0", msg.Properties.SerialNumber)
+
+	deviceID := server.Attach(usbmuxsim.NewDevice("udid1"))
+	msg, err = receiver()
+	require.NoError(t, err)
+	assert.True(t, msg.DeviceAttached())
+	assert.Equal(t, deviceID, msg.DeviceID)
+
+	assert.True(t, server.Detach("udid1"))
+	msg, err = receiver()
+	require.NoError(t, err)
+	assert.True(t, msg.DeviceDetached())
+	assert.Equal(t, deviceID, msg.DeviceID)
+
+	assert.False(t, server.Detach("udid1"))
+}
+
+func TestPairRecordAndBuid(t *testing.T) {
+	server := startServer(t, "tcp:127.0.0.1:0")
+	server.SetBUID("buid")
+	server.SetPairRecord("udid0", ios.PairRecord{HostID: "host", SystemBUID: "buid"})
+
+	muxConn := connect(t, server)
+	buid, err := muxConn.ReadBuid()
+	require.NoError(t, err)
+	assert.Equal(t, "buid", buid)
+
+	record, err := muxConn.ReadPair("udid0")
+	require.NoError(t, err)
+	assert.Equal(t, "host", record.HostID)
+
+	_, err = muxConn.ReadPair("unknown")
+	assert.Error(t, err)
+}
+
+func TestConnect(t *testing.T) {
+	server := startServer(t, "tcp:127.0.0.1:0")
+	device := usbmuxsim.NewDevice("udid0")
+	device.HandleService(1234, func(conn net.Conn) {
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte(line))
+	})
+	deviceID := server.Attach(device)
+
+	muxConn := connect(t, server)
+	assert.Error(t, muxConn.Connect(deviceID+1, 1234))
+
+	muxConn = connect(t, server)
+	assert.Error(t, muxConn.Connect(deviceID, 4321))
+
+	muxConn = connect(t, server)
+	require.NoError(t, muxConn.Connect(deviceID, 1234))
+	deviceConn := muxConn.ReleaseDeviceConnection()
+	defer deviceConn.Close()
+	require.NoError(t, deviceConn.Send([]byte("hello\n")))
+	line, err := bufio.NewReader(deviceConn.Reader()).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", line)
+}
+
+func TestGetDevice(t *testing.T) {
+	server := startServer(t, "tcp:127.0.0.1:0")
+	server.Attach(usbmuxsim.NewDevice("udid0"))
+	server.Attach(usbmuxsim.NewDevice("udid1"))
+	t.Setenv(ios.UsbmuxdSocketEnv, server.Socket())
+	t.Setenv("udid", "")
+
+	device, err := ios.GetDevice("")
+	require.NoError(t, err)
+	assert.Equal(t, "udid0", device.Properties.SerialNumber)
+
+	device, err = ios.GetDevice("udid1")
+	require.NoError(t, err)
+	assert.Equal(t, "udid1", device.Properties.SerialNumber)
+
+	_, err = ios.GetDevice("unknown")
+	assert.Error(t, err)
+}
@@ -73,7 +73,7 @@ func GetDevice(udid string) (DeviceEntry, error) {
 	}
 	if udid == "" {
 		if len(deviceList.DeviceList) == 0 {
-			return DeviceEntry{}, errors.New("no iOS devices are attached to this host")
+			return DeviceEntry{}, fmt.Errorf("no iOS devices are attached to this host: %w", ErrDeviceNotFound)
 		}
 		log.WithFields(log.Fields{"udid": deviceList.DeviceList[0].Properties.SerialNumber}).
 			Info("no udid specified using first device in list")
@@ -84,7 +84,7 @@ func GetDevice(udid string) (DeviceEntry, error) {
 			return device, nil
 		}
 	}
-	return DeviceEntry{}, fmt.Errorf("Device '%s' not found. Is it attached to the machine?", udid)
+	return DeviceEntry{}, fmt.Errorf("Device '%s' not found. Is it attached to the machine? %w", udid, ErrDeviceNotFound)
 }
 
 //PathExists is used to determine whether the path folder exists
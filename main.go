@@ -5,7 +5,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
 	"path"
 	"path/filepath"
 	"runtime"
@@ -15,6 +17,7 @@ import (
 	"syscall"
 
 	"github.com/danielpaulus/go-ios/ios/afc"
+	"github.com/danielpaulus/go-ios/ios/afcfs"
 
 	"github.com/danielpaulus/go-ios/ios/crashreport"
 	"github.com/danielpaulus/go-ios/ios/testmanagerd"
@@ -33,6 +36,7 @@ import (
 	"github.com/danielpaulus/go-ios/ios/accessibility"
 	"github.com/danielpaulus/go-ios/ios/debugproxy"
 	"github.com/danielpaulus/go-ios/ios/diagnostics"
+	"github.com/danielpaulus/go-ios/ios/events"
 	"github.com/danielpaulus/go-ios/ios/forward"
 	"github.com/danielpaulus/go-ios/ios/installationproxy"
 	"github.com/danielpaulus/go-ios/ios/instruments"
@@ -43,6 +47,7 @@ import (
 	syslog "github.com/danielpaulus/go-ios/ios/syslog"
 	"github.com/docopt/docopt-go"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
 )
 
 // JSONdisabled enables or disables output in JSON format
@@ -78,19 +83,32 @@ Usage:
   ios image list [options]
   ios image mount [--path=<imagepath>] [options]
   ios image auto [--basedir=<where_dev_images_are_stored>] [options]
-  ios syslog [options]
+  ios syslog [--process=<processName>] [--pid=<processID>] [--match=<regex>] [--level=<level>] [--json] [options]
+  ios netstat [--process-stats] [--interval=<duration>] [options]
+  ios perf [--pid=<processID>] [--bundleid=<bundleid>] [--interval=<duration>] [options]
+  ios fps [options]
+  ios energy [--pid=<processID>] [--bundleid=<bundleid>] [--interval=<duration>] [options]
+  ios battery [--watch] [--interval=<duration>] [options]
+  ios events [--type=<type>]... [--notification=<name>]... [options]
+  ios notify post <notification>... [options]
+  ios notify observe <notification>... [options]
   ios screenshot [options] [--output=<outfile>]
   ios instruments notifications [options]
   ios crash ls [<pattern>] [options]
   ios crash cp <srcpattern> <target> [options]
   ios crash rm <cwd> <pattern> [options]
+  ios crash parse <file> [options]
+  ios crash symbolicate <file> --dsym=<path>... [options]
+  ios crash watch [--bundleid=<bundleid>] [--interval=<duration>] [--download=<dir>] [--parse] [options]
   ios devicename [options] 
   ios date [options]
   ios devicestate list [options]
   ios devicestate enable <profileTypeId> <profileId> [options]
   ios lang [--setlocale=<locale>] [--setlang=<newlang>] [options]
   ios mobilegestalt <key>... [--plist] [options]
+  ios ioreg [--plane=<plane>] [--name=<entryName>] [--class=<entryClass>] [--tree] [--properties] [options]
   ios diagnostics list [options]
+  ios diagnostics <domain> [options]
   ios profile list [options]
   ios profile remove <profileName> [options]
   ios profile add <profileFile> [--p12file=<orgid>] [--password=<p12password>] [options]
@@ -102,7 +120,7 @@ Usage:
   ios forward [options] <hostPort> <targetPort>
   ios dproxy [--binary]
   ios readpair [options]
-  ios pcap [options] [--pid=<processID>] [--process=<processName>]
+  ios pcap [options] [--pid=<processID>] [--process=<processName>] [--filter=<expression>] [--output=<outfile>] [--pcapng] [--rotate-size=<megabytes>] [--rotate-duration=<duration>]
   ios convert --path=<ipaOrAppFolder> [options]
   ios winfo --path=<test.wzip> [options]
   ios wextract --path=<test.wzip> --item=<file> [options]
@@ -115,8 +133,13 @@ Usage:
   ios runwda [--bundleid=<bundleid>] [--testrunnerbundleid=<testbundleid>] [--xctestconfig=<xctestconfig>] [--arg=<a>]... [--env=<e>]... [options]
   ios ax [options]
   ios debug [options] [--stop-at-entry] <app_path>
-  ios fsync [options] [--bundleID=<bundleid>] (ls | rm | cat | stat | tree | rmtree | mkdir | pull | push) [--path=<targetPath>] [--src=<srcPath>] [--dst=<dstPath>]
-  ios reboot [options]
+  ios fsync [options] [--bundleID=<bundleid>] (ls | rm | cat | stat | tree | rmtree | mkdir | pull | push) [--path=<targetPath>] [--src=<srcPath>] [--dst=<dstPath>] [--progress] [--resume] [--verify]
+  ios fsync [options] [--bundleID=<bundleid>] sync --src=<srcPath> --dst=<dstPath> --direction=<direction> [--delete] [--dry-run] [--include=<glob>]... [--exclude=<glob>]... [--progress] [--verify]
+  ios mount <mountpoint> [--bundleid=<bundleid>] [options]
+  ios fsserve [--addr=<address>] [--bundleid=<bundleid>] [--auth=<user:password>] [options]
+  ios reboot [options] [--wait] [--timeout=<duration>]
+  ios shutdown [options]
+  ios sleep [options]
   ios -h | --help
   ios --version | version [options]
   ios setlocation [options] [--lat=<lat>] [--lon=<lon>]
@@ -145,13 +168,39 @@ The commands work as following:
    ios image auto [--basedir=<where_dev_images_are_stored>] [options] Automatically download correct dev image from the internets and mount it.
    >                                                                  You can specify a dir where images should be cached.
    >                                                                  The default is the current dir. 
-   ios syslog [options]                                               Prints a device's log output
+   ios syslog [--process=<processName>] [--pid=<processID>] [--match=<regex>] [--level=<level>] [--json] [options] Prints a device's log output.
+   >                                                                  Use --process, --pid, --match (a regex on the message) and --level (minimum level, f.ex. warning) to filter.
+   >                                                                  --json prints one JSON object with timestamp, process, pid, subsystem, level and message per line
+   >                                                                  instead of {"msg": <line>}, use --nojson for plain lines.
+   ios netstat [--process-stats] [--interval=<duration>] [options]    Streams network interfaces, connections and their traffic counters of all processes using instruments.
+   >                                                                  The device does not report closed connections, connections without traffic for 30 seconds
+   >                                                                  are reported as connectionExpired instead. Needs a mounted developer image.
+   >                                                                  --process-stats prints the summed up traffic and open and expired connections per process
+   >                                                                  every <duration> (f.ex. 500ms or 5s, default 1s) instead.
+   ios perf [--pid=<processID>] [--bundleid=<bundleid>] [--interval=<duration>] [options] Streams system and per process CPU, memory, disk and thread usage sampled by instruments sysmontap.
+   >                                                                  Use --pid or --bundleid to only include one process. Samples are taken every <duration> (f.ex. 500ms, default 1s).
+   ios fps [options]                                                  Streams the frame rate, GPU device, renderer and tiler utilization per second using instruments.
+   ios energy [--pid=<processID>] [--bundleid=<bundleid>] [--interval=<duration>] [options] Streams the CPU, GPU, network, location and display energy impact of a running process
+   >                                                                  together with the battery power draw in watts every <duration> (f.ex. 500ms, default 1s).
+   ios battery [--watch] [--interval=<duration>] [options]            Prints charge, cycle count, temperature, voltage, amperage, charging state and health of the battery.
+   >                                                                  --watch prints the battery values every <duration> (f.ex. 1m, default 10s) until interrupted.
+   ios events [--type=<type>]... [--notification=<name>]... [options] Streams attach and detach, lock, app install and uninstall, springboard start and app state events.
+   >                                                                  Select events with --type attached|detached|locked|lockStateChanged|appInstalled|appUninstalled|springboardStarted|notification|appState,
+   >                                                                  --notification adds Darwin notifications to observe. appState events need a mounted developer image.
+   ios notify post <notification>... [options]                        Posts Darwin notifications like com.apple.springboard.lockcomplete on the device.
+   ios notify observe <notification>... [options]                     Prints the given Darwin notifications whenever the device sends them until interrupted.
    ios screenshot [options] [--output=<outfile>]                      Takes a screenshot and writes it to the current dir or to <outfile>
    ios instruments notifications [options]                            Listen to application state notifications                                    
    ios crash ls [<pattern>] [options]                                 run "ios crash ls" to get all crashreports in a list, 
    >                                                                  or use a pattern like 'ios crash ls "*ips*"' to filter
    ios crash cp <srcpattern> <target> [options]                       copy "file pattern" to the target dir. Ex.: 'ios crash cp "*" "./crashes"'
    ios crash rm <cwd> <pattern> [options]                             remove file pattern from dir. Ex.: 'ios crash rm "." "*"' to delete everything
+   ios crash parse <file> [options]                                   parses a .crash or .ips report and prints exception, threads and binary images
+   ios crash symbolicate <file> --dsym=<path>... [options]            like parse, but resolves frames to functions, files and lines with the DWARF of dSYMs,
+   >                                                                  directories are searched for dSYMs. Ex.: 'ios crash symbolicate MyApp.ips --dsym=build/'
+   ios crash watch [--bundleid=<bundleid>] [--interval=<duration>] [--download=<dir>] [--parse] [options]
+   >                                                                  prints a JSON event for every crash report that appears on the device until interrupted,
+   >                                                                  optionally only of the app <bundleid>, downloaded to <dir> and parsed. Checks every <duration> (f.ex. 10s, default 2s).
    ios devicename [options]                                           Prints the devicename
    ios date [options]                                                 Prints the device date
    ios devicestate list [options]                                     Prints a list of all supported device conditions, like slow network, gpu etc.
@@ -161,7 +210,11 @@ The commands work as following:
    ios mobilegestalt <key>... [--plist] [options]                     Lets you query mobilegestalt keys. Standard output is json but if desired you can get
    >                                                                  it in plist format by adding the --plist param. 
    >                                                                  Ex.: "ios mobilegestalt MainScreenCanvasSizes ArtworkTraits --plist"
+   ios ioreg [--plane=<plane>] [--name=<entryName>] [--class=<entryClass>] [--tree] [--properties] [options] Dumps the IORegistry like ioreg on macOS. Without --name or --class, the tree of
+   >                                                                  <plane> (default IOService) is printed. Output is JSON, use --tree for the ioreg tree format and --properties to include properties in it.
+   >                                                                  Ex.: "ios ioreg --class=IOPMPowerSource", "ios ioreg --plane=IODeviceTree --tree"
    ios diagnostics list [options]                                     List diagnostic infos
+   ios diagnostics <domain> [options]                                 Prints the diagnostics of one domain: All, WiFi, GasGauge, NAND or HDMI.
    ios pair [--p12file=<orgid>] [--password=<p12password>] [options]  Pairs the device. If the device is supervised, specify the path to the p12 file 
    >                                                                  to pair without a trust dialog. Specify the password either with the argument or
    >                                                                  by setting the environment variable 'P12_PASSWORD'
@@ -192,7 +245,12 @@ The commands work as following:
    ios install --path=<ipaOrAppFolder> [--use-installproxy] [options]  Specify a .app folder or an installable ipa file that will be installed,
    >                                                                  --use-installproxy will use the installproxy instead of the zipconduit install.
    >                                                                  Note: zipconduit not support single file size > 4G.
-   ios pcap [options] [--pid=<processID>] [--process=<processName>]   Starts a pcap dump of network traffic, use --pid or --process to filter specific processes.
+   ios pcap [options] [--pid=<processID>] [--process=<processName>] [--filter=<expression>] [--output=<outfile>] [--pcapng] [--rotate-size=<megabytes>] [--rotate-duration=<duration>] Starts a pcap dump of network traffic, use --pid or --process to filter specific processes.
+   >                                                                  The dump is written to <outfile>, to stdout if <outfile> is "-" or stdout is a pipe, f.ex. "ios pcap | wireshark -k -i -",
+   >                                                                  or to dump-<timestamp>.pcap otherwise. --pcapng keeps interface, pid and process name of every packet as packet comment.
+   >                                                                  --filter takes a tcpdump style expression like "tcp port 443 and not host 10.0.0.1" with host, net, port, portrange,
+   >                                                                  src, dst, ip, ip6, arp, tcp, udp, icmp, icmp6, inbound, outbound, and, or, not and parentheses.
+   >                                                                  --rotate-size and --rotate-duration (f.ex. 10m) start a new numbered file when the current one is full.
    ios apps [--system] [--all]                                        Retrieves a list of installed applications. --system prints out preinstalled system apps. --all prints all apps, including system, user, and hidden apps.
    ios launch <bundleID>                                              Launch app with the bundleID on the device. Get your bundle ID from the apps command.
    ios kill (<bundleID> | --pid=<processID> | --process=<processName>) [options] Kill app with the specified bundleID, process id, or process name on the device.
@@ -201,9 +259,22 @@ The commands work as following:
    >                                                                  specify runtime args and env vars like --env ENV_1=something --env ENV_2=else  and --arg ARG1 --arg ARG2
    ios ax [options]                                                   Access accessibility inspector features. 
    ios debug [--stop-at-entry] <app_path>                             Start debug with lldb
-   ios fsync [options] [--bundleID=<bundleid>] (ls | rm | cat | stat | tree | rmtree | mkdir | pull | push) [--path=<targetPath>] [--src=<srcPath>] [--dst=<dstPath>]
+   ios fsync [options] [--bundleID=<bundleid>] (ls | rm | cat | stat | tree | rmtree | mkdir | pull | push) [--path=<targetPath>] [--src=<srcPath>] [--dst=<dstPath>] [--progress] [--resume] [--verify]
    > app file management
-   ios reboot [options]                                               Reboot the given device
+   >                                                                  pull and push print the transfer rate with --progress, continue partially transferred files with --resume
+   >                                                                  and compare SHA-256 checksums of source and destination afterwards with --verify.
+   ios fsync [options] [--bundleID=<bundleid>] sync --src=<srcPath> --dst=<dstPath> --direction=<direction> [--delete] [--dry-run] [--include=<glob>]... [--exclude=<glob>]... [--progress] [--verify]
+   >                                                                  Makes the directory --dst a copy of --src, --direction push copies from the host to the device and pull the other way around.
+   >                                                                  Only files with a different size or modification time are copied. --delete removes files that are not in --src,
+   >                                                                  --include and --exclude filter paths and file names with globs like *.json and --dry-run only prints the changes.
+   ios mount <mountpoint> [--bundleid=<bundleid>] [options]           Mounts the media directory in /afc, the crash reports in /crashreports and the app sandboxes in /apps
+   >                                                                  on <mountpoint> with FUSE until interrupted, only the Documents of the app with --bundleid. Linux only.
+   ios fsserve [--addr=<address>] [--bundleid=<bundleid>] [--auth=<user:password>] [options] Serves the same directories as mount with WebDAV and HTTP on --addr (default localhost:8080).
+   >                                                                  Directories can be browsed with a browser, --auth enables basic authentication.
+   ios reboot [options] [--wait] [--timeout=<duration>]              Reboot the given device. With --wait, returns only once the device reconnected
+   >                                                                  and springboard finished starting or fails after --timeout (default 5m).
+   ios shutdown [options]                                             Turns off the given device
+   ios sleep [options]                                                Puts the given device to sleep, which also locks it
    ios -h | --help                                                    Prints this screen.
    ios --version | version [options]                                  Prints the version
    ios setlocation [options] [--lat=<lat>] [--lon=<lon>]              Updates the location of the device to the provided by latitude and longitude coordinates. Example: setlocation --lat=40.730610 --lon=-73.935242
@@ -328,6 +399,10 @@ The commands work as following:
 		return
 	}
 
+	if crashParseCommand(arguments) {
+		return
+	}
+
 	udid, _ := arguments.String("--udid")
 	device, err := ios.GetDevice(udid)
 	exitIfError("error getting devicelist", err)
@@ -336,6 +411,10 @@ The commands work as following:
 		return
 	}
 
+	if ioregCommand(device, arguments) {
+		return
+	}
+
 	if deviceStateCommand {
 		if listCommand {
 			deviceState(device, true, false, "", "")
@@ -366,12 +445,24 @@ The commands work as following:
 	if b {
 		p, _ := arguments.String("--process")
 		i, _ := arguments.Int("--pid")
-		pcap.Pid = int32(i)
-		pcap.ProcName = p
-		err := pcap.Start(device)
-		if err != nil {
-			exitIfError("pcap failed", err)
+		output, _ := arguments.String("--output")
+		pcapng, _ := arguments.Bool("--pcapng")
+		filter := pcap.Filter{Pid: int32(i), ProcName: p}
+		if expression, _ := arguments.String("--filter"); expression != "" {
+			filter.Expression, err = pcap.ParseExpression(expression)
+			exitIfError("invalid --filter", err)
+		}
+		rotateSize, _ := arguments.Int("--rotate-size")
+		var rotateDuration time.Duration
+		if duration, _ := arguments.String("--rotate-duration"); duration != "" {
+			rotateDuration, err = time.ParseDuration(duration)
+			exitIfError("invalid --rotate-duration", err)
+		}
+		if rotateSize > 0 || rotateDuration > 0 {
+			runRotatingPcap(device, filter, output, pcapng, int64(rotateSize)*1024*1024, rotateDuration)
+			return
 		}
+		runPcap(device, filter, output, pcapng)
 		return
 	}
 
@@ -448,7 +539,83 @@ The commands work as following:
 
 	b, _ = arguments.Bool("syslog")
 	if b {
-		runSyslog(device)
+		processName, _ := arguments.String("--process")
+		pid, _ := arguments.Int("--pid")
+		match, _ := arguments.String("--match")
+		level, _ := arguments.String("--level")
+		filter, err := syslog.NewFilter(processName, pid, match, level)
+		exitIfError("invalid syslog filter", err)
+		parsed, _ := arguments.Bool("--json")
+		runSyslog(device, filter, parsed)
+		return
+	}
+
+	b, _ = arguments.Bool("netstat")
+	if b {
+		processStats, _ := arguments.Bool("--process-stats")
+		runNetstat(device, processStats, durationArgument(arguments, "--interval", time.Second))
+		return
+	}
+
+	b, _ = arguments.Bool("perf")
+	if b {
+		pid, _ := arguments.Int("--pid")
+		bundleID, _ := arguments.String("--bundleid")
+		processName := ""
+		if bundleID != "" {
+			processName = executableForBundleID(device, bundleID)
+		}
+		config := instruments.DefaultSysmontapConfig()
+		config.Interval = durationArgument(arguments, "--interval", config.Interval)
+		runPerf(device, config, uint64(pid), processName)
+		return
+	}
+
+	b, _ = arguments.Bool("fps")
+	if b {
+		runFps(device)
+		return
+	}
+
+	b, _ = arguments.Bool("battery")
+	if b {
+		watch, _ := arguments.Bool("--watch")
+		runBattery(device, watch, durationArgument(arguments, "--interval", 10*time.Second))
+		return
+	}
+
+	b, _ = arguments.Bool("events")
+	if b {
+		types, err := events.ParseTypes(arguments["--type"].([]string))
+		exitIfError("invalid --type", err)
+		runEvents(device, events.Config{Types: types, Notifications: arguments["--notification"].([]string)})
+		return
+	}
+
+	b, _ = arguments.Bool("notify")
+	if b {
+		notifications := arguments["<notification>"].([]string)
+		if post, _ := arguments.Bool("post"); post {
+			postNotifications(device, notifications)
+		}
+		if observe, _ := arguments.Bool("observe"); observe {
+			observeNotifications(device, notifications)
+		}
+		return
+	}
+
+	b, _ = arguments.Bool("energy")
+	if b {
+		pid, _ := arguments.Int("--pid")
+		bundleID, _ := arguments.String("--bundleid")
+		interval := durationArgument(arguments, "--interval", time.Second)
+		if bundleID != "" {
+			pid = int(pidForProcessName(device, executableForBundleID(device, bundleID)))
+		}
+		if pid <= 0 {
+			log.Fatal("please provide a --pid or --bundleid")
+		}
+		runEnergy(device, uint64(pid), interval)
 		return
 	}
 
@@ -502,7 +669,12 @@ The commands work as following:
 	}
 	b, _ = arguments.Bool("diagnostics")
 	if b {
-		printDiagnostics(device)
+		if listCommand {
+			printDiagnostics(device)
+			return
+		}
+		domain, _ := arguments.String("<domain>")
+		printDiagnosticsDomain(device, domain)
 		return
 	}
 
@@ -702,6 +874,13 @@ The commands work as following:
 
 	b, _ = arguments.Bool("reboot")
 	if b {
+		wait, _ := arguments.Bool("--wait")
+		if wait {
+			device, err = diagnostics.RebootAndWait(device, durationArgument(arguments, "--timeout", 5*time.Minute))
+			exitIfError("failed waiting for reboot", err)
+			log.WithFields(log.Fields{"udid": device.Properties.SerialNumber}).Info("device is ready")
+			return
+		}
 		err := diagnostics.Reboot(device)
 		if err != nil {
 			log.Error(err)
@@ -711,6 +890,42 @@ The commands work as following:
 		return
 	}
 
+	b, _ = arguments.Bool("shutdown")
+	if b {
+		err := diagnostics.Shutdown(device)
+		exitIfError("shutdown failed", err)
+		log.Info("ok")
+		return
+	}
+
+	b, _ = arguments.Bool("sleep")
+	if b {
+		err := diagnostics.Sleep(device)
+		exitIfError("sleep failed", err)
+		log.Info("ok")
+		return
+	}
+
+	b, _ = arguments.Bool("mount")
+	if b {
+		mountPoint, _ := arguments.String("<mountpoint>")
+		bundleID, _ := arguments.String("--bundleid")
+		mountDeviceFs(device, mountPoint, bundleID)
+		return
+	}
+
+	b, _ = arguments.Bool("fsserve")
+	if b {
+		addr, _ := arguments.String("--addr")
+		if addr == "" {
+			addr = "localhost:8080"
+		}
+		bundleID, _ := arguments.String("--bundleid")
+		auth, _ := arguments.String("--auth")
+		serveDeviceFs(device, addr, bundleID, auth)
+		return
+	}
+
 	b, _ = arguments.Bool("fsync")
 	if b {
 		bundleID, _ := arguments.String("--bundleID")
@@ -804,7 +1019,7 @@ The commands work as following:
 				}
 			}
 			dp = path.Join(dp, filepath.Base(sp))
-			err = afcService.Pull(sp, dp)
+			err = afcService.PullWithOptions(sp, dp, transferOptions(arguments))
 			exitIfError("fsync: pull failed", err)
 			return
 		}
@@ -813,14 +1028,120 @@ The commands work as following:
 		if b {
 			sp, _ := arguments.String("--src")
 			dp, _ := arguments.String("--dst")
-			err = afcService.Push(sp, dp)
+			err = afcService.PushWithOptions(sp, dp, transferOptions(arguments))
 			exitIfError("fsync: push failed", err)
 		}
+
+		b, _ = arguments.Bool("sync")
+		if b {
+			sp, _ := arguments.String("--src")
+			dp, _ := arguments.String("--dst")
+			direction, _ := arguments.String("--direction")
+			options := afc.SyncOptions{
+				Direction: afc.SyncDirection(direction),
+				Include:   arguments["--include"].([]string),
+				Exclude:   arguments["--exclude"].([]string),
+				Transfer:  transferOptions(arguments),
+			}
+			options.Delete, _ = arguments.Bool("--delete")
+			options.DryRun, _ = arguments.Bool("--dry-run")
+			options.Action = func(action afc.SyncAction) {
+				if JSONdisabled {
+					fmt.Printf("%s %s\n", action.Type, action.Path)
+				} else {
+					fmt.Println(convertToJSONString(action))
+				}
+			}
+			result, err := afcService.Sync(sp, dp, options)
+			exitIfError("fsync: sync failed", err)
+			if JSONdisabled {
+				fmt.Printf("copied %d files (%d bytes), created %d and deleted %d, %d files were unchanged\n",
+					result.Copied, result.Transferred, result.Created, result.Deleted, result.Unchanged)
+			} else {
+				fmt.Println(convertToJSONString(result))
+			}
+		}
 		afcService.Close()
 		return
 	}
 }
 
+//openDeviceFs returns the Documents of the app bundleID or all directories that afcfs.VirtualRootFs contains
+func openDeviceFs(device ios.DeviceEntry, bundleID string) (afero.Fs, error) {
+	if bundleID != "" {
+		return afc.NewHouseArrestDocumentFs(device, bundleID)
+	}
+	return afcfs.NewVfs(device)
+}
+
+func mountDeviceFs(device ios.DeviceEntry, mountPoint string, bundleID string) {
+	deviceFs, err := openDeviceFs(device, bundleID)
+	exitIfError("mount: connect afc service failed", err)
+	mounted, err := afcfs.Mount(deviceFs, mountPoint, afcfs.MountOptions{})
+	exitIfError("mount failed", err)
+	log.WithFields(log.Fields{"mountpoint": mountPoint}).Info("mounted, press Ctrl+C to unmount")
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for range c {
+			err := mounted.Unmount()
+			if err != nil {
+				log.Errorf("unmount failed, are files still in use? %v", err)
+			}
+		}
+	}()
+	mounted.Wait()
+	log.Info("unmounted")
+}
+
+func serveDeviceFs(device ios.DeviceEntry, addr string, bundleID string, auth string) {
+	deviceFs, err := openDeviceFs(device, bundleID)
+	exitIfError("fsserve: connect afc service failed", err)
+	options := afcfs.ServeOptions{}
+	if auth != "" {
+		credentials := strings.SplitN(auth, ":", 2)
+		if len(credentials) != 2 {
+			log.Fatal("--auth has to be user:password")
+		}
+		options.Username, options.Password = credentials[0], credentials[1]
+	}
+	log.WithFields(log.Fields{"addr": addr}).Info("serving device files with WebDAV and HTTP")
+	err = http.ListenAndServe(addr, afcfs.NewFileServer(deviceFs, options))
+	exitIfError("fsserve failed", err)
+}
+
+func ioregCommand(device ios.DeviceEntry, arguments docopt.Opts) bool {
+	b, _ := arguments.Bool("ioreg")
+	if b {
+		query := diagnostics.IORegistryQuery{}
+		query.Plane, _ = arguments.String("--plane")
+		query.EntryName, _ = arguments.String("--name")
+		query.EntryClass, _ = arguments.String("--class")
+		if query.Plane == "" && query.EntryName == "" && query.EntryClass == "" {
+			query.Plane = "IOService"
+		}
+		tree, _ := arguments.Bool("--tree")
+		properties, _ := arguments.Bool("--properties")
+
+		conn, err := diagnostics.New(device)
+		exitIfError("failed connecting to diagnostics", err)
+		defer conn.Close()
+		values, err := conn.IORegistry(query)
+		exitIfError("ioreg failed", err)
+		entry := diagnostics.NewIORegistryEntry(values)
+		if entry.Name == "" {
+			entry.Name = query.EntryName
+		}
+		if tree {
+			err = entry.WriteTree(os.Stdout, properties)
+			exitIfError("failed writing ioreg tree", err)
+			return true
+		}
+		fmt.Println(convertToJSONString(entry))
+	}
+	return b
+}
+
 func mobileGestaltCommand(device ios.DeviceEntry, arguments docopt.Opts) bool {
 	b, _ := arguments.Bool("mobilegestalt")
 	if b {
@@ -978,10 +1299,77 @@ func crashCommand(device ios.DeviceEntry, arguments docopt.Opts) bool {
 			err := crashreport.RemoveReports(device, cwd, pattern)
 			exitIfError("failed deleting crashreports", err)
 		}
+
+		watch, _ := arguments.Bool("watch")
+		if watch {
+			watchCrashReports(device, arguments)
+		}
 	}
 	return b
 }
 
+func watchCrashReports(device ios.DeviceEntry, arguments docopt.Opts) {
+	options := crashreport.WatchOptions{}
+	if bundleID, _ := arguments.String("--bundleid"); bundleID != "" {
+		options.Process = executableForBundleID(device, bundleID)
+	}
+	options.Interval = durationArgument(arguments, "--interval", crashreport.DefaultWatchInterval)
+	options.Download, _ = arguments.String("--download")
+	options.Parse, _ = arguments.Bool("--parse")
+	if options.Download != "" {
+		err := os.MkdirAll(options.Download, 0755)
+		exitIfError("failed creating download dir", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-c
+		cancel()
+	}()
+	log.WithField("process", options.Process).Info("watching for new crashreports")
+	err := crashreport.Watch(ctx, device, options, func(event crashreport.Event) {
+		if JSONdisabled {
+			fmt.Printf("%s %s %d\n", event.ModTime.Format(time.RFC3339), event.Path, event.Size)
+			if event.Report != nil {
+				fmt.Print(event.Report.String())
+			}
+			return
+		}
+		fmt.Println(convertToJSONString(event))
+	})
+	exitIfError("failed watching crashreports", err)
+}
+
+//crashParseCommand handles the crash subcommands that work on local files and need no device
+func crashParseCommand(arguments docopt.Opts) bool {
+	b, _ := arguments.Bool("crash")
+	parse, _ := arguments.Bool("parse")
+	symbolicate, _ := arguments.Bool("symbolicate")
+	if !b || !(parse || symbolicate) {
+		return false
+	}
+	file, _ := arguments.String("<file>")
+	report, err := crashreport.ParseReportFile(file)
+	exitIfError("failed parsing crashreport", err)
+	if symbolicate {
+		dsyms := arguments["--dsym"].([]string)
+		symbolicator, err := crashreport.NewSymbolicator(dsyms...)
+		exitIfError("failed loading dSYMs", err)
+		count := symbolicator.Symbolicate(report)
+		if count == 0 {
+			log.WithField("uuids", symbolicator.UUIDs()).Warn("no dSYM matched the binary images of the report")
+		}
+		log.Debugf("symbolicated %d frames", count)
+	}
+	if JSONdisabled {
+		fmt.Print(report.String())
+	} else {
+		fmt.Println(convertToJSONString(report))
+	}
+	return true
+}
+
 func deviceState(device ios.DeviceEntry, list bool, enable bool, profileTypeId string, profileId string) {
 	control, err := instruments.NewDeviceStateControl(device)
 	exitIfError("failed to connect to deviceStateControl", err)
@@ -1257,6 +1645,34 @@ func printDiagnostics(device ios.DeviceEntry) {
 	fmt.Println(convertToJSONString(values))
 }
 
+func printDiagnosticsDomain(device ios.DeviceEntry, domain string) {
+	for _, d := range diagnostics.Domains {
+		if strings.EqualFold(d, domain) {
+			domain = d
+		}
+	}
+	diagnosticsService, err := diagnostics.New(device)
+	exitIfError("Starting diagnostics service failed with", err)
+	defer diagnosticsService.Close()
+	var result interface{}
+	switch domain {
+	case diagnostics.DomainWiFi:
+		result, err = diagnosticsService.WiFi()
+	case diagnostics.DomainGasGauge:
+		result, err = diagnosticsService.GasGauge()
+	case diagnostics.DomainNAND:
+		result, err = diagnosticsService.NAND()
+	case diagnostics.DomainHDMI:
+		result, err = diagnosticsService.HDMI()
+	case diagnostics.DomainAll:
+		result, err = diagnosticsService.Query(domain)
+	default:
+		log.Fatalf("unknown diagnostics domain %s, use one of %v", domain, diagnostics.Domains)
+	}
+	exitIfError("getting diagnostics failed", err)
+	fmt.Println(convertToJSONString(result))
+}
+
 func printDeviceDate(device ios.DeviceEntry) {
 	allValues, err := ios.GetValues(device)
 	exitIfError("failed getting values", err)
@@ -1460,10 +1876,10 @@ func outputProcessListNoJSON(device ios.DeviceEntry, processes []instruments.Pro
 func startListening() {
 	go func() {
 		for {
-			deviceConn, err := ios.NewDeviceConnection(ios.DefaultUsbmuxdSocket)
+			deviceConn, err := ios.NewDeviceConnection(ios.GetUsbmuxdSocket())
 			defer deviceConn.Close()
 			if err != nil {
-				log.Errorf("could not connect to %s with err %+v, will retry in 3 seconds...", ios.DefaultUsbmuxdSocket, err)
+				log.Errorf("could not connect to %s with err %+v, will retry in 3 seconds...", ios.GetUsbmuxdSocket(), err)
 				time.Sleep(time.Second * 3)
 				continue
 			}
@@ -1518,7 +1934,7 @@ func printDeviceInfo(device ios.DeviceEntry) {
 	fmt.Println(convertToJSONString(allValues))
 }
 
-func runSyslog(device ios.DeviceEntry) {
+func runSyslog(device ios.DeviceEntry, filter syslog.Filter, parsed bool) {
 	log.Debug("Run Syslog.")
 
 	syslogConnection, err := syslog.New(device)
@@ -1529,16 +1945,20 @@ func runSyslog(device ios.DeviceEntry) {
 	go func() {
 		messageContainer := map[string]string{}
 		for {
-			logMessage, err := syslogConnection.ReadLogMessage()
+			entry, err := syslogConnection.ReadLogEntry()
 			if err != nil {
 				exitIfError("failed reading syslog", err)
 			}
-			logMessage = strings.TrimSuffix(logMessage, "\x00")
-			logMessage = strings.TrimSuffix(logMessage, "\x0A")
-			if JSONdisabled {
-				fmt.Println(logMessage)
-			} else {
-				messageContainer["msg"] = logMessage
+			if !filter.Matches(entry) {
+				continue
+			}
+			switch {
+			case JSONdisabled:
+				fmt.Println(entry)
+			case parsed:
+				fmt.Println(convertToJSONString(entry))
+			default:
+				messageContainer["msg"] = entry.String()
 				fmt.Println(convertToJSONString(messageContainer))
 			}
 		}
@@ -1548,6 +1968,349 @@ func runSyslog(device ios.DeviceEntry) {
 	<-c
 }
 
+func runPcap(device ios.DeviceEntry, filter pcap.Filter, output string, pcapng bool) {
+	var out io.Writer
+	stat, err := os.Stdout.Stat()
+	isPipe := err == nil && stat.Mode()&os.ModeNamedPipe != 0
+	switch {
+	case output == "-" || (output == "" && isPipe):
+		out = os.Stdout
+	default:
+		if output == "" {
+			output = fmt.Sprintf("dump-%d.pcap", time.Now().Unix())
+			if pcapng {
+				output += "ng"
+			}
+		}
+		f, err := os.Create(output)
+		exitIfError("failed creating pcap file", err)
+		defer f.Close()
+		log.Info("Create pcap file: ", output)
+		out = f
+	}
+	var writer pcap.PacketWriter
+	if pcapng {
+		writer, err = pcap.NewPcapngWriter(out)
+	} else {
+		writer, err = pcap.NewPcapWriter(out)
+	}
+	exitIfError("failed writing pcap header", err)
+	err = pcap.Capture(device, filter, writer)
+	exitIfError("pcap failed", err)
+}
+
+func runRotatingPcap(device ios.DeviceEntry, filter pcap.Filter, output string, pcapng bool, maxSize int64, maxDuration time.Duration) {
+	if output == "-" {
+		log.Fatal("rotation needs a file, it cannot be used with --output=-")
+	}
+	if output == "" {
+		output = fmt.Sprintf("dump-%d.pcap", time.Now().Unix())
+		if pcapng {
+			output += "ng"
+		}
+	}
+	writer, err := pcap.NewRotatingWriter(output, pcapng, maxSize, maxDuration)
+	exitIfError("failed creating pcap file", err)
+	defer writer.Close()
+	log.Info("Create pcap file: ", writer.FileName())
+	err = pcap.Capture(device, filter, writer)
+	exitIfError("pcap failed", err)
+}
+
+func runNetstat(device ios.DeviceEntry, processStats bool, interval time.Duration) {
+	const idleTimeout = 30 * time.Second
+	monitor, err := instruments.NewNetworkMonitor(device)
+	exitIfError("failed starting network monitor", err)
+	events := make(chan instruments.NetworkEvent)
+	go func() {
+		for {
+			event, err := monitor.ReadEvent()
+			if err == io.EOF {
+				return
+			}
+			exitIfError("failed reading network events", err)
+			events <- event
+		}
+	}()
+	printEvent := func(event instruments.NetworkEvent) {
+		if processStats {
+			return
+		}
+		if !JSONdisabled {
+			fmt.Println(convertToJSONString(event))
+			return
+		}
+		switch event.Type {
+		case instruments.NetworkInterfaceDetection:
+			fmt.Printf("interface %d %s\n", event.Interface.Index, event.Interface.Name)
+		case instruments.NetworkConnectionDetection, instruments.NetworkConnectionExpired:
+			c := event.Connection
+			fmt.Printf("%s #%d pid %d %s -> %s %s\n", event.Type, c.Serial, c.Pid, c.LocalAddress, c.RemoteAddress, c.Interface)
+		case instruments.NetworkConnectionUpdate:
+			u := event.Update
+			fmt.Printf("update #%d rx %d bytes tx %d bytes rtt %d\n", u.Serial, u.RxBytes, u.TxBytes, u.AvgRTT)
+		}
+	}
+
+	stats := instruments.NewNetworkStatistics()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	for {
+		select {
+		case event := <-events:
+			stats.Add(event)
+			printEvent(event)
+		case now := <-ticker.C:
+			for _, expired := range stats.ExpireConnections(now, idleTimeout) {
+				connection := expired
+				printEvent(instruments.NetworkEvent{Type: instruments.NetworkConnectionExpired, Timestamp: now, Connection: &connection})
+			}
+			if processStats && JSONdisabled {
+				for _, p := range stats.Processes() {
+					fmt.Printf("pid %d connections %d open %d expired rx %d bytes tx %d bytes\n", p.Pid, p.OpenConnections, p.ExpiredConnections, p.RxBytes, p.TxBytes)
+				}
+			} else if processStats {
+				fmt.Println(convertToJSONString(stats.Processes()))
+			}
+		case <-c:
+			monitor.Close()
+			return
+		}
+	}
+}
+
+func executableForBundleID(device ios.DeviceEntry, bundleID string) string {
+	svc, err := installationproxy.New(device)
+	exitIfError("failed connecting to installationproxy", err)
+	defer svc.Close()
+	apps, err := svc.BrowseAllApps()
+	exitIfError("browsing apps failed", err)
+	for _, app := range apps {
+		if app.CFBundleIdentifier == bundleID {
+			return app.CFBundleExecutable
+		}
+	}
+	log.Fatalf("%s not installed", bundleID)
+	return ""
+}
+
+func runBattery(device ios.DeviceEntry, watch bool, interval time.Duration) {
+	conn, err := diagnostics.New(device)
+	exitIfError("failed connecting to diagnostics", err)
+	defer conn.Close()
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		battery, err := conn.BatteryInfo()
+		exitIfError("failed reading battery info", err)
+		if JSONdisabled {
+			fmt.Printf("%s charge %d%% charging %t temperature %.1fC voltage %dmV amperage %dmA cycles %d health %.1f%%\n",
+				battery.Timestamp.Format(time.RFC3339), battery.CurrentCapacity, battery.IsCharging, battery.Temperature,
+				battery.Voltage, battery.InstantAmperage, battery.CycleCount, battery.Health)
+		} else {
+			fmt.Println(convertToJSONString(battery))
+		}
+		if !watch {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-c:
+			return
+		}
+	}
+}
+
+func pidForProcessName(device ios.DeviceEntry, processName string) uint64 {
+	service, err := instruments.NewDeviceInfoService(device)
+	exitIfError("failed opening deviceInfoService for getting process list", err)
+	defer service.Close()
+	processList, err := service.ProcessList()
+	exitIfError("failed getting process list", err)
+	for _, p := range processList {
+		if p.Name == processName {
+			return p.Pid
+		}
+	}
+	log.Fatalf("process %s is not running", processName)
+	return 0
+}
+
+func runEnergy(device ios.DeviceEntry, pid uint64, interval time.Duration) {
+	monitor, err := instruments.NewEnergyMonitor(device, []uint64{pid}, true)
+	exitIfError("failed starting energy monitor", err)
+	reports, errs := monitor.Stream(interval)
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	for {
+		select {
+		case report, ok := <-reports:
+			if !ok {
+				exitIfError("energy sampling failed", <-errs)
+				return
+			}
+			if !JSONdisabled {
+				fmt.Println(convertToJSONString(report))
+				continue
+			}
+			for _, p := range report.Processes {
+				fmt.Printf("%s pid %d energy %.2f cpu %.2f gpu %.2f network %.2f location %.2f display %.2f battery %.2fW\n",
+					report.Timestamp.Format(time.RFC3339), p.Pid, p.Cost, p.CPUCost, p.GPUCost, p.NetworkingCost, p.LocationCost, p.DisplayCost, report.PowerDraw)
+			}
+		case <-c:
+			monitor.Close()
+			return
+		}
+	}
+}
+
+func runPerf(device ios.DeviceEntry, config instruments.SysmontapConfig, pid uint64, processName string) {
+	sysmontap, err := instruments.NewSysmontap(device, config)
+	exitIfError("failed starting sysmontap", err)
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt)
+		<-c
+		sysmontap.Close()
+	}()
+	for {
+		sample, err := sysmontap.ReadSample()
+		if err == io.EOF {
+			return
+		}
+		exitIfError("failed reading sysmontap sample", err)
+		if pid > 0 || processName != "" {
+			var processes []instruments.ProcessSample
+			for _, p := range sample.Processes {
+				if (pid > 0 && p.Pid == pid) || (processName != "" && p.Name == processName) {
+					processes = append(processes, p)
+				}
+			}
+			sample.Processes = processes
+		}
+		if !JSONdisabled {
+			fmt.Println(convertToJSONString(sample))
+			continue
+		}
+		fmt.Printf("%s cpu %.1f%%\n", sample.Timestamp.Format(time.RFC3339), sample.SystemCPUUsage["CPU_TotalLoad"])
+		for _, p := range sample.Processes {
+			fmt.Printf("  %s[%d] cpu %.1f%% mem %d bytes disk read %d written %d bytes threads %d\n",
+				p.Name, p.Pid, p.CPUUsage, p.PhysFootprint, p.DiskBytesRead, p.DiskBytesWritten, p.ThreadCount)
+		}
+	}
+}
+
+func runFps(device ios.DeviceEntry) {
+	sampler, err := instruments.NewGraphicsSampler(device)
+	exitIfError("failed starting graphics sampling", err)
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt)
+		<-c
+		sampler.Close()
+	}()
+	for {
+		sample, err := sampler.ReadSample()
+		if err == io.EOF {
+			return
+		}
+		exitIfError("failed reading graphics sample", err)
+		if JSONdisabled {
+			fmt.Printf("%s fps %.0f gpu %.0f%% renderer %.0f%% tiler %.0f%%\n", sample.Timestamp.Format(time.RFC3339),
+				sample.FPS, sample.DeviceUtilization, sample.RendererUtilization, sample.TilerUtilization)
+		} else {
+			fmt.Println(convertToJSONString(sample))
+		}
+	}
+}
+
+func runEvents(device ios.DeviceEntry, config events.Config) {
+	monitor, err := events.NewMonitor(device, config)
+	exitIfError("failed starting event monitor", err)
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt)
+		<-c
+		monitor.Close()
+	}()
+	for {
+		event, err := monitor.ReadEvent()
+		if err == io.EOF {
+			return
+		}
+		exitIfError("failed reading events", err)
+		if !JSONdisabled {
+			fmt.Println(convertToJSONString(event))
+			continue
+		}
+		details := event.Notification
+		if event.ApplicationState != nil {
+			details = fmt.Sprintf("%s (%d) %s", event.ApplicationState.BundleID, event.ApplicationState.Pid, event.ApplicationState.State)
+		}
+		fmt.Printf("%s %s %s %s\n", event.Timestamp.Format(time.RFC3339), event.UDID, event.Type, details)
+	}
+}
+
+func postNotifications(device ios.DeviceEntry, notifications []string) {
+	conn, err := notificationproxy.New(device)
+	exitIfError("failed connecting to notificationproxy", err)
+	defer conn.Close()
+	for _, notification := range notifications {
+		err := conn.PostNotification(notification)
+		exitIfError("failed posting "+notification, err)
+	}
+	log.Info("ok")
+}
+
+func observeNotifications(device ios.DeviceEntry, notifications []string) {
+	conn, err := notificationproxy.New(device)
+	exitIfError("failed connecting to notificationproxy", err)
+	defer conn.Close()
+	subscription, err := conn.Subscribe(notifications...)
+	exitIfError("failed observing notifications", err)
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	for {
+		select {
+		case notification, ok := <-subscription.Notifications():
+			if !ok {
+				log.WithFields(log.Fields{"err": subscription.Err()}).Fatal("notificationproxy connection ended")
+			}
+			if JSONdisabled {
+				fmt.Printf("%s %s\n", time.Now().Format(time.RFC3339), notification)
+				continue
+			}
+			fmt.Println(convertToJSONString(map[string]interface{}{"timestamp": time.Now(), "notification": notification}))
+		case <-c:
+			return
+		}
+	}
+}
+
+func transferOptions(arguments docopt.Opts) afc.TransferOptions {
+	var options afc.TransferOptions
+	options.Resume, _ = arguments.Bool("--resume")
+	options.Verify, _ = arguments.Bool("--verify")
+	if progress, _ := arguments.Bool("--progress"); progress {
+		options.Progress = func(p afc.TransferProgress) {
+			if !JSONdisabled {
+				fmt.Println(convertToJSONString(p))
+				return
+			}
+			percent := 100.0
+			if p.Total > 0 {
+				percent = float64(p.Transferred) * 100 / float64(p.Total)
+			}
+			fmt.Printf("%s %d/%d bytes %.0f%% %.2f MB/s\n", p.Path, p.Transferred, p.Total, percent, p.BytesPerSecond/1e6)
+		}
+	}
+	return options
+}
+
 func pairDevice(device ios.DeviceEntry, orgIdentityP12File string, p12Password string) {
 	if orgIdentityP12File == "" {
 		err := ios.Pair(device)
@@ -1591,6 +2354,20 @@ func convertToJSONString(data interface{}) string {
 	return string(b)
 }
 
+//durationArgument parses option as a go duration like 500ms or 10s and returns defaultValue if it was not given
+func durationArgument(arguments docopt.Opts, option string, defaultValue time.Duration) time.Duration {
+	value, _ := arguments.String(option)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	exitIfError("invalid "+option, err)
+	if duration <= 0 {
+		log.Fatalf("%s must be positive", option)
+	}
+	return duration
+}
+
 func exitIfError(msg string, err error) {
 	if err != nil {
 		log.WithFields(log.Fields{"err": err}).Fatalf(msg)
@@ -0,0 +1,56 @@
+package ioskit_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/usbmuxsim"
+	"github.com/danielpaulus/go-ios/wdbd/ioskit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransport(t *testing.T) {
+	server := usbmuxsim.NewServer()
+	require.NoError(t, server.Start("tcp:127.0.0.1:0"))
+	defer server.Close()
+	device := usbmuxsim.NewDevice("udid0")
+	device.HandleService(1234, func(conn net.Conn) {
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte(line))
+	})
+	deviceID := server.Attach(device)
+	server.Attach(usbmuxsim.NewDevice("udid1"))
+
+	remote := ioskit.NewRemoteDevice(strings.TrimPrefix(server.Socket(), "tcp:"), "udid0")
+	go remote.Monitor(context.Background())
+	require.Eventually(t, func() bool {
+		_, err := remote.ListDevices()
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	muxConn := newTransportConn(remote)
+	list, err := muxConn.ListDevices()
+	require.NoError(t, err)
+	assert.Equal(t, "udid0\n", list.String())
+
+	muxConn = newTransportConn(remote)
+	require.NoError(t, muxConn.Connect(deviceID, 1234))
+	deviceConn := muxConn.ReleaseDeviceConnection()
+	defer deviceConn.Close()
+	require.NoError(t, deviceConn.Send([]byte("hello\n")))
+	line, err := bufio.NewReader(deviceConn.Reader()).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", line)
+}
+
+func newTransportConn(remote *ioskit.RemoteDevice) *ios.UsbMuxConnection {
+	client, server := net.Pipe()
+	go ioskit.NewTransport(server, remote).HandleLoop()
+	return ios.NewUsbMuxConnection(ios.NewDeviceConnectionWithConn(client))
+}